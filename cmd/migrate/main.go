@@ -0,0 +1,95 @@
+// Command migrate copies every module version from the storage
+// backend of one Athens config file to the storage backend of
+// another. It records its progress in a checkpoint file so that
+// an interrupted migration resumes where it stopped, and retries
+// the versions that failed to copy, when it is run again with the
+// same checkpoint.
+//
+// Note that environment variable overrides, such as
+// ATHENS_STORAGE_TYPE, apply to both config files.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	stdlog "log"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/gomods/athens/cmd/proxy/actions"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/migrate"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	fromFile   = flag.String("from", "", "The path to the config file of the source storage")
+	toFile     = flag.String("to", "", "The path to the config file of the destination storage")
+	checkpoint = flag.String("checkpoint", "athens-migrate.checkpoint", "The file to record progress in, empty to disable resuming")
+	pageSize   = flag.Int("pagesize", 100, "The number of module versions to copy between two checkpoints")
+)
+
+func main() {
+	flag.Parse()
+	if *fromFile == "" || *toFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	from, err := config.ParseConfigFile(*fromFile)
+	if err != nil {
+		stdlog.Fatalf("could not load source config file: %v", err)
+	}
+	to, err := config.ParseConfigFile(*toFile)
+	if err != nil {
+		stdlog.Fatalf("could not load destination config file: %v", err)
+	}
+	src, err := actions.GetStorage(from.StorageType, from.Storage, from.TimeoutDuration(), http.DefaultClient)
+	if err != nil {
+		stdlog.Fatalf("could not create source storage: %v", err)
+	}
+	dst, err := actions.GetStorage(to.StorageType, to.Storage, to.TimeoutDuration(), http.DefaultClient)
+	if err != nil {
+		stdlog.Fatalf("could not create destination storage: %v", err)
+	}
+
+	lvl, err := logrus.ParseLevel(from.LogLevel)
+	if err != nil {
+		stdlog.Fatal(err)
+	}
+	lggr := log.New(from.CloudRuntime, lvl)
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = log.SetEntryInContext(ctx, lggr.WithFields(map[string]interface{}{"component": "migrate"}))
+	go func() {
+		sigint := make(chan os.Signal, 1)
+		signal.Notify(sigint, os.Interrupt)
+		<-sigint
+		// stop between two versions, so that the
+		// checkpoint stays consistent.
+		cancel()
+	}()
+
+	res, err := migrate.Run(ctx, &migrate.Opts{
+		Source:     src,
+		Dest:       dst,
+		Checkpoint: *checkpoint,
+		PageSize:   *pageSize,
+	})
+	if res != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			stdlog.Fatal(err)
+		}
+	}
+	if err != nil {
+		stdlog.Fatalf("migration stopped: %v", err)
+	}
+	if len(res.Failed) > 0 {
+		fmt.Fprintf(os.Stderr, "%d module versions could not be copied\n", len(res.Failed))
+		os.Exit(1)
+	}
+}
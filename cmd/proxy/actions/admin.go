@@ -0,0 +1,294 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/index"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/prewarm"
+	"github.com/gomods/athens/pkg/retention"
+	"github.com/gomods/athens/pkg/scrub"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// adminPrefix is the path under which the admin API is served.
+const adminPrefix = "/admin"
+
+// adminOpts are the dependencies of the admin API.
+type adminOpts struct {
+	Storage storage.Backend
+	Stasher stash.Stasher
+	// Fetcher fetches the versions that are stashed again.
+	Fetcher  module.Fetcher
+	Indexer  index.Indexer
+	Importer *prewarm.Importer
+	// Retainer is nil if retention is disabled.
+	Retainer *retention.Retainer
+	// Scrubber is nil if scrubbing is disabled.
+	Scrubber *scrub.Scrubber
+	// NotFound is nil if not found results are not cached.
+	NotFound notfound.Cache
+	// Events is nil if no events are emitted.
+	Events events.Emitter
+	// Verifier checks imported bundles, and is
+	// nil if there is no checksum database to verify.
+	Verifier *checksum.Verifier
+	// Pins is nil if there is no pin file.
+	Pins *pin.Store
+	// Filter is nil if there is no filter file.
+	Filter     *module.Filter
+	FilterFile string
+	// Fs and TempDir are where bundles and
+	// the zips of re-fetched versions are spooled.
+	Fs      afero.Fs
+	TempDir string
+}
+
+// addAdminRoutes registers the admin API on r. The admin API is
+// protected by authMW, which checks either its own basic auth
+// credentials or the admin scope of the auth file, and lets operators
+// list, delete and re-stash the module versions in storage.
+//
+// The routes are:
+//
+//	GET    /admin/catalog                      list every stored module version
+//	GET    /admin/{module}/@v/list             list the stored versions of a module
+//	DELETE /admin/{module}/@v/{version}        delete a version from storage and the index
+//	POST   /admin/{module}/@v/{version}/stash  fetch a version again from upstream
+//	PUT    /admin/{module}/@v/{version}/pin    pin a version, if enabled
+//	DELETE /admin/{module}/@v/{version}/pin    unpin a version, if enabled
+//	GET    /admin/pins                         list the pinned versions, if enabled
+//	POST   /admin/import                       stash every version in a go.sum, go.mod or module@version list
+//	GET    /admin/bundle                       export stored versions as an offline bundle
+//	POST   /admin/bundle                       import an offline bundle into storage
+//	GET    /admin/retention                    show the state of retention, if enabled
+//	POST   /admin/retention                    apply the retention rules, if enabled
+//	GET    /admin/scrub                        show the state of scrubbing, if enabled
+//	POST   /admin/scrub                        scrub the storage now, if enabled
+//	DELETE /admin/notfound                     invalidate the not found cache, if enabled
+//	GET    /admin/filter                       show the current filter rules, if enabled
+func addAdminRoutes(r *mux.Router, opts *adminOpts, authMW mux.MiddlewareFunc) {
+	s := opts.Storage
+	ar := r.PathPrefix(adminPrefix).Subrouter()
+	ar.Use(authMW)
+	ar.HandleFunc("/catalog", catalogHandler(s)).Methods(http.MethodGet)
+	ar.HandleFunc("/import", adminImportHandler(opts.Importer)).Methods(http.MethodPost)
+	ar.HandleFunc("/bundle", bundleExportHandler(s, opts.Fs, opts.TempDir)).Methods(http.MethodGet)
+	ar.HandleFunc("/bundle", bundleImportHandler(s, opts.Indexer, opts.Verifier, opts.Events, opts.Fs, opts.TempDir)).Methods(http.MethodPost)
+	if opts.Retainer != nil {
+		ar.HandleFunc("/retention", retentionHandler(opts.Retainer)).Methods(http.MethodGet)
+		ar.HandleFunc("/retention", adminRetentionHandler(opts.Retainer)).Methods(http.MethodPost)
+	}
+	if opts.Scrubber != nil {
+		ar.HandleFunc("/scrub", scrubHandler(opts.Scrubber)).Methods(http.MethodGet)
+		ar.HandleFunc("/scrub", adminScrubHandler(opts.Scrubber)).Methods(http.MethodPost)
+	}
+	if opts.NotFound != nil {
+		ar.HandleFunc("/notfound", adminNotFoundHandler(opts.NotFound)).Methods(http.MethodDelete)
+	}
+	if opts.Filter != nil {
+		ar.HandleFunc("/filter", adminFilterHandler(opts.Filter, opts.FilterFile)).Methods(http.MethodGet)
+	}
+	if opts.Pins != nil {
+		ar.HandleFunc("/pins", pinsHandler(opts.Pins)).Methods(http.MethodGet)
+		ar.HandleFunc("/{module:.+}/@v/{version}/pin", adminPinHandler(opts.Pins)).Methods(http.MethodPut)
+		ar.HandleFunc("/{module:.+}/@v/{version}/pin", adminUnpinHandler(opts.Pins)).Methods(http.MethodDelete)
+	}
+	ar.HandleFunc("/{module:.+}/@v/list", adminListHandler(s)).Methods(http.MethodGet)
+	ar.HandleFunc("/{module:.+}/@v/{version}", adminDeleteHandler(s, opts.Indexer, opts.Pins, opts.Events)).Methods(http.MethodDelete)
+	ar.HandleFunc("/{module:.+}/@v/{version}/stash", adminStashHandler(s, opts.Stasher, opts.Fetcher, opts.Indexer, opts.NotFound, opts.Pins, opts.Events, opts.Fs, opts.TempDir)).Methods(http.MethodPost)
+	// anything else under the admin prefix must not fall
+	// through to the download protocol handlers, which
+	// would treat "admin" as part of a module path.
+	ar.PathPrefix("/").HandlerFunc(http.NotFound)
+}
+
+// adminListHandler implements GET baseURL/admin/{module}/@v/list
+func adminListHandler(s storage.Backend) http.HandlerFunc {
+	const op errors.Op = "actions.AdminListHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		mod, err := paths.GetModule(r)
+		if err != nil {
+			lggr.SystemErr(errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		versions, err := s.List(r.Context(), mod)
+		if err != nil {
+			err = errors.E(op, err, errors.M(mod))
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		for _, v := range versions {
+			fmt.Fprintln(w, v)
+		}
+	}
+}
+
+// adminDeleteHandler implements DELETE baseURL/admin/{module}/@v/{version}
+//
+// Pinned versions cannot be deleted.
+func adminDeleteHandler(s storage.Backend, indexer index.Indexer, pins *pin.Store, emitter events.Emitter) http.HandlerFunc {
+	const op errors.Op = "actions.AdminDeleteHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		params, err := paths.GetAllParams(r)
+		if err != nil {
+			lggr.SystemErr(errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := checkPinned(pins, params.Module, params.Version); err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		if err := deleteVersion(r.Context(), s, indexer, emitter, params.Module, params.Version, "deleted by an admin"); err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.WithFields(logrus.Fields{"module": params.Module, "version": params.Version}).Infof("admin deleted module version")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// adminStashHandler implements POST baseURL/admin/{module}/@v/{version}/stash
+//
+// A version that is already in storage is fetched again with f, and
+// only replaces the stored version once the fetch has succeeded, so
+// that it is kept if upstream is unavailable.
+// Pinned versions that are stored cannot be fetched again.
+// A version that is cached as not found is removed from nf first,
+// so that upstream is asked again.
+func adminStashHandler(s storage.Backend, st stash.Stasher, f module.Fetcher, indexer index.Indexer, nf notfound.Cache, pins *pin.Store, emitter events.Emitter, fs afero.Fs, dir string) http.HandlerFunc {
+	const op errors.Op = "actions.AdminStashHandler"
+	checker := storage.WithChecker(s)
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		params, err := paths.GetAllParams(r)
+		if err != nil {
+			lggr.SystemErr(errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mod, ver := params.Module, params.Version
+		exists, err := checker.Exists(r.Context(), mod, ver)
+		if err != nil {
+			err = errors.E(op, err, errors.M(mod), errors.V(ver))
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		if nf != nil {
+			if err := nf.Invalidate(r.Context(), mod, ver); err != nil {
+				err = errors.E(op, err, errors.M(mod), errors.V(ver))
+				lggr.SystemErr(err)
+				http.Error(w, err.Error(), errors.Kind(err))
+				return
+			}
+		}
+		if exists {
+			if err := checkPinned(pins, mod, ver); err != nil {
+				err = errors.E(op, err)
+				lggr.SystemErr(err)
+				http.Error(w, err.Error(), errors.Kind(err))
+				return
+			}
+		}
+		var newVer string
+		if exists {
+			newVer, err = stash.Refetch(r.Context(), f, s, indexer, emitter, fs, dir, mod, ver)
+		} else {
+			newVer, err = st.Stash(r.Context(), mod, ver)
+		}
+		if err != nil {
+			err = errors.E(op, err, errors.M(mod), errors.V(ver))
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.WithFields(logrus.Fields{"module": mod, "version": newVer}).Infof("admin stashed module version")
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(paths.AllPathParams{Module: mod, Version: newVer}); err != nil {
+			lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
+
+// maxImportSize is the largest list of module versions
+// that can be posted to the import endpoint.
+const maxImportSize = 10 << 20
+
+// adminImportHandler implements POST baseURL/admin/import
+//
+// The body is a go.sum, a go.mod or a module@version list, as set
+// by the format query parameter or detected from the body.
+// It responds with a report of the versions that failed.
+func adminImportHandler(importer *prewarm.Importer) http.HandlerFunc {
+	const op errors.Op = "actions.AdminImportHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+		if err != nil {
+			err = errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		entries, err := prewarm.Parse(prewarm.Format(r.FormValue("format")), data)
+		if err != nil {
+			err = errors.E(op, err, logrus.InfoLevel)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		rep := importer.Import(r.Context(), entries)
+		lggr.WithFields(map[string]interface{}{
+			"total":   rep.Total,
+			"stashed": rep.Stashed,
+			"skipped": rep.Skipped,
+			"failed":  len(rep.Failed),
+		}).Infof("admin imported module versions")
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rep); err != nil {
+			lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
+
+// deleteVersion deletes a version from storage and from the index,
+// and emits an event for it to emitter if it is not nil.
+// A version that is stored but was never indexed is not an error.
+func deleteVersion(ctx context.Context, s storage.Backend, indexer index.Indexer, emitter events.Emitter, mod, ver, reason string) error {
+	const op errors.Op = "actions.deleteVersion"
+	if err := s.Delete(ctx, mod, ver); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if err := indexer.Delete(ctx, mod, ver); err != nil && !errors.IsNotFoundErr(err) {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if emitter != nil {
+		emitter.Emit(ctx, &events.Event{Type: events.Deleted, Module: mod, Version: ver, Reason: reason})
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/index"
+	indexmem "github.com/gomods/athens/pkg/index/mem"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/prewarm"
+	"github.com/gomods/athens/pkg/retention"
+	"github.com/gomods/athens/pkg/scrub"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// saveStasher stashes a version by saving fixed
+// contents to storage and indexing it.
+type saveStasher struct {
+	s       storage.Backend
+	indexer index.Indexer
+	stashed int
+}
+
+func (st *saveStasher) Stash(ctx context.Context, mod, ver string) (string, error) {
+	st.stashed++
+	err := st.s.Save(ctx, mod, ver, []byte("module "+mod), strings.NewReader("zip"), []byte("{}"))
+	if err != nil {
+		return "", err
+	}
+	return ver, st.indexer.Index(ctx, mod, ver)
+}
+
+// refetcher fetches fixed contents, or fails if down is set.
+type refetcher struct {
+	down    bool
+	fetched int
+}
+
+func (f *refetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	f.fetched++
+	if f.down {
+		return nil, fmt.Errorf("upstream is down")
+	}
+	return &storage.Version{
+		Mod:    []byte("module " + mod + " // fetched again"),
+		Zip:    ioutil.NopCloser(strings.NewReader("zip")),
+		Info:   []byte("{}"),
+		Semver: ver,
+	}, nil
+}
+
+func TestAdminRoutes(t *testing.T) {
+	const mod, ver = "github.com/athens-artifacts/happy-path", "v0.0.1"
+	ctx := context.Background()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	indexer := indexmem.New()
+	st := &saveStasher{s: s, indexer: indexer}
+	_, err = st.Stash(ctx, mod, ver)
+	require.NoError(t, err)
+	f := &refetcher{}
+
+	r := mux.NewRouter()
+	opts := &adminOpts{
+		Storage:  s,
+		Stasher:  st,
+		Fetcher:  f,
+		Indexer:  indexer,
+		Importer: prewarm.New(st, storage.WithChecker(s), 2),
+		Fs:       memFs,
+		TempDir:  "/tmp",
+	}
+	addAdminRoutes(r, opts, basicAuth("admin", "secret"))
+	doBody := func(method, path, body string, auth bool) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Result()
+	}
+	do := func(method, path string, auth bool) *http.Response {
+		return doBody(method, path, "", auth)
+	}
+
+	resp := do(http.MethodGet, "/admin/"+mod+"/@v/list", false)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = do(http.MethodGet, "/admin/"+mod+"/@v/list", true)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, ver+"\n", string(body))
+
+	resp = do(http.MethodGet, "/admin/catalog", true)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), mod)
+
+	f.down = true
+	resp = do(http.MethodPost, "/admin/"+mod+"/@v/"+ver+"/stash", true)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	gomod, err := s.GoMod(ctx, mod, ver)
+	require.NoError(t, err, "a failed fetch must keep the stored version")
+	require.Equal(t, "module "+mod, string(gomod))
+
+	f.down = false
+	resp = do(http.MethodPost, "/admin/"+mod+"/@v/"+ver+"/stash", true)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, f.fetched, "stash must fetch a stored version again")
+	gomod, err = s.GoMod(ctx, mod, ver)
+	require.NoError(t, err)
+	require.Equal(t, "module "+mod+" // fetched again", string(gomod))
+	lines, err := indexer.Lines(ctx, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+
+	resp = do(http.MethodDelete, "/admin/"+mod+"/@v/"+ver, true)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	exists, err := storage.WithChecker(s).Exists(ctx, mod, ver)
+	require.NoError(t, err)
+	require.False(t, exists)
+	lines, err = indexer.Lines(ctx, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Empty(t, lines)
+
+	resp = do(http.MethodDelete, "/admin/"+mod+"/@v/"+ver, true)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = do(http.MethodGet, "/admin/"+mod+"/@v/"+ver+".info", true)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = doBody(http.MethodPost, "/admin/import?format=list", mod+"@"+ver+"\n"+mod+"@v0.0.2\n", true)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rep prewarm.Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rep))
+	require.Equal(t, 2, rep.Total)
+	require.Equal(t, 2, rep.Stashed)
+	require.Empty(t, rep.Failed)
+
+	resp = doBody(http.MethodPost, "/admin/import?format=list", "not a module version", true)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestBasicAuthExcludedPrefix(t *testing.T) {
+	handler := basicAuth("user", "pass", "/admin/")(http.HandlerFunc(mockHandler))
+	for path, code := range map[string]int{
+		"/admin/catalog": http.StatusOK,
+		"/administrator": http.StatusUnauthorized,
+		"/catalog":       http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, code, w.Code, path)
+	}
+}
+
+func TestAdminRetention(t *testing.T) {
+	const mod = "github.com/athens-artifacts/happy-path"
+	ctx := context.Background()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	for _, ver := range []string{"v0.0.1", "v0.0.2"} {
+		require.NoError(t, s.Save(ctx, mod, ver, []byte("module "+mod), strings.NewReader("zip"), []byte("{}")))
+	}
+	retainer, err := retention.New(&retention.Opts{
+		Storage: s,
+		Rules:   []retention.Rule{{KeepNewest: 1}},
+	})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	addAdminRoutes(r, &adminOpts{Storage: s, Indexer: indexmem.New(), Retainer: retainer}, basicAuth("admin", "secret"))
+	run := func(query string) (int, *retention.Result) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/retention"+query, nil)
+		req.SetBasicAuth("admin", "secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+		var res retention.Result
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		return w.Code, &res
+	}
+
+	code, res := run("")
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, res.DryRun, "runs must be dry unless asked otherwise")
+	require.Len(t, res.Deleted, 1)
+	require.Equal(t, "v0.0.1", res.Deleted[0].Version)
+
+	code, _ = run("?dryrun=maybe")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, res = run("?dryrun=false")
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, res.DryRun)
+	versions, err := s.List(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v0.0.2"}, versions)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/retention", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status retention.Status
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.False(t, status.Running)
+	require.NotNil(t, status.Last)
+	require.False(t, status.Last.DryRun)
+}
+
+func TestAdminScrub(t *testing.T) {
+	const mod = "github.com/athens-artifacts/happy-path"
+	ctx := context.Background()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	require.NoError(t, s.Save(ctx, mod, "v0.0.1", []byte("module "+mod), strings.NewReader("zip"), []byte("{}")))
+	scrubber, err := scrub.New(&scrub.Opts{Storage: s, Action: scrub.Report, Fs: memFs, TempDir: "/tmp"})
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	addAdminRoutes(r, &adminOpts{Storage: s, Indexer: indexmem.New(), Scrubber: scrubber}, basicAuth("admin", "secret"))
+	do := func(method string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/scrub", nil)
+		if auth {
+			req.SetBasicAuth("admin", "secret")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, false).Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodPost, false).Code)
+
+	w := do(http.MethodPost, true)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res scrub.Result
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(t, 1, res.Checked)
+
+	w = do(http.MethodGet, true)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status scrub.Status
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.NotNil(t, status.Last)
+	require.Equal(t, 1, status.Last.Checked)
+}
+
+func TestAdminNotFound(t *testing.T) {
+	const mod = "github.com/athens-artifacts/no-such-module"
+	ctx := context.Background()
+	nf := notfound.NewMemory(time.Hour)
+	require.NoError(t, nf.Add(ctx, mod, ""))
+	require.NoError(t, nf.Add(ctx, mod, "v1.0.0"))
+
+	r := mux.NewRouter()
+	addAdminRoutes(r, &adminOpts{NotFound: nf}, basicAuth("admin", "secret"))
+	do := func(query string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/admin/notfound"+query, nil)
+		req.SetBasicAuth("admin", "secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusBadRequest, do("?version=v1.0.0"))
+	require.Equal(t, http.StatusNoContent, do("?module="+mod+"&version=v1.0.0"))
+	has, err := nf.Has(ctx, mod, "v1.0.0")
+	require.NoError(t, err)
+	require.False(t, has)
+	has, err = nf.Has(ctx, mod, "")
+	require.NoError(t, err)
+	require.True(t, has, "invalidating a version must keep its module")
+
+	require.Equal(t, http.StatusNoContent, do(""))
+	has, err = nf.Has(ctx, mod, "")
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func TestAdminFilter(t *testing.T) {
+	file, err := ioutil.TempFile("", "filter-")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("# comment\n- github.com/a\nD github.com/b v1\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	mf, err := module.NewFilter(file.Name())
+	require.NoError(t, err)
+
+	r := mux.NewRouter()
+	addAdminRoutes(r, &adminOpts{Filter: mf, FilterFile: file.Name()}, basicAuth("admin", "secret"))
+	req := httptest.NewRequest(http.MethodGet, "/admin/filter", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status filterStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.Equal(t, file.Name(), status.File)
+	require.Equal(t, []string{"- github.com/a", "D github.com/b v1"}, status.Rules)
+	_, loadedAt := mf.Rules()
+	require.True(t, loadedAt.Equal(status.LoadedAt))
+}
+
+func TestAdminPins(t *testing.T) {
+	const mod, ver = "github.com/athens-artifacts/happy-path", "v0.0.1"
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "athens-pin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pins, err := pin.NewStore(filepath.Join(dir, "pins"))
+	require.NoError(t, err)
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	indexer := indexmem.New()
+	st := &saveStasher{s: s, indexer: indexer}
+	_, err = st.Stash(ctx, mod, ver)
+	require.NoError(t, err)
+	f := &refetcher{}
+
+	r := mux.NewRouter()
+	addAdminRoutes(r, &adminOpts{
+		Storage: s,
+		Stasher: st,
+		Fetcher: f,
+		Indexer: indexer,
+		Pins:    pins,
+		Fs:      memFs,
+		TempDir: "/tmp",
+	}, basicAuth("admin", "secret"))
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.SetBasicAuth("admin", "secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodPut, "/admin/"+mod+"/@v/"+ver+"/pin").Code)
+	w := do(http.MethodGet, "/admin/pins")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []paths.AllPathParams
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Equal(t, []paths.AllPathParams{{Module: mod, Version: ver}}, list)
+
+	require.Equal(t, http.StatusConflict, do(http.MethodDelete, "/admin/"+mod+"/@v/"+ver).Code)
+	require.Equal(t, http.StatusConflict, do(http.MethodPost, "/admin/"+mod+"/@v/"+ver+"/stash").Code)
+	require.Equal(t, 0, f.fetched, "pinned versions must not be fetched again")
+	exists, err := storage.WithChecker(s).Exists(ctx, mod, ver)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/"+mod+"/@v/"+ver+"/pin").Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/admin/"+mod+"/@v/"+ver+"/pin").Code)
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/admin/"+mod+"/@v/"+ver).Code)
+}
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/log"
 	mw "github.com/gomods/athens/pkg/middleware"
 	"github.com/gomods/athens/pkg/module"
 	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/validation"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"github.com/unrolled/secure"
@@ -22,7 +24,11 @@ const Service = "proxy"
 // App is where all routes and middleware for the proxy
 // should be defined. This is the nerve center of your
 // application.
-func App(conf *config.Config) (http.Handler, error) {
+//
+// The returned func stops the work that the proxy does in the
+// background and must be called once the server has shut down,
+// so that the last download counts are saved.
+func App(conf *config.Config) (http.Handler, func(), error) {
 	// ENV is used to help switch settings based on where the
 	// application is being run. Default is "development".
 	ENV := conf.GoEnv
@@ -46,7 +52,7 @@ func App(conf *config.Config) (http.Handler, error) {
 
 	logLvl, err := logrus.ParseLevel(conf.LogLevel)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	lggr := log.New(conf.CloudRuntime, logLvl)
 
@@ -100,53 +106,71 @@ func App(conf *config.Config) (http.Handler, error) {
 		defer flushStats()
 	}
 
+	client := &http.Client{
+		Transport: &ochttp.Transport{
+			Base: http.DefaultTransport,
+		},
+	}
+
 	user, pass, ok := conf.BasicAuth()
-	if ok {
-		r.Use(basicAuth(user, pass))
+	if conf.AuthFile != "" {
+		if _, _, adminOK := conf.AdminAuth(); ok || adminOK {
+			return nil, nil, fmt.Errorf("AuthFile cannot be used along with BasicAuthUser, BasicAuthPass, AdminUser and AdminPass")
+		}
+		creds, err := startAuth(conf, client, lggr)
+		if err != nil {
+			return nil, nil, err
+		}
+		r.Use(credentialsAuth(creds, conf.PathPrefix))
+	} else if ok {
+		var excluded []string
+		if _, _, ok := conf.AdminAuth(); ok {
+			// the admin API checks its own credentials
+			excluded = append(excluded, strings.TrimSuffix(conf.PathPrefix, "/")+adminPrefix+"/")
+		}
+		r.Use(basicAuth(user, pass, excluded...))
 	}
 
+	var mf *module.Filter
 	if !conf.FilterOff() {
-		mf, err := module.NewFilter(conf.FilterFile)
+		mf, err = startFilter(conf, lggr)
 		if err != nil {
 			lggr.Fatal(err)
 		}
 		r.Use(mw.NewFilterMiddleware(mf, conf.GlobalEndpoint))
 	}
 
-	client := &http.Client{
-		Transport: &ochttp.Transport{
-			Base: http.DefaultTransport,
-		},
-	}
-
 	// Having the hook set means we want to use it
 	if vHook := conf.ValidatorHook; vHook != "" {
-		r.Use(mw.NewValidationMiddleware(client, vHook))
+		cache := validation.NewCache(config.GetTimeoutDuration(conf.ValidatorCacheTTL))
+		r.Use(mw.NewValidationMiddleware(client, vHook, cache))
 	}
 
 	store, err := GetStorage(conf.StorageType, conf.Storage, conf.TimeoutDuration(), client)
 	if err != nil {
 		err = fmt.Errorf("error getting storage configuration (%s)", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	proxyRouter := r
 	if subRouter != nil {
 		proxyRouter = subRouter
 	}
-	if err := addProxyRoutes(
+	stop, err := addProxyRoutes(
 		proxyRouter,
 		store,
+		mf,
 		lggr,
 		conf,
-	); err != nil {
+	)
+	if err != nil {
 		err = fmt.Errorf("error adding proxy routes (%s)", err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	h := &ochttp.Handler{
 		Handler: r,
 	}
 
-	return h, nil
+	return h, stop, nil
 }
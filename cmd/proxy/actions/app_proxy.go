@@ -7,6 +7,8 @@ import (
 	"path"
 	"strings"
 
+	"github.com/gomods/athens/pkg/auth"
+	"github.com/gomods/athens/pkg/checksum"
 	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/download"
 	"github.com/gomods/athens/pkg/download/addons"
@@ -18,18 +20,25 @@ import (
 	"github.com/gomods/athens/pkg/index/postgres"
 	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/prewarm"
+	"github.com/gomods/athens/pkg/retention"
+	"github.com/gomods/athens/pkg/scrub"
 	"github.com/gomods/athens/pkg/stash"
 	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/usage"
+	"github.com/gomods/athens/pkg/validation"
 	"github.com/gorilla/mux"
 	"github.com/spf13/afero"
+	"go.opencensus.io/plugin/ochttp"
 )
 
 func addProxyRoutes(
 	r *mux.Router,
 	s storage.Backend,
+	filter *module.Filter,
 	l *log.Logger,
 	c *config.Config,
-) error {
+) (func(), error) {
 	r.HandleFunc("/", proxyHomeHandler)
 	r.HandleFunc("/healthz", healthHandler)
 	r.HandleFunc("/readyz", getReadinessHandler(s))
@@ -39,17 +48,30 @@ func addProxyRoutes(
 
 	indexer, err := getIndex(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	r.HandleFunc("/index", indexHandler(indexer))
 
+	usageStore, err := getUsageStore(c)
+	if err != nil {
+		return nil, err
+	}
+	var downloads download.DownloadRecorder
+	stop := func() {}
+	if usageStore != nil {
+		var rec *usage.Recorder
+		rec, stop = startUsage(usageStore, l)
+		downloads = rec
+		r.HandleFunc("/stats", statsHandler(usageStore))
+	}
+
 	for _, sumdb := range c.SumDBs {
 		sumdbURL, err := url.Parse(sumdb)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if sumdbURL.Scheme != "https" {
-			return fmt.Errorf("sumdb: %v must have an https scheme", sumdb)
+			return nil, fmt.Errorf("sumdb: %v must have an https scheme", sumdb)
 		}
 		supportPath := path.Join("/sumdb", sumdbURL.Host, "/supported")
 		r.HandleFunc(supportPath, func(w http.ResponseWriter, r *http.Request) {
@@ -94,39 +116,194 @@ func addProxyRoutes(
 		c.GoBinaryEnvVars.Add("GONOSUMDB", strings.Join(c.NoSumPatterns, ","))
 	}
 	if err := c.GoBinaryEnvVars.Validate(); err != nil {
-		return err
+		return nil, err
+	}
+	mf, err := getFetcher(c, fs)
+	if err != nil {
+		return nil, err
+	}
+	var verifier *checksum.Verifier
+	if c.VerifySumDB != "" {
+		verifier, err = checksum.NewVerifier(c.VerifySumDB, c.SumDBs, c.NoSumPatterns, upstreamClient())
+		if err != nil {
+			return nil, err
+		}
+		mf = checksum.NewVerifyingFetcher(mf, verifier, fs, c.GoGetDir)
+	} else {
+		mf = checksum.NewHashingFetcher(mf, fs, c.GoGetDir)
 	}
-	mf, err := module.NewGoGetFetcher(c.GoBinary, c.GoGetDir, c.GoBinaryEnvVars, fs)
+	if c.PostFetchValidatorHook != "" {
+		hook := validation.NewHook(c.PostFetchValidatorHook, upstreamClient())
+		cache := validation.NewCache(config.GetTimeoutDuration(c.ValidatorCacheTTL))
+		mf = validation.NewFetcher(mf, hook, cache, fs, c.GoGetDir)
+	}
+
+	emitter, err := startEvents(c, fs, l)
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := startPins(c, l)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	lister := module.NewVCSLister(c.GoBinary, c.GoBinaryEnvVars, fs)
+	var scrubber *scrub.Scrubber
+	if c.ScrubInterval > 0 {
+		scrubber, err = startScrubber(c, s, verifier, pins, emitter, fs, l)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	startRepair(c, s, l)
+
+	var retainer *retention.Retainer
+	if c.RetentionInterval > 0 {
+		retainer, err = startRetention(c, s, indexer, usageStore, pins, emitter, l)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lister, err := getLister(c, fs)
+	if err != nil {
+		return nil, err
+	}
 	checker := storage.WithChecker(s)
 	withSingleFlight, err := getSingleFlight(c, checker)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	notFound, err := getNotFoundCache(c)
+	if err != nil {
+		return nil, err
+	}
+	stashWrappers := []stash.Wrapper{stash.WithPool(c.GoGetWorkers), withSingleFlight}
+	if notFound != nil {
+		stashWrappers = append(stashWrappers, stash.WithNotFoundCache(notFound))
+	}
+	st := stash.New(mf, s, indexer, emitter, stashWrappers...)
+
+	var adminAuth mux.MiddlewareFunc
+	if user, pass, ok := c.AdminAuth(); ok {
+		adminAuth = basicAuth(user, pass)
+	} else if c.AuthFile != "" {
+		adminAuth = requireScope(auth.ScopeAdmin)
+	}
+	if adminAuth != nil {
+		adminOpts := &adminOpts{
+			Storage:    s,
+			Stasher:    st,
+			Fetcher:    mf,
+			Indexer:    indexer,
+			Importer:   prewarm.New(st, checker, c.GoGetWorkers),
+			Retainer:   retainer,
+			Scrubber:   scrubber,
+			Pins:       pins,
+			NotFound:   notFound,
+			Events:     emitter,
+			Verifier:   verifier,
+			Filter:     filter,
+			FilterFile: c.FilterFile,
+			Fs:         fs,
+			TempDir:    c.GoGetDir,
+		}
+		addAdminRoutes(r, adminOpts, adminAuth)
 	}
-	st := stash.New(mf, s, indexer, stash.WithPool(c.GoGetWorkers), withSingleFlight)
 
 	df, err := mode.NewFile(c.DownloadMode, c.DownloadURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	dpOpts := &download.Opts{
-		Storage:      s,
-		Stasher:      st,
-		Lister:       lister,
-		DownloadFile: df,
+		Storage:        s,
+		Stasher:        st,
+		Lister:         lister,
+		DownloadFile:   df,
+		NotFound:       notFound,
+		ListCacheTTL:   config.GetTimeoutDuration(c.ListCacheTTL),
+		ListCacheStale: config.GetTimeoutDuration(c.ListCacheStale),
+		Fs:             fs,
+		TempDir:        c.GoGetDir,
+		Verifier:       verifier,
 	}
 
 	dp := download.New(dpOpts, addons.WithPool(c.ProtocolWorkers))
 
-	handlerOpts := &download.HandlerOpts{Protocol: dp, Logger: l, DownloadFile: df}
+	handlerOpts := &download.HandlerOpts{
+		Protocol:     dp,
+		Logger:       l,
+		DownloadFile: df,
+		Downloads:    downloads,
+	}
+	if c.ZipURLExpiry > 0 {
+		if zu, ok := s.(storage.ZipURLer); ok {
+			handlerOpts.ZipURLs = zu
+			handlerOpts.ZipURLExpiry = config.GetTimeoutDuration(c.ZipURLExpiry)
+		} else {
+			l.Warnf("ZipURLExpiry is set but the %s storage cannot sign zip URLs, zips are served by Athens", c.StorageType)
+		}
+	}
 	download.RegisterHandlers(r, handlerOpts)
 
-	return nil
+	return stop, nil
+}
+
+func getFetcher(c *config.Config, fs afero.Fs) (module.Fetcher, error) {
+	switch c.FetcherType {
+	case "", "go":
+		return module.NewGoGetFetcher(c.GoBinary, c.GoGetDir, c.GoBinaryEnvVars, fs)
+	case "goproxy":
+		var direct module.Fetcher
+		if hasDirect(c.UpstreamGoProxy) {
+			var err error
+			direct, err = module.NewGoGetFetcher(c.GoBinary, c.GoGetDir, c.GoBinaryEnvVars, fs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return module.NewGoProxyFetcher(c.UpstreamGoProxy, upstreamClient(), direct)
+	default:
+		return nil, fmt.Errorf("unrecognized fetcher type: %v", c.FetcherType)
+	}
+}
+
+func getLister(c *config.Config, fs afero.Fs) (module.UpstreamLister, error) {
+	switch c.ListerType {
+	case "", "go":
+		return module.NewVCSLister(c.GoBinary, c.GoBinaryEnvVars, fs), nil
+	case "goproxy":
+		var direct module.UpstreamLister
+		if hasDirect(c.UpstreamGoProxy) {
+			direct = module.NewVCSLister(c.GoBinary, c.GoBinaryEnvVars, fs)
+		}
+		return module.NewGoProxyLister(c.UpstreamGoProxy, upstreamClient(), direct)
+	default:
+		return nil, fmt.Errorf("unrecognized lister type: %v", c.ListerType)
+	}
+}
+
+// upstreamClient returns the http client used
+// to talk to upstream proxies.
+func upstreamClient() *http.Client {
+	return &http.Client{
+		Transport: &ochttp.Transport{
+			Base: http.DefaultTransport,
+		},
+	}
+}
+
+// hasDirect reports whether the given GOPROXY
+// formatted list contains a direct entry.
+func hasDirect(goproxy string) bool {
+	for _, p := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		if strings.TrimSpace(p) == "direct" {
+			return true
+		}
+	}
+	return false
 }
 
 func getSingleFlight(c *config.Config, checker storage.Checker) (stash.Wrapper, error) {
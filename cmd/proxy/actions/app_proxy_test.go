@@ -34,8 +34,9 @@ func TestProxyRoutes(t *testing.T) {
 	c.NoSumPatterns = []string{"*"} // catch all patterns with noSumWrapper to ensure the sumdb handler doesn't make a real http request to the sumdb server.
 	c.PathPrefix = "/prefix"
 	subRouter := r.PathPrefix(c.PathPrefix).Subrouter()
-	err = addProxyRoutes(subRouter, s, l, c)
+	stop, err := addProxyRoutes(subRouter, s, nil, l, c)
 	require.NoError(t, err)
+	defer stop()
 
 	baseURL := "https://athens.azurefd.net" + c.PathPrefix
 
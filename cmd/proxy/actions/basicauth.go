@@ -4,6 +4,7 @@ import (
 	"crypto/subtle"
 	"net/http"
 	"regexp"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -13,10 +14,13 @@ var (
 	basicAuthExcludedPaths = regexp.MustCompile("^/(health|ready)z$")
 )
 
-func basicAuth(user, pass string) mux.MiddlewareFunc {
+// basicAuth returns a middleware that requires the given credentials,
+// except on the health checks and on paths starting with one of the
+// excludedPrefixes.
+func basicAuth(user, pass string, excludedPrefixes ...string) mux.MiddlewareFunc {
 	return func(h http.Handler) http.Handler {
 		f := func(w http.ResponseWriter, r *http.Request) {
-			if !basicAuthExcludedPaths.MatchString(r.URL.Path) && !checkAuth(r, user, pass) {
+			if !isExcluded(r.URL.Path, excludedPrefixes) && !checkAuth(r, user, pass) {
 				w.Header().Set("WWW-Authenticate", `Basic realm="basic auth required"`)
 				w.WriteHeader(http.StatusUnauthorized)
 				return
@@ -28,6 +32,18 @@ func basicAuth(user, pass string) mux.MiddlewareFunc {
 	}
 }
 
+func isExcluded(path string, excludedPrefixes []string) bool {
+	if basicAuthExcludedPaths.MatchString(path) {
+		return true
+	}
+	for _, prefix := range excludedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func checkAuth(r *http.Request, user, pass string) bool {
 	givenUser, givenPass, ok := r.BasicAuth()
 	if !ok {
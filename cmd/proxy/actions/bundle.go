@@ -0,0 +1,84 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gomods/athens/pkg/bundle"
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/index"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+// bundleExportHandler implements GET baseURL/admin/bundle
+//
+// The optional patterns query parameter is a comma separated
+// list of module path patterns, in the format of GOPRIVATE,
+// that selects the modules to export.
+func bundleExportHandler(s storage.Backend, fs afero.Fs, dir string) http.HandlerFunc {
+	const op errors.Op = "actions.BundleExportHandler"
+	_, isCataloger := s.(storage.Cataloger)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isCataloger {
+			w.WriteHeader(errors.KindNotImplemented)
+			return
+		}
+		var patterns []string
+		if p := r.FormValue("patterns"); p != "" {
+			patterns = strings.Split(p, ",")
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", `attachment; filename="athens-bundle.tar.gz"`)
+		manifest, err := bundle.Export(r.Context(), s, patterns, w, fs, dir)
+		lggr := log.EntryFromContext(r.Context())
+		if err != nil {
+			// the response has already started, so the
+			// client only sees a truncated bundle.
+			lggr.SystemErr(errors.E(op, err))
+			return
+		}
+		lggr.Infof("admin exported a bundle of %d module versions", len(manifest.Modules))
+	}
+}
+
+// maxBundleSize is the largest bundle that can be imported.
+const maxBundleSize = 32 << 30
+
+// bundleImportHandler implements POST baseURL/admin/bundle
+//
+// The body is a bundle created by bundleExportHandler. Its versions
+// are checked against the checksum database of verifier, if it is not
+// nil, and imported versions are emitted to emitter and added to the
+// index, as stashed versions are. The response is a report of the
+// versions that were imported, skipped or failed.
+func bundleImportHandler(s storage.Backend, indexer index.Indexer, verifier *checksum.Verifier, emitter events.Emitter, fs afero.Fs, dir string) http.HandlerFunc {
+	const op errors.Op = "actions.BundleImportHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		lggr := log.EntryFromContext(ctx)
+		rep, err := bundle.Import(ctx, s, verifier, emitter, http.MaxBytesReader(w, r.Body, maxBundleSize), fs, dir)
+		if rep != nil {
+			for _, p := range rep.Imported {
+				if err := indexer.Index(ctx, p.Module, p.Version); err != nil && !errors.Is(err, errors.KindAlreadyExists) {
+					lggr.SystemErr(errors.E(op, err, errors.M(p.Module), errors.V(p.Version)))
+				}
+			}
+		}
+		if err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.Infof("admin imported a bundle: %d imported, %d skipped, %d failed", len(rep.Imported), len(rep.Skipped), len(rep.Failed))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rep); err != nil {
+			lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/gomods/athens/pkg/auth"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gorilla/mux"
+)
+
+// startAuth loads the auth file set in c, and reloads it
+// in the background when it changes or on a SIGHUP.
+func startAuth(c *config.Config, client *http.Client, l *log.Logger) (*auth.Store, error) {
+	store, err := auth.NewStore(c.AuthFile, client)
+	if err != nil {
+		return nil, err
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "auth"}))
+	go store.Watch(ctx, config.GetTimeoutDuration(c.AuthReloadInterval), hup)
+	return store, nil
+}
+
+// credentialsAuth returns a middleware that requires the credentials
+// of store, except on the health checks, and checks that they can
+// use the part of the proxy that is requested, see requiredScope.
+// The credential is added to the context of the request.
+func credentialsAuth(store *auth.Store, pathPrefix string) mux.MiddlewareFunc {
+	pathPrefix = strings.TrimSuffix(pathPrefix, "/")
+	return func(h http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			p := strings.TrimPrefix(r.URL.Path, pathPrefix)
+			if isExcluded(p, nil) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			cred, ok := authenticate(store, r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Basic realm="athens"`)
+				http.Error(w, "valid credentials are required", http.StatusUnauthorized)
+				return
+			}
+			lggr := log.EntryFromContext(r.Context()).WithFields(map[string]interface{}{"credential": cred.Name})
+			scope, ok := requiredScope(r, p)
+			if ok && !cred.Has(scope) {
+				lggr.WithFields(map[string]interface{}{"scope": scope}).Infof("credential lacks scope")
+				http.Error(w, "credential "+cred.Name+" does not have the "+string(scope)+" scope", http.StatusForbidden)
+				return
+			}
+			if mod, err := paths.GetModule(r); err == nil && scope == auth.ScopeDownload && !cred.AllowsModule(mod) {
+				lggr.WithFields(map[string]interface{}{"module": mod}).Infof("credential cannot download module")
+				http.Error(w, "credential "+cred.Name+" cannot download "+mod, http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), cred)))
+		}
+		return http.HandlerFunc(f)
+	}
+}
+
+// authenticate returns the credential of a request,
+// from its basic auth or its bearer token.
+func authenticate(store *auth.Store, r *http.Request) (*auth.Credential, bool) {
+	if user, pass, ok := r.BasicAuth(); ok {
+		return store.Authenticate(user, pass)
+	}
+	const bearer = "Bearer "
+	if h := r.Header.Get("Authorization"); len(h) > len(bearer) && strings.EqualFold(h[:len(bearer)], bearer) {
+		return store.AuthenticateToken(strings.TrimSpace(h[len(bearer):]))
+	}
+	return nil, false
+}
+
+// requiredScope returns the scope needed to request p, the path
+// of r without the path prefix, if any. Pages that are not part of
+// the download protocol, the catalog or the admin API, such as the
+// home page, only need a valid credential.
+func requiredScope(r *http.Request, p string) (auth.Scope, bool) {
+	switch {
+	case p == adminPrefix || strings.HasPrefix(p, adminPrefix+"/"):
+		return auth.ScopeAdmin, true
+	case p == "/catalog" || p == "/index" || p == "/stats":
+		return auth.ScopeCatalog, true
+	case strings.HasPrefix(p, "/sumdb/"):
+		return auth.ScopeDownload, true
+	}
+	if _, err := paths.GetModule(r); err == nil {
+		return auth.ScopeDownload, true
+	}
+	return "", false
+}
+
+// requireScope returns a middleware that requires the
+// credential that credentialsAuth found to have scope.
+func requireScope(scope auth.Scope) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, r *http.Request) {
+			cred, ok := auth.FromContext(r.Context())
+			if !ok || !cred.Has(scope) {
+				http.Error(w, "the "+string(scope)+" scope is required", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(f)
+	}
+}
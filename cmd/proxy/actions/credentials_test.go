@@ -0,0 +1,97 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomods/athens/pkg/auth"
+	"github.com/gomods/athens/pkg/download"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestCredentialsAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	authFile := filepath.Join(dir, "auth.toml")
+	require.NoError(t, ioutil.WriteFile(authFile, []byte(`
+[[Credentials]]
+Name = "ops"
+Hash = "`+sha256Hash("ops-token")+`"
+Scopes = ["download", "catalog", "admin"]
+
+[[Credentials]]
+Name = "contractor"
+User = "alice"
+Hash = "`+sha256Hash("alice-password")+`"
+Scopes = ["download"]
+Modules = ["github.com/acme/public-*"]
+`), 0600))
+	store, err := auth.NewStore(authFile, http.DefaultClient)
+	require.NoError(t, err)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		cred, _ := auth.FromContext(r.Context())
+		w.Write([]byte(cred.Name))
+	}
+	r := mux.NewRouter()
+	r.Use(credentialsAuth(store, "/prefix"))
+	pr := r.PathPrefix("/prefix").Subrouter()
+	pr.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+	pr.HandleFunc("/catalog", ok)
+	pr.HandleFunc("/", ok)
+	ar := pr.PathPrefix(adminPrefix).Subrouter()
+	ar.Use(requireScope(auth.ScopeAdmin))
+	ar.HandleFunc("/catalog", ok)
+	pr.HandleFunc(download.PathList, ok)
+
+	tests := []struct {
+		name       string
+		path       string
+		user, pass string
+		bearer     string
+		status     int
+	}{
+		{name: "health checks are public", path: "/prefix/healthz", status: http.StatusOK},
+		{name: "no credentials", path: "/prefix/", status: http.StatusUnauthorized},
+		{name: "wrong password", path: "/prefix/", user: "alice", pass: "nope", status: http.StatusUnauthorized},
+		{name: "home page", path: "/prefix/", user: "alice", pass: "alice-password", status: http.StatusOK},
+		{name: "allowed module", path: "/prefix/github.com/acme/public-api/@v/list", user: "alice", pass: "alice-password", status: http.StatusOK},
+		{name: "forbidden module", path: "/prefix/github.com/acme/private/@v/list", user: "alice", pass: "alice-password", status: http.StatusForbidden},
+		{name: "no catalog scope", path: "/prefix/catalog", user: "alice", pass: "alice-password", status: http.StatusForbidden},
+		{name: "no admin scope", path: "/prefix/admin/catalog", user: "alice", pass: "alice-password", status: http.StatusForbidden},
+		{name: "token as password", path: "/prefix/github.com/acme/private/@v/list", user: "ci", pass: "ops-token", status: http.StatusOK},
+		{name: "bearer token", path: "/prefix/catalog", bearer: "ops-token", status: http.StatusOK},
+		{name: "wrong bearer token", path: "/prefix/catalog", bearer: "alice-password", status: http.StatusUnauthorized},
+		{name: "admin scope", path: "/prefix/admin/catalog", bearer: "ops-token", status: http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, tc.pass)
+			}
+			if tc.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.bearer)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			if tc.status == http.StatusUnauthorized {
+				require.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/spf13/afero"
+	"go.opencensus.io/stats/view"
+)
+
+// startEvents creates the event sinks configured in c and starts
+// delivering events to them in the background. It returns nil
+// if no sink is configured.
+func startEvents(c *config.Config, filesystem afero.Fs, l *log.Logger) (events.Emitter, error) {
+	var sinks []events.Sink
+	if c.EventsWebhookURL != "" {
+		sinks = append(sinks, events.NewWebhook(c.EventsWebhookURL, c.EventsWebhookSecret, c.EventsWebhookRetries, &http.Client{Timeout: c.TimeoutDuration()}))
+	}
+	if c.EventsFile != "" {
+		f, err := events.NewFile(filesystem, c.EventsFile)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, f)
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	if err := view.Register(events.Views...); err != nil {
+		return nil, err
+	}
+	p := events.NewPublisher(c.EventsQueueSize, sinks...)
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "events"}))
+	p.Start(ctx)
+	return p, nil
+}
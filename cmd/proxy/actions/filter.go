@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+)
+
+// startFilter loads the filter file set in c, and reloads
+// it in the background when it changes or on a SIGHUP.
+func startFilter(c *config.Config, l *log.Logger) (*module.Filter, error) {
+	mf, err := module.NewFilter(c.FilterFile)
+	if err != nil {
+		return nil, err
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "filter"}))
+	go mf.Watch(ctx, config.GetTimeoutDuration(c.FilterReloadInterval), hup)
+	return mf, nil
+}
+
+// filterStatus is the response of the admin filter endpoint.
+type filterStatus struct {
+	File     string    `json:"file"`
+	LoadedAt time.Time `json:"loadedAt"`
+	Rules    []string  `json:"rules"`
+}
+
+// adminFilterHandler implements GET baseURL/admin/filter
+//
+// It responds with the rules that the filter currently
+// applies, in the syntax of the filter file, and when
+// they were loaded.
+func adminFilterHandler(mf *module.Filter, file string) http.HandlerFunc {
+	const op errors.Op = "actions.AdminFilterHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules, loadedAt := mf.Rules()
+		if rules == nil {
+			rules = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(filterStatus{File: file, LoadedAt: loadedAt, Rules: rules}); err != nil {
+			log.EntryFromContext(r.Context()).SystemErr(errors.E(op, err))
+		}
+	}
+}
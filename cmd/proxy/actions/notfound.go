@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/sirupsen/logrus"
+)
+
+// getNotFoundCache returns the cache of not found modules and
+// versions set by NotFoundCacheType, or nil if there is none.
+func getNotFoundCache(c *config.Config) (notfound.Cache, error) {
+	if c.NotFoundCacheType == "" || c.NotFoundCacheType == "none" {
+		return nil, nil
+	}
+	if c.NotFoundCacheTTL <= 0 {
+		return nil, fmt.Errorf("NotFoundCacheTTL must be more than 0 with a %v NotFoundCacheType", c.NotFoundCacheType)
+	}
+	ttl := config.GetTimeoutDuration(c.NotFoundCacheTTL)
+	switch c.NotFoundCacheType {
+	case "memory":
+		return notfound.NewMemory(ttl), nil
+	case "redis":
+		if c.SingleFlight == nil || c.SingleFlight.Redis == nil {
+			return nil, fmt.Errorf("Redis config must be present")
+		}
+		return notfound.NewRedis(c.SingleFlight.Redis.Endpoint, c.SingleFlight.Redis.Password, ttl)
+	case "etcd":
+		if c.SingleFlight == nil || c.SingleFlight.Etcd == nil {
+			return nil, fmt.Errorf("Etcd config must be present")
+		}
+		return notfound.NewEtcd(strings.Split(c.SingleFlight.Etcd.Endpoints, ","), ttl)
+	default:
+		return nil, fmt.Errorf("unrecognized not found cache type: %v", c.NotFoundCacheType)
+	}
+}
+
+// adminNotFoundHandler implements DELETE baseURL/admin/notfound
+//
+// It removes a version from the cache of not found modules and
+// versions if the module and version query parameters are set,
+// a module and all of its versions if only the module is set,
+// and everything otherwise.
+func adminNotFoundHandler(c notfound.Cache) http.HandlerFunc {
+	const op errors.Op = "actions.AdminNotFoundHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		mod, ver := r.FormValue("module"), r.FormValue("version")
+		if mod == "" && ver != "" {
+			err := errors.E(op, "a version requires a module", errors.KindBadRequest, logrus.InfoLevel)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		if err := c.Invalidate(r.Context(), mod, ver); err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.WithFields(logrus.Fields{"module": mod, "version": ver}).Infof("admin invalidated not found cache")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
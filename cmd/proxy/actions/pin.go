@@ -0,0 +1,103 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/sirupsen/logrus"
+)
+
+// startPins loads the pin file set in c, and reloads it in the
+// background when it changes or on a SIGHUP. It returns nil if
+// there is no pin file.
+func startPins(c *config.Config, l *log.Logger) (*pin.Store, error) {
+	if c.PinFile == "" {
+		return nil, nil
+	}
+	pins, err := pin.NewStore(c.PinFile)
+	if err != nil {
+		return nil, err
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "pin"}))
+	go pins.Watch(ctx, config.GetTimeoutDuration(c.PinReloadInterval), hup)
+	return pins, nil
+}
+
+// pinsHandler implements GET baseURL/admin/pins
+func pinsHandler(pins *pin.Store) http.HandlerFunc {
+	const op errors.Op = "actions.PinsHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(pins.List()); err != nil {
+			log.EntryFromContext(r.Context()).SystemErr(errors.E(op, err))
+		}
+	}
+}
+
+// adminPinHandler implements PUT baseURL/admin/{module}/@v/{version}/pin
+//
+// The version does not need to be stored yet, and
+// is kept once it is.
+func adminPinHandler(pins *pin.Store) http.HandlerFunc {
+	const op errors.Op = "actions.AdminPinHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		params, err := paths.GetAllParams(r)
+		if err != nil {
+			lggr.SystemErr(errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := pins.Pin(params.Module, params.Version); err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.WithFields(logrus.Fields{"module": params.Module, "version": params.Version}).Infof("admin pinned module version")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// adminUnpinHandler implements DELETE baseURL/admin/{module}/@v/{version}/pin
+func adminUnpinHandler(pins *pin.Store) http.HandlerFunc {
+	const op errors.Op = "actions.AdminUnpinHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		params, err := paths.GetAllParams(r)
+		if err != nil {
+			lggr.SystemErr(errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := pins.Unpin(params.Module, params.Version); err != nil {
+			err = errors.E(op, err, errors.Expect(err, errors.KindNotFound))
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.WithFields(logrus.Fields{"module": params.Module, "version": params.Version}).Infof("admin unpinned module version")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// checkPinned returns an error of KindAlreadyExists
+// if mod@ver is pinned. pins may be nil.
+func checkPinned(pins *pin.Store, mod, ver string) error {
+	const op errors.Op = "actions.checkPinned"
+	if pins != nil && pins.Pinned(mod, ver) {
+		return errors.E(op, "version is pinned", errors.M(mod), errors.V(ver), errors.KindAlreadyExists, logrus.InfoLevel)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/index"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/retention"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/usage"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats/view"
+)
+
+// startRetention creates a retainer for s as configured in c
+// and starts applying the retention rules in the background.
+// Versions are idle if u has no recent downloads of them,
+// and u may be nil if no rule deletes idle versions.
+func startRetention(c *config.Config, s storage.Backend, indexer index.Indexer, u usage.Store, pins *pin.Store, emitter events.Emitter, l *log.Logger) (*retention.Retainer, error) {
+	rules := make([]retention.Rule, 0, len(c.RetentionRules))
+	for _, r := range c.RetentionRules {
+		rules = append(rules, retention.Rule{
+			Patterns:     r.Patterns,
+			MaxIdle:      time.Duration(r.MaxIdleDays) * 24 * time.Hour,
+			KeepNewest:   r.KeepNewest,
+			MaxPseudoAge: time.Duration(r.MaxPseudoAgeDays) * 24 * time.Hour,
+		})
+	}
+	retainer, err := retention.New(&retention.Opts{
+		Storage: s,
+		Indexer: indexer,
+		Usage:   u,
+		Events:  emitter,
+		Pins:    pins,
+		Rules:   rules,
+		DryRun:  c.RetentionDryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := view.Register(retention.Views...); err != nil {
+		return nil, err
+	}
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "retention"}))
+	retainer.Start(ctx, config.GetTimeoutDuration(c.RetentionInterval))
+	return retainer, nil
+}
+
+// retentionHandler implements GET baseURL/admin/retention
+func retentionHandler(retainer *retention.Retainer) http.HandlerFunc {
+	const op errors.Op = "actions.RetentionHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(retainer.Status()); err != nil {
+			log.EntryFromContext(r.Context()).SystemErr(errors.E(op, err))
+		}
+	}
+}
+
+// adminRetentionHandler implements POST baseURL/admin/retention
+//
+// It applies the retention rules once and responds with the result.
+// Nothing is deleted unless the dryrun query parameter is false.
+func adminRetentionHandler(retainer *retention.Retainer) http.HandlerFunc {
+	const op errors.Op = "actions.AdminRetentionHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		dryRun := true
+		if v := r.FormValue("dryrun"); v != "" {
+			var err error
+			dryRun, err = strconv.ParseBool(v)
+			if err != nil {
+				err = errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel)
+				lggr.SystemErr(err)
+				http.Error(w, err.Error(), errors.Kind(err))
+				return
+			}
+		}
+		res, err := retainer.Run(r.Context(), dryRun)
+		if err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.WithFields(map[string]interface{}{
+			"dryRun":  res.DryRun,
+			"checked": res.Checked,
+			"deleted": len(res.Deleted),
+			"errors":  len(res.Errors),
+		}).Infof("admin applied retention rules")
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/scrub"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"go.opencensus.io/stats/view"
+)
+
+// startScrubber creates a scrubber for s as configured
+// in c and starts scrubbing in the background.
+func startScrubber(c *config.Config, s storage.Backend, verifier *checksum.Verifier, pins *pin.Store, emitter events.Emitter, filesystem afero.Fs, l *log.Logger) (*scrub.Scrubber, error) {
+	opts := &scrub.Opts{
+		Storage:  s,
+		Verifier: verifier,
+		Action:   scrub.Action(c.ScrubAction),
+		Events:   emitter,
+		Pins:     pins,
+		Fs:       filesystem,
+		TempDir:  c.GoGetDir,
+	}
+	if opts.Action == scrub.Quarantine {
+		if c.ScrubQuarantine == "" {
+			return nil, fmt.Errorf("ScrubQuarantine must be set to quarantine module versions")
+		}
+		if err := filesystem.MkdirAll(c.ScrubQuarantine, 0777); err != nil {
+			return nil, err
+		}
+		q, err := fs.NewStorage(c.ScrubQuarantine, filesystem)
+		if err != nil {
+			return nil, err
+		}
+		opts.Quarantine = q
+	}
+	scrubber, err := scrub.New(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := view.Register(scrub.Views...); err != nil {
+		return nil, err
+	}
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "scrub"}))
+	scrubber.Start(ctx, config.GetTimeoutDuration(c.ScrubInterval))
+	return scrubber, nil
+}
+
+// scrubHandler implements GET baseURL/admin/scrub
+func scrubHandler(scrubber *scrub.Scrubber) http.HandlerFunc {
+	const op errors.Op = "actions.ScrubHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(scrubber.Status()); err != nil {
+			log.EntryFromContext(r.Context()).SystemErr(errors.E(op, err))
+		}
+	}
+}
+
+// adminScrubHandler implements POST baseURL/admin/scrub
+//
+// It scrubs the storage once and responds with the result.
+func adminScrubHandler(scrubber *scrub.Scrubber) http.HandlerFunc {
+	const op errors.Op = "actions.AdminScrubHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		res, err := scrubber.Run(r.Context())
+		if err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		lggr.WithFields(map[string]interface{}{
+			"checked": res.Checked,
+			"corrupt": len(res.Corrupt),
+			"errors":  len(res.Errors),
+		}).Infof("admin scrubbed the storage")
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
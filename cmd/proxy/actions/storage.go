@@ -8,6 +8,7 @@ import (
 
 	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/storage"
 	"github.com/gomods/athens/pkg/storage/azureblob"
 	"github.com/gomods/athens/pkg/storage/external"
@@ -16,7 +17,9 @@ import (
 	"github.com/gomods/athens/pkg/storage/mem"
 	"github.com/gomods/athens/pkg/storage/minio"
 	"github.com/gomods/athens/pkg/storage/mongo"
+	"github.com/gomods/athens/pkg/storage/replicated"
 	"github.com/gomods/athens/pkg/storage/s3"
+	"github.com/gomods/athens/pkg/storage/tiered"
 	"github.com/spf13/afero"
 )
 
@@ -67,7 +70,81 @@ func GetStorage(storageType string, storageConfig *config.Storage, timeout time.
 			return nil, errors.E(op, "Invalid External Storage Configuration")
 		}
 		return external.NewClient(storageConfig.External.URL, client), nil
+	case "tiered":
+		if storageConfig.Tiered == nil {
+			return nil, errors.E(op, "Invalid Tiered Storage Configuration")
+		}
+		return getTieredStorage(storageConfig, timeout, client)
+	case "replicated":
+		if storageConfig.Replicated == nil {
+			return nil, errors.E(op, "Invalid Replicated Storage Configuration")
+		}
+		return getReplicatedStorage(storageConfig, timeout, client)
 	default:
 		return nil, fmt.Errorf("storage type %s is unknown", storageType)
 	}
 }
+
+// getTieredStorage returns a tiered storage in front of
+// the backend of type RemoteStorageType.
+func getTieredStorage(storageConfig *config.Storage, timeout time.Duration, client *http.Client) (storage.Backend, error) {
+	const op errors.Op = "actions.getTieredStorage"
+	conf := storageConfig.Tiered
+	if conf.RemoteStorageType == "tiered" {
+		return nil, errors.E(op, "the remote storage of a tiered storage cannot be tiered")
+	}
+	remote, err := GetStorage(conf.RemoteStorageType, storageConfig, timeout, client)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	filesystem, root := afero.NewOsFs(), conf.LocalPath
+	if root == "" {
+		filesystem = afero.NewMemMapFs()
+		if root, err = afero.TempDir(filesystem, "", ""); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+	local, err := fs.NewStorage(root, filesystem)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return tiered.New(context.Background(), local, remote, int64(conf.MaxSizeMB)<<20)
+}
+
+// getReplicatedStorage returns a replicated storage of the
+// backends of type PrimaryStorageType and SecondaryStorageTypes.
+// The secondaries are configured by Secondaries, if they are set.
+func getReplicatedStorage(storageConfig *config.Storage, timeout time.Duration, client *http.Client) (storage.Backend, error) {
+	const op errors.Op = "actions.getReplicatedStorage"
+	conf := storageConfig.Replicated
+	if conf.PrimaryStorageType == "replicated" {
+		return nil, errors.E(op, "a replicated storage cannot replicate a replicated storage")
+	}
+	primary, err := GetStorage(conf.PrimaryStorageType, storageConfig, timeout, client)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var secondaries []storage.Backend
+	for i, st := range conf.SecondaryStorageTypes {
+		if st == "replicated" {
+			return nil, errors.E(op, "a replicated storage cannot replicate a replicated storage")
+		}
+		b, err := GetStorage(st, conf.SecondaryStorage(i, storageConfig), timeout, client)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		secondaries = append(secondaries, b)
+	}
+	return replicated.New(primary, secondaries, conf.Async), nil
+}
+
+// startRepair periodically repairs the secondaries of s
+// if it is a replicated storage with a repair interval.
+func startRepair(c *config.Config, s storage.Backend, l *log.Logger) {
+	rs, ok := s.(*replicated.Storage)
+	if !ok || c.Storage.Replicated.RepairInterval <= 0 {
+		return
+	}
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "repair"}))
+	rs.StartRepair(ctx, config.GetTimeoutDuration(c.Storage.Replicated.RepairInterval))
+}
@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/usage"
+	usagemem "github.com/gomods/athens/pkg/usage/mem"
+	usagemysql "github.com/gomods/athens/pkg/usage/mysql"
+	usagepostgres "github.com/gomods/athens/pkg/usage/postgres"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// usageFlushInterval is how often download
+	// counts are added to the usage store.
+	usageFlushInterval = 10 * time.Second
+	defaultStatsTop    = 10
+	maxStatsTop        = 1000
+)
+
+// getUsageStore returns the usage store set in c,
+// or nil if download counting is disabled.
+func getUsageStore(c *config.Config) (usage.Store, error) {
+	switch c.UsageType {
+	case "", "none":
+		return nil, nil
+	case "memory":
+		return usagemem.New(), nil
+	case "mysql":
+		return usagemysql.New(c.Index.MySQL)
+	case "postgres":
+		return usagepostgres.New(c.Index.Postgres)
+	}
+	return nil, fmt.Errorf("unknown usage type: %q", c.UsageType)
+}
+
+// startUsage returns a recorder that counts downloads in s, and flushes
+// it in the background. Calling the returned func flushes it once more and
+// waits for that, so that the last downloads are counted on shutdown.
+func startUsage(s usage.Store, l *log.Logger) (*usage.Recorder, func()) {
+	rec := usage.NewRecorder(s)
+	ctx := log.SetEntryInContext(context.Background(), l.WithFields(map[string]interface{}{"component": "usage"}))
+	ctx, cancel := context.WithCancel(ctx)
+	done := rec.Start(ctx, usageFlushInterval)
+	return rec, func() {
+		cancel()
+		<-done
+	}
+}
+
+// statsResponse is the body of GET baseURL/stats
+type statsResponse struct {
+	Since     *time.Time     `json:"since,omitempty"`
+	Until     *time.Time     `json:"until,omitempty"`
+	Downloads []*usage.Count `json:"downloads"`
+}
+
+// statsHandler implements GET baseURL/stats
+func statsHandler(s usage.Store) http.HandlerFunc {
+	const op errors.Op = "actions.StatsHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		lggr := log.EntryFromContext(r.Context())
+		q, err := getStatsQuery(r, time.Now())
+		if err != nil {
+			err = errors.E(op, err, errors.KindBadRequest, logrus.InfoLevel)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		counts, err := s.Top(r.Context(), q)
+		if err != nil {
+			err = errors.E(op, err)
+			lggr.SystemErr(err)
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		resp := &statsResponse{Downloads: counts}
+		if !q.Since.IsZero() {
+			resp.Since = &q.Since
+		}
+		if !q.Until.IsZero() {
+			resp.Until = &q.Until
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
+
+func getStatsQuery(r *http.Request, now time.Time) (*usage.Query, error) {
+	q := &usage.Query{Module: r.FormValue("module"), Limit: defaultStatsTop}
+	var err error
+	if top := r.FormValue("top"); top != "" {
+		q.Limit, err = strconv.Atoi(top)
+		if err != nil || q.Limit <= 0 || q.Limit > maxStatsTop {
+			return nil, fmt.Errorf("top must be between 1 and %d", maxStatsTop)
+		}
+	}
+	switch by := r.FormValue("by"); by {
+	case "", "version":
+	case "module":
+		q.ByModule = true
+	default:
+		return nil, fmt.Errorf("by must be module or version, not %q", by)
+	}
+	if window := r.FormValue("window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid window %q", window)
+		}
+		q.Since = now.Add(-d)
+	}
+	if since := r.FormValue("since"); since != "" {
+		if !q.Since.IsZero() {
+			return nil, fmt.Errorf("since and window cannot both be set")
+		}
+		q.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if until := r.FormValue("until"); until != "" {
+		q.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
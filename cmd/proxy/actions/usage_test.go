@@ -0,0 +1,59 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/usage"
+	usagemem "github.com/gomods/athens/pkg/usage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	s := usagemem.New()
+	hour := time.Now().UTC().Truncate(time.Hour)
+	require.NoError(t, s.Add(context.Background(), []*usage.Increment{
+		{Module: "github.com/pkg/errors", Version: "v0.9.1", Hour: hour, Zips: 3, LastAccess: hour},
+		{Module: "github.com/pkg/errors", Version: "v0.8.0", Hour: hour.Add(-48 * time.Hour), Zips: 5, LastAccess: hour},
+		{Module: "github.com/gomods/athens", Version: "v0.9.0", Hour: hour, Zips: 1, LastAccess: hour},
+	}))
+
+	tests := []struct {
+		query string
+		code  int
+		want  []string
+	}{
+		{query: "", code: http.StatusOK, want: []string{"github.com/pkg/errors@v0.8.0", "github.com/pkg/errors@v0.9.1", "github.com/gomods/athens@v0.9.0"}},
+		{query: "?top=1", code: http.StatusOK, want: []string{"github.com/pkg/errors@v0.8.0"}},
+		{query: "?window=24h", code: http.StatusOK, want: []string{"github.com/pkg/errors@v0.9.1", "github.com/gomods/athens@v0.9.0"}},
+		{query: "?by=module", code: http.StatusOK, want: []string{"github.com/pkg/errors@", "github.com/gomods/athens@"}},
+		{query: "?module=github.com/gomods/athens", code: http.StatusOK, want: []string{"github.com/gomods/athens@v0.9.0"}},
+		{query: "?until=" + hour.Add(-time.Hour).Format(time.RFC3339), code: http.StatusOK, want: []string{"github.com/pkg/errors@v0.8.0"}},
+		{query: "?top=0", code: http.StatusBadRequest},
+		{query: "?by=day", code: http.StatusBadRequest},
+		{query: "?window=yesterday", code: http.StatusBadRequest},
+		{query: "?window=24h&since=" + hour.Format(time.RFC3339), code: http.StatusBadRequest},
+	}
+	handler := statsHandler(s)
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodGet, "/stats"+tc.query, nil))
+			require.Equal(t, tc.code, w.Code)
+			if tc.code != http.StatusOK {
+				return
+			}
+			var resp statsResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			got := []string{}
+			for _, c := range resp.Downloads {
+				got = append(got, c.Module+"@"+c.Version)
+			}
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	_ "net/http/pprof"
@@ -33,7 +34,7 @@ func main() {
 		log.Fatalf("could not load config file: %v", err)
 	}
 
-	handler, err := actions.App(conf)
+	handler, stop, err := actions.App(conf)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -51,13 +52,17 @@ func main() {
 
 	go func() {
 		sigint := make(chan os.Signal, 1)
-		signal.Notify(sigint, os.Interrupt)
+		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
 		<-sigint
 
 		// We received an interrupt signal, shut down.
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 		defer cancel()
-		if err := srv.Shutdown(ctx); err != nil {
+		err := srv.Shutdown(ctx)
+		// no more requests are served, so the
+		// background work can be stopped.
+		stop()
+		if err != nil {
 			log.Fatal(err)
 		}
 		close(idleConnsClosed)
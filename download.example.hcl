@@ -2,8 +2,16 @@ downloadURL = "https://proxy.golang.org"
 
 mode = "async_redirect"
 
+# the version lists of modules are cached for 5 minutes,
+# and then served for another hour while they are refreshed.
+listCacheTTL = "5m"
+listCacheStale = "1h"
+
 download "github.com/gomods/*" {
     mode = "sync"
+    # new tags must be listed right away.
+    listCacheTTL = "0s"
+    listCacheStale = "0s"
 }
 
 download "golang.org/x/*" {
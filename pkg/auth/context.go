@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx that carries c.
+func NewContext(ctx context.Context, c *Credential) context.Context {
+	return context.WithValue(ctx, ctxKey{}, c)
+}
+
+// FromContext returns the credential that ctx carries, if any.
+func FromContext(ctx context.Context) (*Credential, bool) {
+	c, ok := ctx.Value(ctxKey{}).(*Credential)
+	return c, ok
+}
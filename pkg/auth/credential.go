@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gomods/athens/pkg/paths"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Scope is a part of the proxy that a credential can use.
+type Scope string
+
+const (
+	// ScopeDownload lets a credential use the download protocol
+	// and the checksum database proxy.
+	ScopeDownload Scope = "download"
+	// ScopeCatalog lets a credential list the modules
+	// of the proxy, through its catalog and its index.
+	ScopeCatalog Scope = "catalog"
+	// ScopeAdmin lets a credential use the admin API.
+	ScopeAdmin Scope = "admin"
+)
+
+// sha256Prefix is the prefix of the SHA-256 hashes of credentials.
+const sha256Prefix = "sha256:"
+
+// Credential is a user or a token of the auth file.
+type Credential struct {
+	// Name identifies the credential in logs.
+	Name string
+	// User is the user name of the credential,
+	// or empty if the credential is a token.
+	User string
+	// Hash is the hash of the password or of the token, either a bcrypt
+	// hash, or "sha256:" followed by the hex encoded SHA-256 hash. Tokens
+	// must have SHA-256 hashes, so that they are found by their hash.
+	Hash   string
+	Scopes []Scope
+	// Modules are the glob patterns, as in GOPRIVATE, of the modules
+	// that the credential can download. All modules can be downloaded
+	// if it is empty.
+	Modules []string
+}
+
+// Has reports whether c has scope s.
+func (c *Credential) Has(s Scope) bool {
+	for _, cs := range c.Scopes {
+		if cs == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsModule reports whether c can download mod.
+func (c *Credential) AllowsModule(mod string) bool {
+	if len(c.Modules) == 0 {
+		return true
+	}
+	for _, p := range c.Modules {
+		if paths.MatchesPattern(p, mod) {
+			return true
+		}
+	}
+	return false
+}
+
+// verify reports whether secret matches the hash of c.
+func (c *Credential) verify(secret string) bool {
+	if strings.HasPrefix(c.Hash, sha256Prefix) {
+		sum := sha256.Sum256([]byte(secret))
+		want, err := hex.DecodeString(strings.TrimPrefix(c.Hash, sha256Prefix))
+		return err == nil && subtle.ConstantTimeCompare(sum[:], want) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(c.Hash), []byte(secret)) == nil
+}
+
+func (c *Credential) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("a credential has no name")
+	}
+	if strings.HasPrefix(c.Hash, sha256Prefix) {
+		h, err := hex.DecodeString(strings.TrimPrefix(c.Hash, sha256Prefix))
+		if err != nil || len(h) != sha256.Size {
+			return fmt.Errorf("credential %q: invalid SHA-256 hash", c.Name)
+		}
+	} else if c.User == "" {
+		return fmt.Errorf("credential %q: the hash of a token must start with %q", c.Name, sha256Prefix)
+	} else if _, err := bcrypt.Cost([]byte(c.Hash)); err != nil {
+		return fmt.Errorf("credential %q: the hash must be a bcrypt hash or start with %q", c.Name, sha256Prefix)
+	}
+	return validateGrants("credential "+strconv.Quote(c.Name), c.Scopes, c.Modules)
+}
+
+// validateGrants checks the scopes and the module patterns of what.
+func validateGrants(what string, scopes []Scope, modules []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("%s has no scopes", what)
+	}
+	for _, s := range scopes {
+		switch s {
+		case ScopeDownload, ScopeCatalog, ScopeAdmin:
+		default:
+			return fmt.Errorf("%s: unknown scope %q", what, s)
+		}
+	}
+	for _, p := range modules {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%s: invalid module pattern %q", what, p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+// Package auth authenticates the clients of the proxy with the
+// credentials of an auth file, and decides what each of them may do.
+//
+// A credential is either a user with a password or an API token. Both
+// are sent with HTTP basic auth, the token as the password with any
+// user name, and tokens can also be sent as bearer tokens. Only hashes
+// of the passwords and tokens are kept in the file. Tokens are hashed
+// with SHA-256, so that a token is found by its hash instead of being
+// checked against every token.
+//
+// Each credential has scopes, which are the parts of the proxy that
+// it can use, and optionally module path patterns, which restrict the
+// modules that it can download.
+//
+// The auth file can also accept JSON Web Tokens, such as the OpenID
+// Connect ID tokens of CI systems, signed by the keys of a JSON Web
+// Key Set. Rules on their claims grant them scopes and modules.
+package auth
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// minKeysRefresh is the minimum time between two fetches of
+	// a key set, so that unknown key IDs cannot flood its source.
+	minKeysRefresh = time.Minute
+	// keysTimeout is the timeout of the requests for a key set.
+	keysTimeout = 10 * time.Second
+)
+
+// keySet is a JSON Web Key Set read from a file or a URL. It is
+// fetched again when it is older than refresh, in the background,
+// or when a token is signed with a key it does not have, for when
+// keys are rotated. Fetches never block the tokens whose keys are
+// already known.
+type keySet struct {
+	source  string
+	client  *http.Client
+	refresh time.Duration
+	now     func() time.Time
+
+	// mu guards the fields below. It is
+	// never held while the keys are fetched.
+	mu      sync.Mutex
+	keys    []jose.JSONWebKey
+	fetched time.Time
+	// tried is when the last fetch started.
+	tried time.Time
+}
+
+func newKeySet(source string, client *http.Client, refresh time.Duration) (*keySet, error) {
+	ks := &keySet{source: source, client: client, refresh: refresh, now: time.Now}
+	keys, err := ks.fetch()
+	if err != nil {
+		return nil, err
+	}
+	ks.keys = keys
+	ks.fetched = ks.now()
+	ks.tried = ks.fetched
+	return ks, nil
+}
+
+// key returns the keys with ID kid, or the only
+// key of the set if kid is empty.
+func (ks *keySet) key(kid string) []jose.JSONWebKey {
+	ks.mu.Lock()
+	keys := ks.find(kid)
+	now := ks.now()
+	update := (len(keys) == 0 || now.Sub(ks.fetched) > ks.refresh) && now.Sub(ks.tried) >= minKeysRefresh
+	if update {
+		ks.tried = now
+	}
+	ks.mu.Unlock()
+	if !update {
+		return keys
+	}
+	if len(keys) > 0 {
+		// the current keys are good until the new ones are in
+		go ks.update()
+		return keys
+	}
+	ks.update()
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.find(kid)
+}
+
+// update fetches the keys again, and keeps
+// the current ones if the source is unavailable.
+func (ks *keySet) update() {
+	keys, err := ks.fetch()
+	if err != nil {
+		return
+	}
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.fetched = ks.now()
+	ks.mu.Unlock()
+}
+
+func (ks *keySet) find(kid string) []jose.JSONWebKey {
+	if kid == "" {
+		if len(ks.keys) == 1 {
+			return ks.keys
+		}
+		return nil
+	}
+	var keys []jose.JSONWebKey
+	for _, k := range ks.keys {
+		if k.KeyID == kid {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (ks *keySet) fetch() ([]jose.JSONWebKey, error) {
+	var b []byte
+	var err error
+	if strings.HasPrefix(ks.source, "https://") || strings.HasPrefix(ks.source, "http://") {
+		b, err = ks.get()
+	} else {
+		b, err = ioutil.ReadFile(ks.source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseKeySet(b)
+}
+
+func (ks *keySet) get() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), keysTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", ks.source, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// parseKeySet returns the public signing keys of a JSON Web Key Set.
+// Encryption keys and keys of unsupported types are ignored, and so
+// are symmetric keys, which would let anyone who can read the set
+// sign tokens.
+func parseKeySet(b []byte) ([]jose.JSONWebKey, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid key set: %v", err)
+	}
+	var keys []jose.JSONWebKey
+	for _, raw := range set.Keys {
+		var k jose.JSONWebKey
+		if err := k.UnmarshalJSON(raw); err != nil || k.Use == "enc" || !k.IsPublic() {
+			continue
+		}
+		if rk, ok := k.Key.(*rsa.PublicKey); ok && rk.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("invalid key %q: RSA keys must have at least 2048 bits", k.KeyID)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("the key set has no public signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	// defaultKeysRefresh is the default number of
+	// seconds after which a key set is fetched again.
+	defaultKeysRefresh = 3600
+	// jwtLeeway is the clock skew allowed when
+	// checking the times of a token.
+	jwtLeeway = time.Minute
+)
+
+// JWT configures the JSON Web Tokens, such as OpenID Connect ID
+// tokens, that the auth file accepts, and the rules that map
+// their claims to scopes and modules.
+type JWT struct {
+	// Keys is the file or the URL of the JSON
+	// Web Key Set whose keys sign the tokens.
+	Keys string
+	// Issuer must be the "iss" claim of the tokens.
+	Issuer string
+	// Audience must be in the "aud" claim of the tokens.
+	Audience string
+	// KeysRefresh is the number of seconds after which the keys
+	// are fetched again, 3600 if it is zero. They are also fetched
+	// again, at most once a minute, when a token is signed with
+	// an unknown key.
+	KeysRefresh int
+	Rules       []*JWTRule
+}
+
+// JWTRule grants scopes and modules to the tokens whose claims match.
+// A token that matches several rules gets the scopes and the modules
+// of all of them, and a token that matches none is rejected.
+type JWTRule struct {
+	// Name identifies the rule in logs.
+	Name string
+	// Claims maps the names of claims to the glob patterns, as in
+	// path.Match, that they must all match. A claim that is a list
+	// matches if one of its values matches.
+	Claims  map[string]string
+	Scopes  []Scope
+	Modules []string
+}
+
+func (r *JWTRule) matches(claims map[string]interface{}) bool {
+	for name, pattern := range r.Claims {
+		if !claimMatches(pattern, claims[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func claimMatches(pattern string, v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, e := range v {
+			if claimMatches(pattern, e) {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		return false
+	case float64:
+		ok, _ := path.Match(pattern, strconv.FormatFloat(v, 'f', -1, 64))
+		return ok
+	default:
+		ok, _ := path.Match(pattern, fmt.Sprint(v))
+		return ok
+	}
+}
+
+func (j *JWT) validate() error {
+	if j.Keys == "" || j.Issuer == "" || j.Audience == "" {
+		return fmt.Errorf("JWT: Keys, Issuer and Audience are required")
+	}
+	if j.KeysRefresh < 0 {
+		return fmt.Errorf("JWT: KeysRefresh must not be negative")
+	}
+	if len(j.Rules) == 0 {
+		return fmt.Errorf("JWT: no rules grant anything to the tokens")
+	}
+	names := map[string]bool{}
+	for _, r := range j.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("JWT: a rule has no name")
+		}
+		if names[r.Name] {
+			return fmt.Errorf("JWT rule %q is defined twice", r.Name)
+		}
+		names[r.Name] = true
+		for name, pattern := range r.Claims {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("JWT rule %q: invalid pattern %q for claim %q", r.Name, pattern, name)
+			}
+		}
+		if err := validateGrants("JWT rule "+strconv.Quote(r.Name), r.Scopes, r.Modules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jwtVerifier checks the tokens of a JWT configuration.
+type jwtVerifier struct {
+	conf *JWT
+	keys *keySet
+	now  func() time.Time
+}
+
+func newJWTVerifier(conf *JWT, client *http.Client) (*jwtVerifier, error) {
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	refresh := conf.KeysRefresh
+	if refresh == 0 {
+		refresh = defaultKeysRefresh
+	}
+	keys, err := newKeySet(conf.Keys, client, time.Duration(refresh)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("JWT: loading the keys: %v", err)
+	}
+	return &jwtVerifier{conf: conf, keys: keys, now: time.Now}, nil
+}
+
+// looksLikeJWT reports whether s is shaped like a signed JWT,
+// whose header is always a base64url encoded JSON object.
+func looksLikeJWT(s string) bool {
+	return strings.HasPrefix(s, "eyJ") && strings.Count(s, ".") == 2
+}
+
+// authenticate returns the credential that the rules grant to token.
+func (v *jwtVerifier) authenticate(token string) (*Credential, bool) {
+	claims, err := v.verify(token)
+	if err != nil {
+		return nil, false
+	}
+	var names []string
+	c := &Credential{}
+	allModules := false
+	for _, r := range v.conf.Rules {
+		if !r.matches(claims) {
+			continue
+		}
+		names = append(names, r.Name)
+		for _, s := range r.Scopes {
+			if !c.Has(s) {
+				c.Scopes = append(c.Scopes, s)
+			}
+		}
+		if len(r.Modules) == 0 {
+			allModules = true
+		}
+		c.Modules = append(c.Modules, r.Modules...)
+	}
+	if len(names) == 0 {
+		return nil, false
+	}
+	if allModules {
+		c.Modules = nil
+	}
+	c.Name = fmt.Sprintf("%s (%v)", strings.Join(names, ","), claims["sub"])
+	return c, true
+}
+
+// jwtAlgorithms are the signature algorithms that tokens may use.
+var jwtAlgorithms = map[string]bool{
+	string(jose.RS256): true, string(jose.RS384): true, string(jose.RS512): true,
+	string(jose.PS256): true, string(jose.PS384): true, string(jose.PS512): true,
+	string(jose.ES256): true, string(jose.ES384): true, string(jose.ES512): true,
+	string(jose.EdDSA): true,
+}
+
+// verify checks the signature, the issuer, the audience
+// and the times of token, and returns its claims.
+func (v *jwtVerifier) verify(token string) (map[string]interface{}, error) {
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(tok.Headers) != 1 {
+		return nil, fmt.Errorf("tokens must have a single signature")
+	}
+	header := tok.Headers[0]
+	if !jwtAlgorithms[header.Algorithm] {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Algorithm)
+	}
+	var claims jwt.Claims
+	var all map[string]interface{}
+	err = fmt.Errorf("unknown key %q", header.KeyID)
+	for _, k := range v.keys.key(header.KeyID) {
+		// the key type must match the algorithm, so a
+		// token cannot choose how its signature is checked.
+		if err = tok.Claims(k.Key, &claims, &all); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.Expiry == nil {
+		return nil, fmt.Errorf("the token has no expiration time")
+	}
+	err = claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   v.conf.Issuer,
+		Audience: jwt.Audience{v.conf.Audience},
+		Time:     v.now(),
+	}, jwtLeeway)
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
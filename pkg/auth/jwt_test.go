@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "https://athens.example.com"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// testJWKS returns a key set of keys, which
+// are private keys or symmetric secrets.
+func testJWKS(t *testing.T, keys map[string]interface{}) []byte {
+	t.Helper()
+	var set jose.JSONWebKeySet
+	for kid, key := range keys {
+		if signer, ok := key.(crypto.Signer); ok {
+			key = signer.Public()
+		}
+		set.Keys = append(set.Keys, jose.JSONWebKey{Key: key, KeyID: kid})
+	}
+	b, err := json.Marshal(set)
+	require.NoError(t, err)
+	return b
+}
+
+// signJWT returns a token of claims signed by key with alg.
+func signJWT(t *testing.T, alg jose.SignatureAlgorithm, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	opts := &jose.SignerOptions{}
+	if kid != "" {
+		opts.WithHeader("kid", kid)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, opts.WithType("JWT"))
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func testClaims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":              testIssuer,
+		"aud":              testAudience,
+		"sub":              "repo:acme/app:ref:refs/heads/main",
+		"exp":              time.Now().Add(time.Hour).Unix(),
+		"repository_owner": "acme",
+		"ref":              "refs/heads/main",
+		"groups":           []string{"developers", "athens-admins"},
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func jwtAuthFile(keys string) string {
+	return fmt.Sprintf(`
+[JWT]
+Keys = %q
+Issuer = %q
+Audience = %q
+
+[[JWT.Rules]]
+Name = "acme-ci"
+Scopes = ["download"]
+Modules = ["github.com/acme/*"]
+[JWT.Rules.Claims]
+repository_owner = "acme"
+ref = "refs/heads/*"
+
+[[JWT.Rules]]
+Name = "admins"
+Scopes = ["admin", "catalog"]
+Modules = ["github.com/acme-internal/*"]
+[JWT.Rules.Claims]
+groups = "athens-admins"
+`, keys, testIssuer, testAudience)
+}
+
+func TestJWT(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	secret := []byte("a symmetric secret that is public in the key set")
+	keysPath := filepath.Join(dir, "jwks.json")
+	keys := testJWKS(t, map[string]interface{}{"rsa": rsaKey, "ec": ecKey, "ed": edKey, "oct": secret})
+	require.NoError(t, ioutil.WriteFile(keysPath, keys, 0600))
+	s, err := NewStore(writeAuthFile(t, dir, jwtAuthFile(keysPath)), http.DefaultClient)
+	require.NoError(t, err)
+
+	valid := signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(nil))
+	tests := []struct {
+		name    string
+		token   string
+		scopes  []Scope
+		modules []string
+	}{
+		{
+			name:    "rules are merged",
+			token:   valid,
+			scopes:  []Scope{ScopeDownload, ScopeAdmin, ScopeCatalog},
+			modules: []string{"github.com/acme/*", "github.com/acme-internal/*"},
+		},
+		{
+			name:    "ES256",
+			token:   signJWT(t, jose.ES256, "ec", ecKey, testClaims(map[string]interface{}{"groups": nil})),
+			scopes:  []Scope{ScopeDownload},
+			modules: []string{"github.com/acme/*"},
+		},
+		{
+			name:    "EdDSA",
+			token:   signJWT(t, jose.EdDSA, "ed", edKey, testClaims(map[string]interface{}{"groups": nil})),
+			scopes:  []Scope{ScopeDownload},
+			modules: []string{"github.com/acme/*"},
+		},
+		{
+			name:    "audience list",
+			token:   signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"aud": []string{"other", testAudience}, "groups": nil})),
+			scopes:  []Scope{ScopeDownload},
+			modules: []string{"github.com/acme/*"},
+		},
+		{
+			name:    "glob in claim pattern does not match",
+			token:   signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"ref": "refs/pull/1/merge"})),
+			scopes:  []Scope{ScopeAdmin, ScopeCatalog},
+			modules: []string{"github.com/acme-internal/*"},
+		},
+		{name: "no rule matches", token: signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"repository_owner": "evil", "groups": nil}))},
+		{name: "wrong issuer", token: signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"iss": "https://evil.example.com"}))},
+		{name: "wrong audience", token: signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"aud": "other"}))},
+		{name: "expired", token: signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}))},
+		{name: "no expiration", token: signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"exp": nil}))},
+		{name: "not valid yet", token: signJWT(t, jose.RS256, "rsa", rsaKey, testClaims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()}))},
+		{name: "unknown key", token: signJWT(t, jose.RS256, "other", otherKey, testClaims(nil))},
+		{name: "signed by another key", token: signJWT(t, jose.RS256, "rsa", otherKey, testClaims(nil))},
+		{name: "algorithm does not match the key", token: signJWT(t, jose.ES256, "rsa", ecKey, testClaims(nil))},
+		{name: "symmetric key", token: signJWT(t, jose.HS256, "oct", secret, testClaims(nil))},
+		{name: "no kid with several keys", token: signJWT(t, jose.RS256, "", rsaKey, testClaims(nil))},
+		{
+			name: "tampered claims",
+			token: func() string {
+				parts := strings.Split(valid, ".")
+				c, _ := json.Marshal(testClaims(map[string]interface{}{"repository_owner": "evil"}))
+				return parts[0] + "." + b64(c) + "." + parts[2]
+			}(),
+		},
+		{
+			name: "none algorithm",
+			token: func() string {
+				h, _ := json.Marshal(map[string]string{"alg": "none"})
+				c, _ := json.Marshal(testClaims(nil))
+				return b64(h) + "." + b64(c) + "."
+			}(),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, auth := range []func() (*Credential, bool){
+				func() (*Credential, bool) { return s.AuthenticateToken(tc.token) },
+				func() (*Credential, bool) { return s.Authenticate("anyone", tc.token) },
+			} {
+				c, ok := auth()
+				require.Equal(t, tc.scopes != nil, ok)
+				if ok {
+					require.Equal(t, tc.scopes, c.Scopes)
+					require.Equal(t, tc.modules, c.Modules)
+				}
+			}
+		})
+	}
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	jwks := testJWKS(t, map[string]interface{}{"old": oldKey})
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		w.Write(jwks)
+	}))
+	defer srv.Close()
+	s, err := NewStore(writeAuthFile(t, dir, jwtAuthFile(srv.URL)), srv.Client())
+	require.NoError(t, err)
+
+	_, ok := s.AuthenticateToken(signJWT(t, jose.RS256, "old", oldKey, testClaims(nil)))
+	require.True(t, ok)
+
+	mu.Lock()
+	jwks = testJWKS(t, map[string]interface{}{"old": oldKey, "new": newKey})
+	mu.Unlock()
+	token := signJWT(t, jose.RS256, "new", newKey, testClaims(nil))
+	// the keys were fetched less than a minute ago
+	_, ok = s.AuthenticateToken(token)
+	require.False(t, ok)
+
+	ks := s.load().jwt.keys
+	ks.now = func() time.Time { return time.Now().Add(minKeysRefresh) }
+	_, ok = s.AuthenticateToken(token)
+	require.True(t, ok)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 2, fetches)
+}
+
+func TestJWTRefreshDoesNotBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := testJWKS(t, map[string]interface{}{"rsa": key})
+
+	release := make(chan struct{})
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			// an issuer that hangs while the keys are refreshed
+			<-release
+		}
+		w.Write(jwks)
+	}))
+	defer srv.Close()
+	defer close(release)
+	s, err := NewStore(writeAuthFile(t, dir, jwtAuthFile(srv.URL)), srv.Client())
+	require.NoError(t, err)
+
+	ks := s.load().jwt.keys
+	ks.now = func() time.Time { return time.Now().Add(time.Duration(defaultKeysRefresh) * time.Second * 2) }
+	token := signJWT(t, jose.RS256, "rsa", key, testClaims(nil))
+	for i := 0; i < 3; i++ {
+		done := make(chan bool)
+		go func() {
+			_, ok := s.AuthenticateToken(token)
+			done <- ok
+		}()
+		select {
+		case ok := <-done:
+			require.True(t, ok, "known keys must be used while they are refreshed")
+		case <-time.After(5 * time.Second):
+			t.Fatal("authentication waited for the keys to be refreshed")
+		}
+	}
+}
+
+func TestJWTInvalidConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keysPath := filepath.Join(dir, "jwks.json")
+	require.NoError(t, ioutil.WriteFile(keysPath, testJWKS(t, map[string]interface{}{"rsa": key}), 0600))
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	weakPath := filepath.Join(dir, "weak.json")
+	require.NoError(t, ioutil.WriteFile(weakPath, testJWKS(t, map[string]interface{}{"rsa": weakKey}), 0600))
+	symmetricPath := filepath.Join(dir, "symmetric.json")
+	require.NoError(t, ioutil.WriteFile(symmetricPath, testJWKS(t, map[string]interface{}{"oct": []byte("secret")}), 0600))
+
+	rule := "\n[[JWT.Rules]]\nName = \"ci\"\nScopes = [\"download\"]\n"
+	tests := map[string]string{
+		"no issuer":           fmt.Sprintf("[JWT]\nKeys = %q\nAudience = \"a\"\n", keysPath) + rule,
+		"no rules":            fmt.Sprintf("[JWT]\nKeys = %q\nIssuer = \"i\"\nAudience = \"a\"\n", keysPath),
+		"missing keys":        fmt.Sprintf("[JWT]\nKeys = %q\nIssuer = \"i\"\nAudience = \"a\"\n", filepath.Join(dir, "nope.json")) + rule,
+		"weak key":            fmt.Sprintf("[JWT]\nKeys = %q\nIssuer = \"i\"\nAudience = \"a\"\n", weakPath) + rule,
+		"symmetric keys only": fmt.Sprintf("[JWT]\nKeys = %q\nIssuer = \"i\"\nAudience = \"a\"\n", symmetricPath) + rule,
+		"unknown scope":       fmt.Sprintf("[JWT]\nKeys = %q\nIssuer = \"i\"\nAudience = \"a\"\n", keysPath) + strings.Replace(rule, "download", "write", 1),
+		"bad pattern":         fmt.Sprintf("[JWT]\nKeys = %q\nIssuer = \"i\"\nAudience = \"a\"\n", keysPath) + rule + "[JWT.Rules.Claims]\nsub = \"[\"\n",
+		"duplicate rule":      fmt.Sprintf("[JWT]\nKeys = %q\nIssuer = \"i\"\nAudience = \"a\"\n", keysPath) + rule + rule,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewStore(writeAuthFile(t, dir, content), http.DefaultClient)
+			require.Error(t, err)
+		})
+	}
+}
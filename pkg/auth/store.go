@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/filewatch"
+)
+
+// maxVerified is the number of successful
+// authentications that a Store remembers.
+const maxVerified = 1024
+
+// file is the content of an auth file.
+type file struct {
+	Credentials []*Credential
+	JWT         *JWT
+}
+
+// Store holds the credentials of an auth file. It is safe for
+// concurrent use, and its credentials are replaced atomically
+// when the file is reloaded.
+type Store struct {
+	path   string
+	client *http.Client
+
+	// mu serializes the writers of state.
+	mu    sync.Mutex
+	state atomic.Value // *storeState
+}
+
+type storeState struct {
+	// users maps user names to their credential.
+	users map[string]*Credential
+	// tokens maps the SHA-256 hashes of tokens to their credential,
+	// so that a secret is checked against a single hash at most.
+	tokens map[[sha256.Size]byte]*Credential
+	// jwt checks the JWTs if the file configures them.
+	jwt *jwtVerifier
+	// file is the auth file as it was loaded.
+	file os.FileInfo
+
+	// verified maps the SHA-256 hashes of the users and passwords
+	// that were already checked to their credentials, since
+	// checking bcrypt hashes is slow on purpose.
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]*Credential
+}
+
+// NewStore loads the auth file at path. The key
+// sets of JWTs are fetched with client, if it is a URL.
+func NewStore(path string, client *http.Client) (*Store, error) {
+	const op errors.Op = "auth.NewStore"
+	st, err := load(path, client)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	s := &Store{path: path, client: client}
+	s.state.Store(st)
+	return s, nil
+}
+
+// Reload reads the auth file again. If the file cannot be read or is
+// invalid, the store keeps its current credentials and an error is returned.
+func (s *Store) Reload() error {
+	const op errors.Op = "auth.Reload"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, err := load(s.path, s.client)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	s.state.Store(st)
+	return nil
+}
+
+// Watch reloads the auth file whenever it changes, which is checked
+// every interval, or whenever a value is received from reload, until
+// ctx is done. A zero interval disables the checks.
+func (s *Store) Watch(ctx context.Context, interval time.Duration, reload <-chan os.Signal) {
+	filewatch.Watch(ctx, s.path, s.load().file, interval, reload, s.Reload)
+}
+
+// Authenticate returns the credential of a basic auth user and password.
+// The password of a token, or of a JWT, is the token itself, whatever the user is.
+func (s *Store) Authenticate(user, pass string) (*Credential, bool) {
+	return s.load().authenticate(user, pass, true)
+}
+
+// AuthenticateToken returns the credential of a bearer token or JWT.
+func (s *Store) AuthenticateToken(token string) (*Credential, bool) {
+	return s.load().authenticate("", token, false)
+}
+
+func (s *Store) load() *storeState {
+	return s.state.Load().(*storeState)
+}
+
+func (st *storeState) authenticate(user, secret string, basic bool) (*Credential, bool) {
+	if secret == "" {
+		return nil, false
+	}
+	// JWTs expire, so they are checked every time
+	if st.jwt != nil && looksLikeJWT(secret) {
+		return st.jwt.authenticate(secret)
+	}
+	if c, ok := st.tokens[sha256.Sum256([]byte(secret))]; ok {
+		return c, true
+	}
+	c, ok := st.users[user]
+	if !basic || !ok {
+		return nil, false
+	}
+	key := sha256.Sum256([]byte(user + "\x00" + secret))
+	st.mu.Lock()
+	verified := st.verified[key] == c
+	st.mu.Unlock()
+	if verified {
+		return c, true
+	}
+	if !c.verify(secret) {
+		return nil, false
+	}
+	st.mu.Lock()
+	if len(st.verified) >= maxVerified {
+		st.verified = map[[sha256.Size]byte]*Credential{}
+	}
+	st.verified[key] = c
+	st.mu.Unlock()
+	return c, true
+}
+
+func load(path string, client *http.Client) (*storeState, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	md, err := toml.DecodeFile(path, &f)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown keys %v", undecoded)
+	}
+	names := map[string]bool{}
+	users := map[string]*Credential{}
+	tokens := map[[sha256.Size]byte]*Credential{}
+	for _, c := range f.Credentials {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("credential %q is defined twice", c.Name)
+		}
+		names[c.Name] = true
+		if c.User != "" {
+			if users[c.User] != nil {
+				return nil, fmt.Errorf("user %q is defined twice", c.User)
+			}
+			users[c.User] = c
+			continue
+		}
+		// the hash was validated above
+		var h [sha256.Size]byte
+		hex.Decode(h[:], []byte(strings.TrimPrefix(c.Hash, sha256Prefix)))
+		if other := tokens[h]; other != nil {
+			return nil, fmt.Errorf("credentials %q and %q have the same token", other.Name, c.Name)
+		}
+		tokens[h] = c
+	}
+	var jwt *jwtVerifier
+	if f.JWT != nil {
+		if jwt, err = newJWTVerifier(f.JWT, client); err != nil {
+			return nil, err
+		}
+	}
+	return &storeState{
+		users:    users,
+		tokens:   tokens,
+		jwt:      jwt,
+		file:     fi,
+		verified: map[[sha256.Size]byte]*Credential{},
+	}, nil
+}
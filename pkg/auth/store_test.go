@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func sha256Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return sha256Prefix + hex.EncodeToString(sum[:])
+}
+
+func bcryptHash(t *testing.T, secret string) string {
+	t.Helper()
+	h, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(h)
+}
+
+func writeAuthFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "auth.toml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeAuthFile(t, dir, `
+[[Credentials]]
+Name = "ci"
+Hash = "`+sha256Hash("ci-token")+`"
+Scopes = ["download", "catalog"]
+
+[[Credentials]]
+Name = "contractor"
+User = "alice"
+Hash = "`+bcryptHash(t, "alice-password")+`"
+Scopes = ["download"]
+Modules = ["github.com/acme/public-*", "github.com/acme/sdk"]
+`)
+	s, err := NewStore(path, http.DefaultClient)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		user, pass string
+		bearer     bool
+		want       string
+	}{
+		{name: "token as password", user: "anyone", pass: "ci-token", want: "ci"},
+		{name: "bearer token", pass: "ci-token", bearer: true, want: "ci"},
+		{name: "wrong token", user: "anyone", pass: "nope"},
+		{name: "user", user: "alice", pass: "alice-password", want: "contractor"},
+		{name: "user again from the cache", user: "alice", pass: "alice-password", want: "contractor"},
+		{name: "wrong user", user: "bob", pass: "alice-password"},
+		{name: "wrong password", user: "alice", pass: "bob-password"},
+		{name: "token with a user name", user: "alice", pass: "ci-token", want: "ci"},
+		{name: "password as bearer token", pass: "alice-password", bearer: true},
+		{name: "empty", user: "alice"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var c *Credential
+			var ok bool
+			if tc.bearer {
+				c, ok = s.AuthenticateToken(tc.pass)
+			} else {
+				c, ok = s.Authenticate(tc.user, tc.pass)
+			}
+			if tc.want == "" {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, tc.want, c.Name)
+		})
+	}
+
+	c, _ := s.Authenticate("alice", "alice-password")
+	require.True(t, c.Has(ScopeDownload))
+	require.False(t, c.Has(ScopeCatalog))
+	require.True(t, c.AllowsModule("github.com/acme/public-api"))
+	require.True(t, c.AllowsModule("github.com/acme/sdk/v2"))
+	require.False(t, c.AllowsModule("github.com/acme/private"))
+	ci, _ := s.AuthenticateToken("ci-token")
+	require.True(t, ci.AllowsModule("github.com/acme/private"))
+
+	// a reload replaces the credentials and forgets
+	// the ones that were already checked
+	writeAuthFile(t, dir, `
+[[Credentials]]
+Name = "ci"
+Hash = "`+sha256Hash("new-ci-token")+`"
+Scopes = ["download"]
+`)
+	require.NoError(t, s.Reload())
+	_, ok := s.AuthenticateToken("ci-token")
+	require.False(t, ok)
+	_, ok = s.Authenticate("alice", "alice-password")
+	require.False(t, ok)
+	_, ok = s.AuthenticateToken("new-ci-token")
+	require.True(t, ok)
+
+	// a broken file keeps the current credentials
+	writeAuthFile(t, dir, `[[Credentials]]
+Name = "ci"
+`)
+	require.Error(t, s.Reload())
+	_, ok = s.AuthenticateToken("new-ci-token")
+	require.True(t, ok)
+}
+
+func TestStoreInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	hash := sha256Hash("token")
+
+	tests := map[string]string{
+		"no name":        `[[Credentials]]` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["download"]`,
+		"plain password": `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "secret"` + "\n" + `Scopes = ["download"]`,
+		"short sha256":   `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "sha256:abcd"` + "\n" + `Scopes = ["download"]`,
+		"no scopes":      `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + hash + `"`,
+		"unknown scope":  `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["write"]`,
+		"unknown key":    `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["download"]` + "\n" + `Module = ["github.com/a"]`,
+		"bad pattern":    `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["download"]` + "\n" + `Modules = ["github.com/[a"]`,
+		"bcrypt token":   `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + bcryptHash(t, "token") + `"` + "\n" + `Scopes = ["download"]`,
+		"duplicate token": `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["download"]` + "\n" +
+			`[[Credentials]]` + "\n" + `Name = "b"` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["catalog"]`,
+		"duplicate name": `[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["download"]` + "\n" +
+			`[[Credentials]]` + "\n" + `Name = "a"` + "\n" + `Hash = "` + hash + `"` + "\n" + `Scopes = ["download"]`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewStore(writeAuthFile(t, dir, content), http.DefaultClient)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/internal/spool"
+	"github.com/spf13/afero"
+	"golang.org/x/mod/module"
+)
+
+// ManifestName is the name of the manifest in a bundle.
+const ManifestName = "manifest.json"
+
+const (
+	// maxZipSize is the size of the largest
+	// zip that the go command accepts.
+	maxZipSize = 500 << 20
+	// maxFileSize is the size of the largest manifest, or .info,
+	// .mod or .sum file of a version, in a bundle. It is also
+	// the size of the largest go.mod that the go command accepts.
+	maxFileSize = 16 << 20
+)
+
+// Manifest lists the module versions in a bundle.
+type Manifest struct {
+	Created  time.Time `json:"created"`
+	Patterns []string  `json:"patterns,omitempty"`
+	Modules  []Entry   `json:"modules"`
+}
+
+// Entry is a module version in a bundle, along
+// with the go.sum hashes of its zip and go.mod file.
+type Entry struct {
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	ZipHash   string `json:"zip"`
+	GoModHash string `json:"mod"`
+}
+
+// fileName returns the name of the file with the
+// given extension of a module version in a bundle.
+func fileName(mod, ver, ext string) (string, error) {
+	const op errors.Op = "bundle.fileName"
+	encMod, err := module.EscapePath(mod)
+	if err != nil {
+		return "", errors.E(op, err, errors.KindBadRequest)
+	}
+	encVer, err := module.EscapeVersion(ver)
+	if err != nil {
+		return "", errors.E(op, err, errors.KindBadRequest)
+	}
+	return encMod + "/@v/" + encVer + "." + ext, nil
+}
+
+// parseName is the inverse of fileName.
+func parseName(name string) (mod, ver, ext string, err error) {
+	const op errors.Op = "bundle.parseName"
+	i := strings.LastIndex(name, "/@v/")
+	j := strings.LastIndex(name, ".")
+	if i <= 0 || j < i {
+		return "", "", "", errors.E(op, "unexpected file "+name, errors.KindBadRequest)
+	}
+	mod, err = module.UnescapePath(name[:i])
+	if err != nil {
+		return "", "", "", errors.E(op, err, errors.KindBadRequest)
+	}
+	ver, err = module.UnescapeVersion(name[i+len("/@v/") : j])
+	if err != nil {
+		return "", "", "", errors.E(op, err, errors.KindBadRequest)
+	}
+	return mod, ver, name[j+1:], nil
+}
+
+// spoolZip copies r into a temporary file in dir, and returns
+// it positioned at its start. r must hold a zip, which is an
+// error of KindBadRequest if it is larger than maxZipSize.
+func spoolZip(fs afero.Fs, dir string, r io.Reader) (*spool.File, error) {
+	const op errors.Op = "bundle.spoolZip"
+	tmp, err := spool.New(fs, dir, "athens-bundle", io.LimitReader(r, maxZipSize+1))
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if tmp.Size > maxZipSize {
+		tmp.Close()
+		return nil, errors.E(op, fmt.Sprintf("zip is larger than %d bytes", maxZipSize), errors.KindBadRequest)
+	}
+	return tmp, nil
+}
+
+// readFile reads a file of a bundle from r. It is an error
+// of KindBadRequest if it is larger than maxFileSize.
+func readFile(r io.Reader, name string) ([]byte, error) {
+	const op errors.Op = "bundle.readFile"
+	b, err := ioutil.ReadAll(io.LimitReader(r, maxFileSize+1))
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if len(b) > maxFileSize {
+		return nil, errors.E(op, fmt.Sprintf("%s is larger than %d bytes", name, maxFileSize), errors.KindBadRequest)
+	}
+	return b, nil
+}
@@ -0,0 +1,318 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+func makeZip(t *testing.T, mod, ver, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create(mod + "@" + ver + "/main.go")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func newStorage(t *testing.T, versions ...paths.AllPathParams) storage.Backend {
+	t.Helper()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	for _, v := range versions {
+		zip := makeZip(t, v.Module, v.Version, "package mod")
+		err := s.Save(context.Background(), v.Module, v.Version, []byte("module "+v.Module+"\n"), bytes.NewReader(zip), []byte(`{"Version":"`+v.Version+`"}`))
+		require.NoError(t, err)
+	}
+	return s
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	tmpFs := afero.NewMemMapFs()
+	src := newStorage(t,
+		paths.AllPathParams{Module: "github.com/Azure/azure-sdk", Version: "v1.0.0"},
+		paths.AllPathParams{Module: "github.com/Azure/azure-sdk", Version: "v1.1.0"},
+		paths.AllPathParams{Module: "github.com/pkg/errors", Version: "v0.8.1"},
+		paths.AllPathParams{Module: "golang.org/x/mod", Version: "v0.2.0"},
+	)
+	buf := &bytes.Buffer{}
+	manifest, err := Export(ctx, src, []string{"github.com/Azure", "golang.org/*"}, buf, tmpFs, "/tmp")
+	require.NoError(t, err)
+	require.Len(t, manifest.Modules, 3)
+	for _, e := range manifest.Modules {
+		require.NotEqual(t, "github.com/pkg/errors", e.Module)
+		require.True(t, strings.HasPrefix(e.ZipHash, "h1:"))
+	}
+
+	dst := newStorage(t, paths.AllPathParams{Module: "golang.org/x/mod", Version: "v0.2.0"})
+	rep, err := Import(ctx, dst, nil, nil, bytes.NewReader(buf.Bytes()), tmpFs, "/tmp")
+	require.NoError(t, err)
+	require.Empty(t, rep.Failed)
+	require.Len(t, rep.Imported, 2)
+	require.Equal(t, []paths.AllPathParams{{Module: "golang.org/x/mod", Version: "v0.2.0"}}, rep.Skipped)
+
+	versions, err := dst.List(ctx, "github.com/Azure/azure-sdk")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"v1.0.0", "v1.1.0"}, versions)
+	zip, err := dst.Zip(ctx, "github.com/Azure/azure-sdk", "v1.1.0")
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(zip)
+	zip.Close()
+	require.NoError(t, err)
+	require.Equal(t, makeZip(t, "github.com/Azure/azure-sdk", "v1.1.0", "package mod"), got)
+	sum, err := dst.(storage.SumGetter).Sum(ctx, "github.com/Azure/azure-sdk", "v1.1.0")
+	require.NoError(t, err)
+	zipHash, _ := storage.ParseSumLines(sum, "github.com/Azure/azure-sdk", "v1.1.0")
+	require.Equal(t, manifest.Modules[1].ZipHash, zipHash)
+
+	files, err := afero.ReadDir(tmpFs, "/tmp")
+	require.NoError(t, err)
+	require.Empty(t, files, "temporary files must be removed")
+}
+
+// rewrite copies bundle, replacing the contents of
+// the file called name with the result of edit.
+func rewrite(t *testing.T, bundle []byte, name string, edit func([]byte) []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	out := &bytes.Buffer{}
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		if hdr.Name == name {
+			b = edit(b)
+			if b == nil {
+				continue
+			}
+			hdr.Size = int64(len(b))
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = tw.Write(b)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return out.Bytes()
+}
+
+func TestImportVerifies(t *testing.T) {
+	ctx := context.Background()
+	tmpFs := afero.NewMemMapFs()
+	const mod, ver = "github.com/pkg/errors", "v0.8.1"
+	src := newStorage(t, paths.AllPathParams{Module: mod, Version: ver})
+	buf := &bytes.Buffer{}
+	_, err := Export(ctx, src, nil, buf, tmpFs, "/tmp")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		file    string
+		edit    func([]byte) []byte
+		failure string
+		wantErr bool
+	}{
+		{
+			name:    "tampered zip",
+			file:    "github.com/pkg/errors/@v/v0.8.1.zip",
+			edit:    func([]byte) []byte { return makeZip(t, mod, ver, "package evil") },
+			failure: "zip hash",
+		},
+		{
+			name:    "tampered go.mod",
+			file:    "github.com/pkg/errors/@v/v0.8.1.mod",
+			edit:    func([]byte) []byte { return []byte("module evil\n") },
+			failure: "go.mod hash",
+		},
+		{
+			name: "sum that disagrees with the manifest",
+			file: "github.com/pkg/errors/@v/v0.8.1.sum",
+			edit: func([]byte) []byte {
+				evil := makeZip(t, mod, ver, "package evil")
+				return storage.SumLines(mod, ver, mustHashZip(t, evil), "h1:evil")
+			},
+			failure: "does not match the manifest",
+		},
+		{
+			name: "version not in the manifest",
+			file: ManifestName,
+			edit: func(b []byte) []byte {
+				var m Manifest
+				require.NoError(t, json.Unmarshal(b, &m))
+				m.Modules = []Entry{}
+				b, err := json.Marshal(m)
+				require.NoError(t, err)
+				return b
+			},
+			failure: "not in the manifest",
+		},
+		{
+			name:    "missing version",
+			file:    "github.com/pkg/errors/@v/v0.8.1.zip",
+			edit:    func([]byte) []byte { return nil },
+			failure: "missing from bundle",
+		},
+		{
+			name:    "missing manifest",
+			file:    ManifestName,
+			edit:    func([]byte) []byte { return nil },
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := newStorage(t)
+			rep, err := Import(ctx, dst, nil, nil, bytes.NewReader(rewrite(t, buf.Bytes(), tc.file, tc.edit)), tmpFs, "/tmp")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Empty(t, rep.Imported)
+			require.Len(t, rep.Failed, 1)
+			require.Contains(t, rep.Failed[0].Error, tc.failure)
+			exists, err := storage.WithChecker(dst).Exists(ctx, mod, ver)
+			require.NoError(t, err)
+			require.False(t, exists)
+		})
+	}
+}
+
+func TestImportRequiresManifestFirst(t *testing.T) {
+	ctx := context.Background()
+	tmpFs := afero.NewMemMapFs()
+	src := newStorage(t, paths.AllPathParams{Module: "github.com/pkg/errors", Version: "v0.8.1"})
+	buf := &bytes.Buffer{}
+	_, err := Export(ctx, src, nil, buf, tmpFs, "/tmp")
+	require.NoError(t, err)
+
+	// move the manifest to the end of the bundle
+	var manifest []byte
+	bundle := rewrite(t, buf.Bytes(), ManifestName, func(b []byte) []byte {
+		manifest = b
+		return nil
+	})
+	out := &bytes.Buffer{}
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = io.Copy(tw, tr)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: ManifestName, Mode: 0644, Size: int64(len(manifest))}))
+	_, err = tw.Write(manifest)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	dst := newStorage(t)
+	_, err = Import(ctx, dst, nil, nil, out, tmpFs, "/tmp")
+	require.Error(t, err)
+	exists, err := storage.WithChecker(dst).Exists(ctx, "github.com/pkg/errors", "v0.8.1")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+type mockEmitter struct {
+	events []*events.Event
+}
+
+func (em *mockEmitter) Emit(ctx context.Context, e *events.Event) {
+	em.events = append(em.events, e)
+}
+
+func TestImportChecksSumDB(t *testing.T) {
+	ctx := context.Background()
+	tmpFs := afero.NewMemMapFs()
+	const mod = "github.com/pkg/errors"
+	src := newStorage(t,
+		paths.AllPathParams{Module: mod, Version: "v0.8.0"},
+		paths.AllPathParams{Module: mod, Version: "v0.8.1"},
+	)
+	buf := &bytes.Buffer{}
+	manifest, err := Export(ctx, src, nil, buf, tmpFs, "/tmp")
+	require.NoError(t, err)
+
+	// the checksum database records another zip for v0.8.1,
+	// so the bundle must not be trusted for that version.
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.athens.test")
+	require.NoError(t, err)
+	gosum := func(path, vers string) ([]byte, error) {
+		zipHash := mustHashZip(t, makeZip(t, path, vers, "package evil"))
+		if vers == "v0.8.0" {
+			zipHash = manifest.Modules[0].ZipHash
+		}
+		return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod %s\n", path, vers, zipHash, path, vers, manifest.Modules[0].GoModHash)), nil
+	}
+	srv := httptest.NewServer(sumdb.NewServer(sumdb.NewTestServer(skey, gosum)))
+	defer srv.Close()
+	v, err := checksum.NewVerifier(vkey+" "+srv.URL, nil, nil, srv.Client())
+	require.NoError(t, err)
+
+	dst := newStorage(t)
+	em := &mockEmitter{}
+	rep, err := Import(ctx, dst, v, em, bytes.NewReader(buf.Bytes()), tmpFs, "/tmp")
+	require.NoError(t, err)
+	require.Equal(t, []paths.AllPathParams{{Module: mod, Version: "v0.8.0"}}, rep.Imported)
+	require.Len(t, rep.Failed, 1)
+	require.Equal(t, "v0.8.1", rep.Failed[0].Version)
+	require.Contains(t, rep.Failed[0].Error, "checksum mismatch")
+	exists, err := storage.WithChecker(dst).Exists(ctx, mod, "v0.8.1")
+	require.NoError(t, err)
+	require.False(t, exists, "a version that fails verification must not be saved")
+
+	require.Len(t, em.events, 1)
+	require.Equal(t, events.Stashed, em.events[0].Type)
+	require.Equal(t, mod, em.events[0].Module)
+	require.Equal(t, "v0.8.0", em.events[0].Version)
+}
+
+func mustHashZip(t *testing.T, zip []byte) string {
+	t.Helper()
+	h, err := checksum.HashZip(bytes.NewReader(zip), int64(len(zip)))
+	require.NoError(t, err)
+	return h
+}
@@ -0,0 +1,16 @@
+// Package bundle exports module versions from a storage backend into a
+// single portable archive, and imports such archives into another backend,
+// so that Athens can be used to mirror modules into an air-gapped network.
+//
+// A bundle is a gzipped tar file. It starts with a manifest.json file that
+// lists every version in the bundle along with its hashes. Each module
+// version is then stored under its case-encoded path as in the download
+// protocol, as the files
+//
+//	<module>/@v/<version>.info
+//	<module>/@v/<version>.mod
+//	<module>/@v/<version>.sum
+//	<module>/@v/<version>.zip
+//
+// in that order, where the .sum file holds the go.sum lines of the version.
+package bundle
@@ -0,0 +1,214 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/internal/spool"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+const pageSize = 100
+
+// Export writes a bundle of the module versions in s whose path
+// matches one of patterns to w, and returns its manifest. Every
+// version is exported if there are no patterns. Zips are spooled
+// to temporary files in dir in order to be hashed.
+//
+// The manifest is written first, so that Import can check every
+// version against it. Versions are therefore read twice, once to
+// hash them and once to write them, and the export fails if one
+// changed in between.
+func Export(ctx context.Context, s storage.Backend, patterns []string, w io.Writer, fs afero.Fs, dir string) (*Manifest, error) {
+	const op errors.Op = "bundle.Export"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	cataloger, ok := s.(storage.Cataloger)
+	if !ok {
+		return nil, errors.E(op, "storage does not implement a catalog", errors.KindNotImplemented)
+	}
+
+	manifest := &Manifest{Created: time.Now().UTC(), Patterns: patterns, Modules: []Entry{}}
+	token := ""
+	for {
+		page, next, err := cataloger.Catalog(ctx, token, pageSize)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, p := range page {
+			if !matches(patterns, p.Module) {
+				continue
+			}
+			entry, err := hashVersion(ctx, s, fs, dir, p.Module, p.Version)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			manifest.Modules = append(manifest.Modules, *entry)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if err := writeFile(tw, ManifestName, bytes.NewReader(b), int64(len(b))); err != nil {
+		return nil, errors.E(op, err)
+	}
+	for i := range manifest.Modules {
+		if err := exportVersion(ctx, s, tw, fs, dir, &manifest.Modules[i]); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return manifest, nil
+}
+
+// matches reports whether mod matches one of patterns,
+// or whether there are no patterns at all.
+func matches(patterns []string, mod string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if paths.MatchesPattern(p, mod) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashVersion returns the manifest entry of mod@ver.
+func hashVersion(ctx context.Context, s storage.Backend, fs afero.Fs, dir, mod, ver string) (*Entry, error) {
+	const op errors.Op = "bundle.hashVersion"
+	goMod, tmp, err := readVersion(ctx, s, fs, dir, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	defer tmp.Close()
+	entry, err := hashFiles(mod, ver, goMod, tmp)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return entry, nil
+}
+
+// exportVersion writes the files of the version of entry to tw.
+func exportVersion(ctx context.Context, s storage.Backend, tw *tar.Writer, fs afero.Fs, dir string, entry *Entry) error {
+	const op errors.Op = "bundle.exportVersion"
+	mod, ver := entry.Module, entry.Version
+	info, err := s.Info(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	goMod, tmp, err := readVersion(ctx, s, fs, dir, mod, ver)
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	defer tmp.Close()
+	got, err := hashFiles(mod, ver, goMod, tmp)
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if got.ZipHash != entry.ZipHash || got.GoModHash != entry.GoModHash {
+		return errors.E(op, "version changed during the export", errors.M(mod), errors.V(ver))
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	sum := storage.SumLines(mod, ver, entry.ZipHash, entry.GoModHash)
+
+	files := []struct {
+		ext  string
+		r    io.Reader
+		size int64
+	}{
+		{"info", bytes.NewReader(info), int64(len(info))},
+		{"mod", bytes.NewReader(goMod), int64(len(goMod))},
+		{"sum", bytes.NewReader(sum), int64(len(sum))},
+		{"zip", tmp, tmp.Size},
+	}
+	for _, f := range files {
+		name, err := fileName(mod, ver, f.ext)
+		if err != nil {
+			return errors.E(op, err, errors.M(mod), errors.V(ver))
+		}
+		if err := writeFile(tw, name, f.r, f.size); err != nil {
+			return errors.E(op, err, errors.M(mod), errors.V(ver))
+		}
+	}
+	return nil
+}
+
+// readVersion returns the go.mod of mod@ver, and
+// its zip spooled to a temporary file in dir.
+func readVersion(ctx context.Context, s storage.Backend, fs afero.Fs, dir, mod, ver string) ([]byte, *spool.File, error) {
+	const op errors.Op = "bundle.readVersion"
+	goMod, err := s.GoMod(ctx, mod, ver)
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	zip, err := s.Zip(ctx, mod, ver)
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	tmp, err := spoolZip(fs, dir, zip)
+	zip.Close()
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	return goMod, tmp, nil
+}
+
+// hashFiles returns the entry of mod@ver with the hashes of goMod and zip.
+func hashFiles(mod, ver string, goMod []byte, zip *spool.File) (*Entry, error) {
+	const op errors.Op = "bundle.hashFiles"
+	entry := &Entry{Module: mod, Version: ver}
+	var err error
+	entry.ZipHash, err = checksum.HashZip(zip, zip.Size)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	entry.GoModHash, err = checksum.HashGoMod(goMod)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return entry, nil
+}
+
+func writeFile(tw *tar.Writer, name string, r io.Reader, size int64) error {
+	const op errors.Op = "bundle.writeFile"
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errors.E(op, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
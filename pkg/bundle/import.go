@@ -0,0 +1,243 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+// Failure is a module version that could not be imported.
+type Failure struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Error   string `json:"error"`
+}
+
+// Report is the outcome of an import.
+type Report struct {
+	Imported []paths.AllPathParams `json:"imported"`
+	Skipped  []paths.AllPathParams `json:"skipped"`
+	Failed   []Failure             `json:"failed"`
+}
+
+// pending holds the files of the version being read from a bundle.
+type pending struct {
+	mod, ver         string
+	info, goMod, sum []byte
+}
+
+// Import reads a bundle from r and saves its module versions into s.
+// The bundle must start with its manifest. The zip, go.mod and .sum
+// file of every version are checked against the hashes of the version
+// in the manifest, and against the checksum database of v if it is not
+// nil, before they are saved. Versions that fail the checks, or that are
+// not in the manifest, are reported rather than saved. Versions that are
+// already in s are skipped. Every saved version is emitted to emitter as
+// a stashed event, if emitter is not nil. Zips are spooled to temporary
+// files in dir in order to be hashed.
+//
+// An error is returned if the bundle itself cannot be read, in which
+// case the versions read up to that point have already been saved.
+func Import(ctx context.Context, s storage.Backend, v *checksum.Verifier, emitter events.Emitter, r io.Reader, fs afero.Fs, dir string) (*Report, error) {
+	const op errors.Op = "bundle.Import"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	rep := &Report{Imported: []paths.AllPathParams{}, Skipped: []paths.AllPathParams{}, Failed: []Failure{}}
+	imp := &importer{s: s, checker: storage.WithChecker(s), verifier: v, emitter: emitter, fs: fs, dir: dir, rep: rep}
+	seen := map[paths.AllPathParams]bool{}
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return rep, errors.E(op, err)
+	}
+	entries := map[paths.AllPathParams]*Entry{}
+	for i, e := range manifest.Modules {
+		entries[paths.AllPathParams{Module: e.Module, Version: e.Version}] = &manifest.Modules[i]
+	}
+	var cur *pending
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rep, errors.E(op, err, errors.KindBadRequest)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		mod, ver, ext, err := parseName(hdr.Name)
+		if err != nil {
+			return rep, errors.E(op, err)
+		}
+		if cur == nil || cur.mod != mod || cur.ver != ver {
+			cur = &pending{mod: mod, ver: ver}
+		}
+		switch ext {
+		case "info":
+			cur.info, err = readFile(tr, hdr.Name)
+		case "mod":
+			cur.goMod, err = readFile(tr, hdr.Name)
+		case "sum":
+			cur.sum, err = readFile(tr, hdr.Name)
+		case "zip":
+			params := paths.AllPathParams{Module: mod, Version: ver}
+			seen[params] = true
+			err = imp.importVersion(ctx, cur, entries[params], tr)
+			cur = nil
+		default:
+			err = errors.E(op, "unexpected file "+hdr.Name, errors.KindBadRequest)
+		}
+		if err != nil {
+			return rep, errors.E(op, err)
+		}
+	}
+	for _, e := range manifest.Modules {
+		if !seen[paths.AllPathParams{Module: e.Module, Version: e.Version}] {
+			rep.Failed = append(rep.Failed, Failure{Module: e.Module, Version: e.Version, Error: "missing from bundle"})
+		}
+	}
+	sort.Slice(rep.Failed, func(i, j int) bool {
+		if rep.Failed[i].Module != rep.Failed[j].Module {
+			return rep.Failed[i].Module < rep.Failed[j].Module
+		}
+		return rep.Failed[i].Version < rep.Failed[j].Version
+	})
+	return rep, nil
+}
+
+// readManifest reads the manifest, which must be the first file of tr.
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	const op errors.Op = "bundle.readManifest"
+	hdr, err := tr.Next()
+	if err == io.EOF || (err == nil && hdr.Name != ManifestName) {
+		return nil, errors.E(op, "bundle does not start with a manifest", errors.KindBadRequest)
+	}
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	b, err := readFile(tr, hdr.Name)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	return manifest, nil
+}
+
+// importer saves the versions of a bundle into s.
+type importer struct {
+	s       storage.Backend
+	checker storage.Checker
+	// verifier and emitter may be nil.
+	verifier *checksum.Verifier
+	emitter  events.Emitter
+	fs       afero.Fs
+	dir      string
+	rep      *Report
+}
+
+// importVersion verifies the version in p, whose zip is read from zip,
+// against its manifest entry, which is nil if the manifest does not list
+// it, and against the checksum database. It then saves the version and
+// records the outcome in the report. Only errors that prevent the rest
+// of the bundle from being read are returned.
+func (imp *importer) importVersion(ctx context.Context, p *pending, entry *Entry, zip io.Reader) error {
+	const op errors.Op = "bundle.importVersion"
+	params := paths.AllPathParams{Module: p.mod, Version: p.ver}
+	rep := imp.rep
+	fail := func(reason string) {
+		rep.Failed = append(rep.Failed, Failure{Module: p.mod, Version: p.ver, Error: reason})
+	}
+	tmp, err := spoolZip(imp.fs, imp.dir, zip)
+	if err != nil {
+		return errors.E(op, err, errors.M(p.mod), errors.V(p.ver))
+	}
+	defer tmp.Close()
+	if entry == nil {
+		fail("not in the manifest")
+		return nil
+	}
+	if p.info == nil || p.goMod == nil || p.sum == nil {
+		fail("incomplete: the .info, .mod and .sum files must precede the .zip file")
+		return nil
+	}
+	wantZip, wantMod := entry.ZipHash, entry.GoModHash
+	if sumZip, sumMod := storage.ParseSumLines(p.sum, p.mod, p.ver); sumZip != wantZip || sumMod != wantMod {
+		fail("the .sum file does not match the manifest")
+		return nil
+	}
+	zipHash, err := checksum.HashZip(tmp, tmp.Size)
+	if err != nil {
+		fail(fmt.Sprintf("zip cannot be hashed: %v", err))
+		return nil
+	}
+	if zipHash != wantZip {
+		fail(fmt.Sprintf("zip hash %s does not match %q", zipHash, wantZip))
+		return nil
+	}
+	modHash, err := checksum.HashGoMod(p.goMod)
+	if err != nil {
+		fail(fmt.Sprintf("go.mod cannot be hashed: %v", err))
+		return nil
+	}
+	if modHash != wantMod {
+		fail(fmt.Sprintf("go.mod hash %s does not match %q", modHash, wantMod))
+		return nil
+	}
+
+	exists, err := imp.checker.Exists(ctx, p.mod, p.ver)
+	if err != nil {
+		fail(err.Error())
+		return nil
+	}
+	if exists {
+		rep.Skipped = append(rep.Skipped, params)
+		return nil
+	}
+	// a bundle is only as trustworthy as the proxy that
+	// exported it, so check it like a fetched version.
+	if imp.verifier != nil {
+		if err := imp.verifier.Verify(ctx, p.mod, p.ver, zipHash, modHash); err != nil {
+			fail(err.Error())
+			return nil
+		}
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return errors.E(op, err, errors.M(p.mod), errors.V(p.ver))
+	}
+	if err := imp.s.Save(ctx, p.mod, p.ver, p.goMod, tmp, p.info); err != nil {
+		fail(err.Error())
+		return nil
+	}
+	if ss, ok := imp.s.(storage.SumSaver); ok {
+		if err := ss.SaveSum(ctx, p.mod, p.ver, p.sum); err != nil {
+			fail(err.Error())
+			return nil
+		}
+	}
+	rep.Imported = append(rep.Imported, params)
+	if imp.emitter != nil {
+		imp.emitter.Emit(ctx, &events.Event{Type: events.Stashed, Module: p.mod, Version: p.ver})
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package checksum
+
+import (
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/internal/spool"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+type verifyingFetcher struct {
+	fetcher module.Fetcher
+	// verifier is nil if the hashes are only computed.
+	verifier *Verifier
+	fs       afero.Fs
+	dir      string
+}
+
+// NewHashingFetcher wraps f so that every module it fetches has
+// its go.sum hashes, which are computed if f does not provide them.
+// The zip is spooled to a temporary file in dir in order to be hashed,
+// and that file is removed once the returned zip is closed.
+func NewHashingFetcher(f module.Fetcher, fs afero.Fs, dir string) module.Fetcher {
+	return &verifyingFetcher{
+		fetcher: f,
+		fs:      fs,
+		dir:     dir,
+	}
+}
+
+// NewVerifyingFetcher wraps f so that every module it fetches is
+// verified against v before being returned. The zip is spooled to
+// a temporary file in dir in order to be hashed, and that file is
+// removed once the returned zip is closed. Modules that fail
+// verification are never returned, so they cannot be stashed.
+func NewVerifyingFetcher(f module.Fetcher, v *Verifier, fs afero.Fs, dir string) module.Fetcher {
+	return &verifyingFetcher{
+		fetcher:  f,
+		verifier: v,
+		fs:       fs,
+		dir:      dir,
+	}
+}
+
+func (f *verifyingFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "verifyingFetcher.Fetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	v, err := f.fetcher.Fetch(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if f.verifier == nil && v.Sum != "" && v.GoModSum != "" {
+		return v, nil
+	}
+	zip, err := spool.Reuse(f.fs, f.dir, "athens-verify", v.Zip)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(v.Semver), err)
+	}
+	if err := f.verify(ctx, mod, v, zip); err != nil {
+		zip.Close()
+		return nil, errors.E(op, err)
+	}
+	v.Zip = zip
+	return v, nil
+}
+
+func (f *verifyingFetcher) verify(ctx context.Context, mod string, v *storage.Version, zip *spool.File) error {
+	const op errors.Op = "verifyingFetcher.verify"
+	zipHash, err := HashZip(zip, zip.Size)
+	if err != nil {
+		return errors.E(op, errors.M(mod), errors.V(v.Semver), err, errors.KindChecksumMismatch)
+	}
+	modHash, err := HashGoMod(v.Mod)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if f.verifier != nil {
+		if err := f.verifier.Verify(ctx, mod, v.Semver, zipHash, modHash); err != nil {
+			return errors.E(op, err)
+		}
+	}
+	// upstream proxies do not serve hashes, so record the computed ones.
+	v.Sum, v.GoModSum = zipHash, modHash
+	if _, err := zip.Seek(0, io.SeekStart); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package checksum
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const testGoMod = "module example.com/mod\n"
+
+type mockFetcher struct {
+	zip []byte
+}
+
+func (m *mockFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	return &storage.Version{
+		Semver: ver,
+		Mod:    []byte(testGoMod),
+		Zip:    ioutil.NopCloser(bytes.NewReader(m.zip)),
+	}, nil
+}
+
+func makeZip(t *testing.T, mod, ver, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, data := range map[string]string{"go.mod": testGoMod, "main.go": content} {
+		w, err := zw.Create(mod + "@" + ver + "/" + name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// newTestSumDB starts a checksum database that records
+// the hashes of the given zip for every module version.
+func newTestSumDB(t *testing.T, zipBytes []byte) (gosumdb string, close func()) {
+	t.Helper()
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.athens.test")
+	require.NoError(t, err)
+	zipHash, err := HashZip(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	require.NoError(t, err)
+	modHash, err := HashGoMod([]byte(testGoMod))
+	require.NoError(t, err)
+	gosum := func(path, vers string) ([]byte, error) {
+		return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod %s\n", path, vers, zipHash, path, vers, modHash)), nil
+	}
+	srv := httptest.NewServer(sumdb.NewServer(sumdb.NewTestServer(skey, gosum)))
+	return vkey + " " + srv.URL, srv.Close
+}
+
+func TestVerifyingFetcher(t *testing.T) {
+	const mod, ver = "example.com/mod", "v1.0.0"
+	good := makeZip(t, mod, ver, "package mod")
+	tampered := makeZip(t, mod, ver, "package mod // evil")
+	gosumdb, close := newTestSumDB(t, good)
+	defer close()
+
+	tests := []struct {
+		name          string
+		zip           []byte
+		noSumPatterns []string
+		wantErr       bool
+	}{
+		{name: "verified", zip: good},
+		{name: "tampered", zip: tampered, wantErr: true},
+		{name: "tampered but excluded", zip: tampered, noSumPatterns: []string{"example.com/*"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewVerifier(gosumdb, nil, tc.noSumPatterns, nil)
+			require.NoError(t, err)
+			fs := afero.NewMemMapFs()
+			f := NewVerifyingFetcher(&mockFetcher{zip: tc.zip}, v, fs, "/tmp")
+			version, err := f.Fetch(context.Background(), mod, ver)
+			if tc.wantErr {
+				require.Error(t, err)
+				require.Equal(t, errors.KindChecksumMismatch, errors.Kind(err))
+			} else {
+				require.NoError(t, err)
+				require.NotEmpty(t, version.Sum, "verified hashes should be recorded")
+				require.NotEmpty(t, version.GoModSum, "verified hashes should be recorded")
+				zipBytes, err := ioutil.ReadAll(version.Zip)
+				require.NoError(t, err)
+				require.Equal(t, tc.zip, zipBytes)
+				require.NoError(t, version.Zip.Close())
+			}
+			files, err := afero.ReadDir(fs, "/tmp")
+			require.NoError(t, err)
+			require.Empty(t, files, "temporary zip files must be removed")
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	v, err := NewVerifier("sum.golang.org", []string{"https://sum.golang.org"}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://sum.golang.org", v.ops.url)
+
+	_, err = NewVerifier("sum.example.com", nil, nil, nil)
+	require.Error(t, err, "unknown sumdbs require a verifier key")
+
+	_, err = NewVerifier("", nil, nil, nil)
+	require.Error(t, err)
+}
+
+func TestVerifyContext(t *testing.T) {
+	const mod, ver = "example.com/mod", "v1.0.0"
+	zipBytes := makeZip(t, mod, ver, "package mod")
+	gosumdb, close := newTestSumDB(t, zipBytes)
+	defer close()
+	v, err := NewVerifier(gosumdb, nil, nil, nil)
+	require.NoError(t, err)
+	zipHash, err := HashZip(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	require.NoError(t, err)
+	modHash, err := HashGoMod([]byte(testGoMod))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = v.Verify(ctx, mod, ver, zipHash, modHash)
+	require.Error(t, err, "a cancelled lookup must fail")
+	require.NotEqual(t, errors.KindChecksumMismatch, errors.Kind(err))
+
+	require.NoError(t, v.Verify(context.Background(), mod, ver, zipHash, modHash))
+}
+
+func TestHashingFetcher(t *testing.T) {
+	const mod, ver = "example.com/mod", "v1.0.0"
+	zipBytes := makeZip(t, mod, ver, "package mod")
+	zipHash, err := HashZip(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	require.NoError(t, err)
+	modHash, err := HashGoMod([]byte(testGoMod))
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	f := NewHashingFetcher(&mockFetcher{zip: zipBytes}, fs, "/tmp")
+	version, err := f.Fetch(context.Background(), mod, ver)
+	require.NoError(t, err)
+	require.Equal(t, zipHash, version.Sum)
+	require.Equal(t, modHash, version.GoModSum)
+	got, err := ioutil.ReadAll(version.Zip)
+	require.NoError(t, err)
+	require.Equal(t, zipBytes, got)
+	require.NoError(t, version.Zip.Close())
+	files, err := afero.ReadDir(fs, "/tmp")
+	require.NoError(t, err)
+	require.Empty(t, files, "temporary zip files must be removed")
+}
@@ -0,0 +1,75 @@
+package checksum
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// HashZip returns the h1: hash of a module zip file, exactly
+// as it would appear in a go.sum file. Only the names and
+// contents of the files inside the zip are hashed. The error
+// is of KindBadRequest if the zip is malformed, rather than
+// unreadable.
+func HashZip(r io.ReaderAt, size int64) (string, error) {
+	const op errors.Op = "checksum.HashZip"
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", errors.E(op, err, zipErrKind(err))
+	}
+	var files []string
+	zfiles := make(map[string]*zip.File, len(z.File))
+	for _, file := range z.File {
+		files = append(files, file.Name)
+		zfiles[file.Name] = file
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		f := zfiles[name]
+		if f == nil {
+			return nil, fmt.Errorf("file %q not found in zip", name)
+		}
+		return f.Open()
+	}
+	h, err := dirhash.Hash1(files, open)
+	if err != nil {
+		return "", errors.E(op, err, zipErrKind(err))
+	}
+	return h, nil
+}
+
+// zipErrKind returns KindBadRequest if err comes from a malformed
+// zip, and KindUnexpected if it comes from reading it.
+func zipErrKind(err error) int {
+	if _, ok := err.(flate.CorruptInputError); ok {
+		return errors.KindBadRequest
+	}
+	switch err {
+	case zip.ErrFormat, zip.ErrAlgorithm, zip.ErrChecksum, io.ErrUnexpectedEOF:
+		return errors.KindBadRequest
+	}
+	if strings.HasPrefix(err.Error(), "dirhash: ") {
+		return errors.KindBadRequest
+	}
+	return errors.KindUnexpected
+}
+
+// HashGoMod returns the h1: hash of a go.mod file, exactly
+// as it would appear in the /go.mod line of a go.sum file.
+func HashGoMod(mod []byte) (string, error) {
+	const op errors.Op = "checksum.HashGoMod"
+	open := func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(mod)), nil
+	}
+	h, err := dirhash.Hash1([]string{"go.mod"}, open)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	return h, nil
+}
@@ -0,0 +1,53 @@
+package checksum
+
+import (
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/internal/spool"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+// HashZipStream returns the h1: hash of the module zip read
+// from zip, which is closed afterwards. The zip is spooled to
+// a temporary file in dir in order to be hashed. As with HashZip,
+// only malformed zips are errors of KindBadRequest.
+func HashZipStream(fs afero.Fs, dir string, zip io.ReadCloser) (string, error) {
+	const op errors.Op = "checksum.HashZipStream"
+	tmp, err := spool.Reuse(fs, dir, "athens-verify", zip)
+	if err != nil {
+		return "", errors.E(op, err, errors.KindUnexpected)
+	}
+	defer tmp.Close()
+	h, err := HashZip(tmp, tmp.Size)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	return h, nil
+}
+
+// HashVersion returns the go.sum lines of mod@ver, hashed from the
+// zip and go.mod file stored in s. The zip is spooled to a temporary
+// file in dir in order to be hashed.
+func HashVersion(ctx context.Context, s storage.Backend, fs afero.Fs, dir, mod, ver string) ([]byte, error) {
+	const op errors.Op = "checksum.HashVersion"
+	goMod, err := s.GoMod(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	modHash, err := HashGoMod(goMod)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	zip, err := s.Zip(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	zipHash, err := HashZipStream(fs, dir, zip)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return storage.SumLines(mod, ver, zipHash, modHash), nil
+}
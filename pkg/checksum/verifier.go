@@ -0,0 +1,238 @@
+package checksum
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"golang.org/x/mod/sumdb"
+)
+
+// knownSumDBs maps the names of well known checksum
+// databases to their verifier keys, the same way the
+// go command does for GOSUMDB.
+var knownSumDBs = map[string]string{
+	"sum.golang.org": "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8",
+}
+
+// Verifier checks module hashes against a checksum
+// database using the tiled transparency log protocol.
+type Verifier struct {
+	ops           *clientOps
+	noSumPatterns []string
+}
+
+// NewVerifier returns a Verifier for the checksum database described
+// by gosumdb, which follows the format of the GOSUMDB environment variable:
+// either a known database name such as "sum.golang.org", or a verifier key
+// optionally followed by the database URL, e.g.
+// "sum.example.com+1234abcd+AeC... https://sum.example.com".
+// If no URL is given, the entry of sumDBs with the same host name is used,
+// falling back to https://<name>. Modules matching one of noSumPatterns
+// are never verified.
+func NewVerifier(gosumdb string, sumDBs, noSumPatterns []string, client *http.Client) (*Verifier, error) {
+	const op errors.Op = "checksum.NewVerifier"
+	fields := strings.Fields(gosumdb)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, errors.E(op, fmt.Sprintf("invalid sumdb %q", gosumdb))
+	}
+	key := fields[0]
+	if k, ok := knownSumDBs[key]; ok {
+		key = k
+	}
+	name := key
+	if i := strings.Index(key, "+"); i >= 0 {
+		name = key[:i]
+	} else {
+		return nil, errors.E(op, fmt.Sprintf("sumdb %q is not a known name and has no verifier key", gosumdb))
+	}
+	rawURL := "https://" + name
+	if len(fields) == 2 {
+		rawURL = fields[1]
+	} else {
+		for _, db := range sumDBs {
+			if u, err := url.Parse(db); err == nil && u.Host == name {
+				rawURL = db
+				break
+			}
+		}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	ops := &clientOps{
+		url:    strings.TrimSuffix(u.String(), "/"),
+		key:    key,
+		client: client,
+		config: map[string][]byte{},
+		tiles:  list.New(),
+		cache:  map[string]*list.Element{},
+	}
+	return &Verifier{
+		ops:           ops,
+		noSumPatterns: noSumPatterns,
+	}, nil
+}
+
+// Verify checks that zipHash and modHash match the go.sum lines
+// that the checksum database records for mod@ver. It returns an
+// error of KindChecksumMismatch if they do not.
+func (v *Verifier) Verify(ctx context.Context, mod, ver, zipHash, modHash string) error {
+	const op errors.Op = "checksum.Verify"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	for _, p := range v.noSumPatterns {
+		if paths.MatchesPattern(p, mod) {
+			return nil
+		}
+	}
+	// the requests of a client cannot be cancelled, so every
+	// call gets its own client, on top of the shared caches.
+	client := sumdb.NewClient(&ctxOps{clientOps: v.ops, ctx: ctx})
+	if err := verifyLine(client, mod, ver, zipHash); err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	if err := verifyLine(client, mod, ver+"/go.mod", modHash); err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	return nil
+}
+
+func verifyLine(client *sumdb.Client, mod, ver, hash string) error {
+	const op errors.Op = "checksum.verifyLine"
+	lines, err := client.Lookup(mod, ver)
+	if err == sumdb.ErrGONOSUMDB {
+		return nil
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), sumdb.ErrSecurity.Error()) {
+			return errors.E(op, err, errors.KindChecksumMismatch)
+		}
+		return errors.E(op, err)
+	}
+	want := mod + " " + ver + " " + hash
+	for _, line := range lines {
+		if line == want {
+			return nil
+		}
+	}
+	return errors.E(op, fmt.Sprintf("checksum mismatch for %s@%s: downloaded %s but sumdb has %v", mod, ver, hash, lines), errors.KindChecksumMismatch)
+}
+
+// maxCachedTiles is the number of tiles kept in memory. A tile
+// holds at most 256 hashes of 32 bytes, so the cache stays within
+// 8MiB while keeping the recent tiles that most lookups share.
+const maxCachedTiles = 1024
+
+// clientOps holds the configuration and the in-memory
+// cache of a checksum database, shared by its clients.
+type clientOps struct {
+	url    string
+	key    string
+	client *http.Client
+
+	mu     sync.Mutex
+	config map[string][]byte
+	// tiles holds the cached tiles, least recently used
+	// last, and cache maps their names to their elements.
+	tiles *list.List
+	cache map[string]*list.Element
+}
+
+type cachedTile struct {
+	file string
+	data []byte
+}
+
+// ctxOps implements sumdb.ClientOps by talking to the checksum
+// database over HTTP within ctx, and caching in clientOps.
+type ctxOps struct {
+	*clientOps
+	ctx context.Context
+}
+
+func (c *ctxOps) ReadRemote(path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s%s: %s", c.url, path, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c *clientOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(c.key), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// an empty latest tree is a valid starting point.
+	return c.config[file], nil
+}
+
+func (c *clientOps) WriteConfig(file string, old, new []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if string(c.config[file]) != string(old) {
+		return sumdb.ErrWriteConflict
+	}
+	c.config[file] = new
+	return nil
+}
+
+func (c *clientOps) ReadCache(file string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.cache[file]
+	if !ok {
+		return nil, fmt.Errorf("%s not cached", file)
+	}
+	c.tiles.MoveToFront(el)
+	return el.Value.(*cachedTile).data, nil
+}
+
+func (c *clientOps) WriteCache(file string, data []byte) {
+	// lookup records are only read once per module version,
+	// so only the tiles are worth keeping around.
+	if strings.Contains(file, "/lookup/") {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.cache[file]; ok {
+		el.Value.(*cachedTile).data = data
+		c.tiles.MoveToFront(el)
+		return
+	}
+	c.cache[file] = c.tiles.PushFront(&cachedTile{file: file, data: data})
+	for c.tiles.Len() > maxCachedTiles {
+		t := c.tiles.Remove(c.tiles.Back()).(*cachedTile)
+		delete(c.cache, t.file)
+	}
+}
+
+func (c *ctxOps) Log(msg string) {}
+
+func (c *ctxOps) SecurityError(msg string) {
+	log.EntryFromContext(c.ctx).Errorf("checksum database %s: %s", c.url, msg)
+}
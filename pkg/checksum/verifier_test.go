@@ -0,0 +1,35 @@
+package checksum
+
+import (
+	"container/list"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTileCacheBounded(t *testing.T) {
+	c := &clientOps{tiles: list.New(), cache: map[string]*list.Element{}}
+	c.WriteCache("sum.athens.test/lookup/example.com/mod@v1.0.0", []byte("record"))
+	_, err := c.ReadCache("sum.athens.test/lookup/example.com/mod@v1.0.0")
+	require.Error(t, err, "lookup records must not be cached")
+
+	tile := func(i int) string { return fmt.Sprintf("sum.athens.test/tile/8/0/%03d", i) }
+	for i := 0; i < maxCachedTiles; i++ {
+		c.WriteCache(tile(i), []byte{byte(i)})
+	}
+	// reading the oldest tile makes it the most recently used.
+	_, err = c.ReadCache(tile(0))
+	require.NoError(t, err)
+	c.WriteCache(tile(maxCachedTiles), []byte("new"))
+
+	require.Len(t, c.cache, maxCachedTiles)
+	_, err = c.ReadCache(tile(1))
+	require.Error(t, err, "the least recently used tile must be evicted")
+	data, err := c.ReadCache(tile(0))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0}, data)
+	data, err = c.ReadCache(tile(maxCachedTiles))
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), data)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
@@ -21,44 +22,74 @@ const defaultConfigFile = "athens.toml"
 // Config provides configuration values for all components
 type Config struct {
 	TimeoutConf
-	GoEnv            string    `validate:"required" envconfig:"GO_ENV"`
-	GoBinary         string    `validate:"required" envconfig:"GO_BINARY_PATH"`
-	GoProxy          string    `envconfig:"GOPROXY"`
-	GoBinaryEnvVars  EnvList   `envconfig:"ATHENS_GO_BINARY_ENV_VARS"`
-	GoGetWorkers     int       `validate:"required" envconfig:"ATHENS_GOGET_WORKERS"`
-	GoGetDir         string    `envconfig:"ATHENS_GOGOET_DIR"`
-	ProtocolWorkers  int       `validate:"required" envconfig:"ATHENS_PROTOCOL_WORKERS"`
-	LogLevel         string    `validate:"required" envconfig:"ATHENS_LOG_LEVEL"`
-	CloudRuntime     string    `validate:"required" envconfig:"ATHENS_CLOUD_RUNTIME"`
-	EnablePprof      bool      `envconfig:"ATHENS_ENABLE_PPROF"`
-	PprofPort        string    `envconfig:"ATHENS_PPROF_PORT"`
-	FilterFile       string    `envconfig:"ATHENS_FILTER_FILE"`
-	TraceExporterURL string    `envconfig:"ATHENS_TRACE_EXPORTER_URL"`
-	TraceExporter    string    `envconfig:"ATHENS_TRACE_EXPORTER"`
-	StatsExporter    string    `envconfig:"ATHENS_STATS_EXPORTER"`
-	StorageType      string    `validate:"required" envconfig:"ATHENS_STORAGE_TYPE"`
-	GlobalEndpoint   string    `envconfig:"ATHENS_GLOBAL_ENDPOINT"` // This feature is not yet implemented
-	Port             string    `envconfig:"ATHENS_PORT"`
-	BasicAuthUser    string    `envconfig:"BASIC_AUTH_USER"`
-	BasicAuthPass    string    `envconfig:"BASIC_AUTH_PASS"`
-	ForceSSL         bool      `envconfig:"PROXY_FORCE_SSL"`
-	ValidatorHook    string    `envconfig:"ATHENS_PROXY_VALIDATOR"`
-	PathPrefix       string    `envconfig:"ATHENS_PATH_PREFIX"`
-	NETRCPath        string    `envconfig:"ATHENS_NETRC_PATH"`
-	GithubToken      string    `envconfig:"ATHENS_GITHUB_TOKEN"`
-	HGRCPath         string    `envconfig:"ATHENS_HGRC_PATH"`
-	TLSCertFile      string    `envconfig:"ATHENS_TLSCERT_FILE"`
-	TLSKeyFile       string    `envconfig:"ATHENS_TLSKEY_FILE"`
-	SumDBs           []string  `envconfig:"ATHENS_SUM_DBS"`
-	NoSumPatterns    []string  `envconfig:"ATHENS_GONOSUM_PATTERNS"`
-	DownloadMode     mode.Mode `envconfig:"ATHENS_DOWNLOAD_MODE"`
-	DownloadURL      string    `envconfig:"ATHENS_DOWNLOAD_URL"`
-	SingleFlightType string    `envconfig:"ATHENS_SINGLE_FLIGHT_TYPE"`
-	RobotsFile       string    `envconfig:"ATHENS_ROBOTS_FILE"`
-	IndexType        string    `envconfig:"ATHENS_INDEX_TYPE"`
-	SingleFlight     *SingleFlight
-	Storage          *Storage
-	Index            *Index
+	GoEnv                  string          `validate:"required" envconfig:"GO_ENV"`
+	GoBinary               string          `validate:"required" envconfig:"GO_BINARY_PATH"`
+	GoProxy                string          `envconfig:"GOPROXY"`
+	GoBinaryEnvVars        EnvList         `envconfig:"ATHENS_GO_BINARY_ENV_VARS"`
+	GoGetWorkers           int             `validate:"required" envconfig:"ATHENS_GOGET_WORKERS"`
+	GoGetDir               string          `envconfig:"ATHENS_GOGOET_DIR"`
+	FetcherType            string          `envconfig:"ATHENS_FETCHER_TYPE"`
+	ListerType             string          `envconfig:"ATHENS_LISTER_TYPE"`
+	UpstreamGoProxy        string          `envconfig:"ATHENS_UPSTREAM_GOPROXY"`
+	ProtocolWorkers        int             `validate:"required" envconfig:"ATHENS_PROTOCOL_WORKERS"`
+	LogLevel               string          `validate:"required" envconfig:"ATHENS_LOG_LEVEL"`
+	CloudRuntime           string          `validate:"required" envconfig:"ATHENS_CLOUD_RUNTIME"`
+	EnablePprof            bool            `envconfig:"ATHENS_ENABLE_PPROF"`
+	PprofPort              string          `envconfig:"ATHENS_PPROF_PORT"`
+	FilterFile             string          `envconfig:"ATHENS_FILTER_FILE"`
+	FilterReloadInterval   int             `envconfig:"ATHENS_FILTER_RELOAD_INTERVAL"`
+	PinFile                string          `envconfig:"ATHENS_PIN_FILE"`
+	PinReloadInterval      int             `envconfig:"ATHENS_PIN_RELOAD_INTERVAL"`
+	TraceExporterURL       string          `envconfig:"ATHENS_TRACE_EXPORTER_URL"`
+	TraceExporter          string          `envconfig:"ATHENS_TRACE_EXPORTER"`
+	StatsExporter          string          `envconfig:"ATHENS_STATS_EXPORTER"`
+	StorageType            string          `validate:"required" envconfig:"ATHENS_STORAGE_TYPE"`
+	GlobalEndpoint         string          `envconfig:"ATHENS_GLOBAL_ENDPOINT"` // This feature is not yet implemented
+	Port                   string          `envconfig:"ATHENS_PORT"`
+	BasicAuthUser          string          `envconfig:"BASIC_AUTH_USER"`
+	BasicAuthPass          string          `envconfig:"BASIC_AUTH_PASS"`
+	AdminUser              string          `envconfig:"ATHENS_ADMIN_USER"`
+	AdminPass              string          `envconfig:"ATHENS_ADMIN_PASS"`
+	AuthFile               string          `envconfig:"ATHENS_AUTH_FILE"`
+	AuthReloadInterval     int             `envconfig:"ATHENS_AUTH_RELOAD_INTERVAL"`
+	ForceSSL               bool            `envconfig:"PROXY_FORCE_SSL"`
+	ValidatorHook          string          `envconfig:"ATHENS_PROXY_VALIDATOR"`
+	PostFetchValidatorHook string          `envconfig:"ATHENS_PROXY_POST_FETCH_VALIDATOR"`
+	ValidatorCacheTTL      int             `envconfig:"ATHENS_PROXY_VALIDATOR_CACHE_TTL"`
+	PathPrefix             string          `envconfig:"ATHENS_PATH_PREFIX"`
+	NETRCPath              string          `envconfig:"ATHENS_NETRC_PATH"`
+	GithubToken            string          `envconfig:"ATHENS_GITHUB_TOKEN"`
+	HGRCPath               string          `envconfig:"ATHENS_HGRC_PATH"`
+	TLSCertFile            string          `envconfig:"ATHENS_TLSCERT_FILE"`
+	TLSKeyFile             string          `envconfig:"ATHENS_TLSKEY_FILE"`
+	SumDBs                 []string        `envconfig:"ATHENS_SUM_DBS"`
+	NoSumPatterns          []string        `envconfig:"ATHENS_GONOSUM_PATTERNS"`
+	VerifySumDB            string          `envconfig:"ATHENS_VERIFY_SUMDB"`
+	DownloadMode           mode.Mode       `envconfig:"ATHENS_DOWNLOAD_MODE"`
+	DownloadURL            string          `envconfig:"ATHENS_DOWNLOAD_URL"`
+	ZipURLExpiry           int             `envconfig:"ATHENS_ZIP_URL_EXPIRY"`
+	ListCacheTTL           int             `envconfig:"ATHENS_LIST_CACHE_TTL"`
+	ListCacheStale         int             `envconfig:"ATHENS_LIST_CACHE_STALE"`
+	SingleFlightType       string          `envconfig:"ATHENS_SINGLE_FLIGHT_TYPE"`
+	NotFoundCacheType      string          `envconfig:"ATHENS_NOT_FOUND_CACHE_TYPE"`
+	NotFoundCacheTTL       int             `envconfig:"ATHENS_NOT_FOUND_CACHE_TTL"`
+	RobotsFile             string          `envconfig:"ATHENS_ROBOTS_FILE"`
+	IndexType              string          `envconfig:"ATHENS_INDEX_TYPE"`
+	UsageType              string          `envconfig:"ATHENS_USAGE_TYPE"`
+	ScrubInterval          int             `envconfig:"ATHENS_SCRUB_INTERVAL"`
+	ScrubAction            string          `envconfig:"ATHENS_SCRUB_ACTION"`
+	ScrubQuarantine        string          `envconfig:"ATHENS_SCRUB_QUARANTINE_DIR"`
+	RetentionInterval      int             `envconfig:"ATHENS_RETENTION_INTERVAL"`
+	RetentionDryRun        bool            `envconfig:"ATHENS_RETENTION_DRY_RUN"`
+	RetentionRules         []RetentionRule `ignored:"true"`
+	EventsWebhookURL       string          `envconfig:"ATHENS_EVENTS_WEBHOOK_URL"`
+	EventsWebhookSecret    string          `envconfig:"ATHENS_EVENTS_WEBHOOK_SECRET"`
+	EventsWebhookRetries   int             `envconfig:"ATHENS_EVENTS_WEBHOOK_RETRIES"`
+	EventsFile             string          `envconfig:"ATHENS_EVENTS_FILE"`
+	EventsQueueSize        int             `envconfig:"ATHENS_EVENTS_QUEUE_SIZE"`
+	SingleFlight           *SingleFlight
+	Storage                *Storage
+	Index                  *Index
 }
 
 // EnvList is a list of key-value environment
@@ -142,29 +173,43 @@ func Load(configFile string) (*Config, error) {
 
 func defaultConfig() *Config {
 	return &Config{
-		GoBinary:         "go",
-		GoBinaryEnvVars:  EnvList{"GOPROXY=direct"},
-		GoEnv:            "development",
-		GoProxy:          "direct",
-		GoGetWorkers:     10,
-		ProtocolWorkers:  30,
-		LogLevel:         "debug",
-		CloudRuntime:     "none",
-		EnablePprof:      false,
-		PprofPort:        ":3001",
-		StatsExporter:    "prometheus",
-		TimeoutConf:      TimeoutConf{Timeout: 300},
-		StorageType:      "memory",
-		Port:             ":3000",
-		SingleFlightType: "memory",
-		GlobalEndpoint:   "http://localhost:3001",
-		TraceExporterURL: "http://localhost:14268",
-		SumDBs:           []string{"https://sum.golang.org"},
-		NoSumPatterns:    []string{},
-		DownloadMode:     "sync",
-		DownloadURL:      "",
-		RobotsFile:       "robots.txt",
-		IndexType:        "none",
+		GoBinary:             "go",
+		GoBinaryEnvVars:      EnvList{"GOPROXY=direct"},
+		GoEnv:                "development",
+		GoProxy:              "direct",
+		GoGetWorkers:         10,
+		FetcherType:          "go",
+		ListerType:           "go",
+		UpstreamGoProxy:      "https://proxy.golang.org,direct",
+		ProtocolWorkers:      30,
+		LogLevel:             "debug",
+		CloudRuntime:         "none",
+		EnablePprof:          false,
+		PprofPort:            ":3001",
+		StatsExporter:        "prometheus",
+		TimeoutConf:          TimeoutConf{Timeout: 300},
+		StorageType:          "memory",
+		Port:                 ":3000",
+		SingleFlightType:     "memory",
+		NotFoundCacheType:    "none",
+		NotFoundCacheTTL:     300,
+		ValidatorCacheTTL:    300,
+		FilterReloadInterval: 10,
+		PinReloadInterval:    10,
+		AuthReloadInterval:   10,
+		GlobalEndpoint:       "http://localhost:3001",
+		TraceExporterURL:     "http://localhost:14268",
+		SumDBs:               []string{"https://sum.golang.org"},
+		NoSumPatterns:        []string{},
+		DownloadMode:         "sync",
+		DownloadURL:          "",
+		RobotsFile:           "robots.txt",
+		IndexType:            "none",
+		UsageType:            "none",
+		ScrubAction:          "report",
+		RetentionDryRun:      true,
+		EventsWebhookRetries: 3,
+		EventsQueueSize:      1000,
 		SingleFlight: &SingleFlight{
 			Etcd:  &Etcd{"localhost:2379,localhost:22379,localhost:32379"},
 			Redis: &Redis{"127.0.0.1:6379", ""},
@@ -211,6 +256,15 @@ func (c *Config) BasicAuth() (user, pass string, ok bool) {
 	return user, pass, ok
 }
 
+// AdminAuth returns AdminUser and AdminPass
+// and ok if neither of them are empty
+func (c *Config) AdminAuth() (user, pass string, ok bool) {
+	user = c.AdminUser
+	pass = c.AdminPass
+	ok = user != "" && pass != ""
+	return user, pass, ok
+}
+
 // TLSCertFiles returns certificate and key files and an error if
 // both files doesn't exist and have approperiate file permissions
 func (c *Config) TLSCertFiles() (cert, key string, err error) {
@@ -256,7 +310,7 @@ func ParseConfigFile(configFile string) (*Config, error) {
 
 	// Check file perms from config
 	if config.GoEnv == "production" {
-		if err := checkFilePerms(configFile, config.FilterFile); err != nil {
+		if err := checkFilePerms(configFile, config.FilterFile, config.AuthFile); err != nil {
 			return nil, err
 		}
 	}
@@ -308,6 +362,11 @@ func validateConfig(config Config) error {
 	if err != nil {
 		return err
 	}
+	// usage stores connect to the same databases as the index
+	err = validateIndex(validate, config.UsageType, config.Index)
+	if err != nil {
+		return fmt.Errorf("usage: %w", err)
+	}
 	return nil
 }
 
@@ -329,11 +388,79 @@ func validateStorage(validate *validator.Validate, storageType string, config *S
 		return validate.Struct(config.AzureBlob)
 	case "external":
 		return validate.Struct(config.External)
+	case "tiered":
+		if err := validate.Struct(config.Tiered); err != nil {
+			return err
+		}
+		if config.Tiered.RemoteStorageType == "tiered" {
+			return fmt.Errorf("the remote storage of a tiered storage cannot be tiered")
+		}
+		return validateStorage(validate, config.Tiered.RemoteStorageType, config)
+	case "replicated":
+		if err := validate.Struct(config.Replicated); err != nil {
+			return err
+		}
+		return validateReplicas(validate, config)
 	default:
 		return fmt.Errorf("storage type %q is unknown", storageType)
 	}
 }
 
+// validateReplicas validates the backends of a replicated storage, which
+// must not be the same backend twice, since a backend would then be
+// replicated onto itself.
+func validateReplicas(validate *validator.Validate, config *Storage) error {
+	rc := config.Replicated
+	if len(rc.Secondaries) > len(rc.SecondaryStorageTypes) {
+		return fmt.Errorf("a replicated storage has %d secondaries but %d secondary storage types", len(rc.Secondaries), len(rc.SecondaryStorageTypes))
+	}
+	types := []string{rc.PrimaryStorageType}
+	configs := []*Storage{config}
+	for i, st := range rc.SecondaryStorageTypes {
+		types = append(types, st)
+		configs = append(configs, rc.SecondaryStorage(i, config))
+	}
+	for i, st := range types {
+		if st == "replicated" {
+			return fmt.Errorf("a replicated storage cannot replicate a replicated storage")
+		}
+		if err := validateStorage(validate, st, configs[i]); err != nil {
+			return err
+		}
+		for j := 0; j < i; j++ {
+			if types[j] == st && reflect.DeepEqual(backendConfig(st, configs[j]), backendConfig(st, configs[i])) {
+				return fmt.Errorf("a replicated storage cannot replicate a %s storage onto itself, configure its secondaries in Storage.Replicated.Secondaries", st)
+			}
+		}
+	}
+	return nil
+}
+
+// backendConfig returns the section of config that configures
+// a backend of type storageType.
+func backendConfig(storageType string, config *Storage) interface{} {
+	switch storageType {
+	case "mongo":
+		return config.Mongo
+	case "disk":
+		return config.Disk
+	case "minio":
+		return config.Minio
+	case "gcp":
+		return config.GCP
+	case "s3":
+		return config.S3
+	case "azureblob":
+		return config.AzureBlob
+	case "external":
+		return config.External
+	case "tiered":
+		return config.Tiered
+	default:
+		return nil
+	}
+}
+
 func validateIndex(validate *validator.Validate, indexType string, config *Index) error {
 	switch indexType {
 	case "", "none", "memory":
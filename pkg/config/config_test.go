@@ -9,10 +9,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/BurntSushi/toml"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/go-playground/validator.v9"
 )
 
 func testConfigFile(t *testing.T) (testConfigFile string) {
@@ -269,31 +271,45 @@ func TestParseExampleConfig(t *testing.T) {
 		GoBinary:        "go",
 		GoProxy:         "direct",
 		GoGetWorkers:    10,
+		FetcherType:     "go",
+		ListerType:      "go",
+		UpstreamGoProxy: "https://proxy.golang.org,direct",
 		ProtocolWorkers: 30,
 		CloudRuntime:    "none",
 		TimeoutConf: TimeoutConf{
 			Timeout: 300,
 		},
-		StorageType:      "memory",
-		GlobalEndpoint:   "http://localhost:3001",
-		Port:             ":3000",
-		EnablePprof:      false,
-		PprofPort:        ":3001",
-		BasicAuthUser:    "",
-		BasicAuthPass:    "",
-		Storage:          expStorage,
-		TraceExporterURL: "http://localhost:14268",
-		TraceExporter:    "",
-		StatsExporter:    "prometheus",
-		SingleFlightType: "memory",
-		GoBinaryEnvVars:  []string{"GOPROXY=direct"},
-		SingleFlight:     &SingleFlight{},
-		SumDBs:           []string{"https://sum.golang.org"},
-		NoSumPatterns:    []string{},
-		DownloadMode:     "sync",
-		RobotsFile:       "robots.txt",
-		IndexType:        "none",
-		Index:            &Index{},
+		StorageType:          "memory",
+		GlobalEndpoint:       "http://localhost:3001",
+		Port:                 ":3000",
+		EnablePprof:          false,
+		PprofPort:            ":3001",
+		BasicAuthUser:        "",
+		BasicAuthPass:        "",
+		Storage:              expStorage,
+		TraceExporterURL:     "http://localhost:14268",
+		TraceExporter:        "",
+		StatsExporter:        "prometheus",
+		SingleFlightType:     "memory",
+		NotFoundCacheType:    "none",
+		NotFoundCacheTTL:     300,
+		ValidatorCacheTTL:    300,
+		FilterReloadInterval: 10,
+		PinReloadInterval:    10,
+		AuthReloadInterval:   10,
+		GoBinaryEnvVars:      []string{"GOPROXY=direct"},
+		SingleFlight:         &SingleFlight{},
+		SumDBs:               []string{"https://sum.golang.org"},
+		NoSumPatterns:        []string{},
+		DownloadMode:         "sync",
+		RobotsFile:           "robots.txt",
+		IndexType:            "none",
+		UsageType:            "none",
+		ScrubAction:          "report",
+		EventsWebhookRetries: 3,
+		EventsQueueSize:      1000,
+		RetentionDryRun:      true,
+		Index:                &Index{},
 	}
 
 	absPath, err := filepath.Abs(testConfigFile(t))
@@ -638,3 +654,37 @@ func testDecode(t *testing.T, tc decodeTestCase) {
 	}
 	require.Equal(t, tc.expected, config.GoBinaryEnvVars)
 }
+
+func TestReplicatedStorage(t *testing.T) {
+	const conf = `
+[Storage]
+    [Storage.S3]
+        Region = "us-east-1"
+        Bucket = "modules"
+    [Storage.Replicated]
+        PrimaryStorageType = "s3"
+        SecondaryStorageTypes = ["s3"]
+        [[Storage.Replicated.Secondaries]]
+            [Storage.Replicated.Secondaries.S3]
+                Region = "eu-west-1"
+                Bucket = "modules-replica"
+`
+	var c Config
+	_, err := toml.Decode(conf, &c)
+	require.NoError(t, err)
+	require.NoError(t, envconfig.Process("athens", &c))
+	rc := c.Storage.Replicated
+	require.Len(t, rc.Secondaries, 1)
+	require.Equal(t, "eu-west-1", rc.SecondaryStorage(0, c.Storage).S3.Region)
+	require.NoError(t, validateStorage(validator.New(), "replicated", c.Storage))
+
+	rc.Secondaries = nil
+	require.Equal(t, c.Storage, rc.SecondaryStorage(0, c.Storage))
+	err = validateStorage(validator.New(), "replicated", c.Storage)
+	require.Error(t, err, "a bucket must not be replicated onto itself")
+
+	rc.Secondaries = []*Storage{{S3: &S3Config{}}}
+	*rc.Secondaries[0].S3 = *c.Storage.S3
+	err = validateStorage(validator.New(), "replicated", c.Storage)
+	require.Error(t, err, "a bucket must not be replicated onto itself")
+}
@@ -0,0 +1,24 @@
+package config
+
+// ReplicatedConfig specifies the properties required to replicate
+// a primary storage backend to secondary storage backends
+type ReplicatedConfig struct {
+	PrimaryStorageType    string   `validate:"required" envconfig:"ATHENS_REPLICATED_PRIMARY_STORAGE_TYPE"`
+	SecondaryStorageTypes []string `validate:"required" envconfig:"ATHENS_REPLICATED_SECONDARY_STORAGE_TYPES"`
+	// Secondaries configure the backends of SecondaryStorageTypes, in
+	// the same order, so that a secondary can be of the same type as
+	// the primary, such as a bucket in another region. A secondary
+	// without one is configured by the section of its type in Storage.
+	Secondaries    []*Storage
+	Async          bool `envconfig:"ATHENS_REPLICATED_ASYNC"`
+	RepairInterval int  `envconfig:"ATHENS_REPLICATED_REPAIR_INTERVAL"`
+}
+
+// SecondaryStorage returns the configuration of the i-th
+// secondary backend, which is shared if it has none of its own.
+func (c *ReplicatedConfig) SecondaryStorage(i int, shared *Storage) *Storage {
+	if i < len(c.Secondaries) && c.Secondaries[i] != nil {
+		return c.Secondaries[i]
+	}
+	return shared
+}
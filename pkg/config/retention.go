@@ -0,0 +1,17 @@
+package config
+
+// RetentionRule selects module versions for retention to delete.
+// A version is deleted if any condition of the first rule whose
+// Patterns match its module is met. A rule without conditions
+// keeps the versions of the modules it matches.
+type RetentionRule struct {
+	// Patterns are module path patterns in the GONOSUMDB format.
+	// A rule without patterns matches every module.
+	Patterns []string
+	// MaxIdleDays deletes versions not downloaded for that many days.
+	MaxIdleDays int
+	// KeepNewest deletes all but the newest KeepNewest versions of a module.
+	KeepNewest int
+	// MaxPseudoAgeDays deletes pseudo-versions of commits older than that many days.
+	MaxPseudoAgeDays int
+}
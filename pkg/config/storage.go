@@ -2,11 +2,13 @@ package config
 
 // Storage provides configs for various storage backends
 type Storage struct {
-	Disk      *DiskConfig
-	GCP       *GCPConfig
-	Minio     *MinioConfig
-	Mongo     *MongoConfig
-	S3        *S3Config
-	AzureBlob *AzureBlobConfig
-	External  *External
+	Disk       *DiskConfig
+	GCP        *GCPConfig
+	Minio      *MinioConfig
+	Mongo      *MongoConfig
+	S3         *S3Config
+	AzureBlob  *AzureBlobConfig
+	External   *External
+	Tiered     *TieredConfig
+	Replicated *ReplicatedConfig
 }
@@ -0,0 +1,9 @@
+package config
+
+// TieredConfig specifies the properties required to use a bounded
+// local tier of storage in front of another storage backend
+type TieredConfig struct {
+	RemoteStorageType string `validate:"required" envconfig:"ATHENS_TIERED_REMOTE_STORAGE_TYPE"`
+	LocalPath         string `envconfig:"ATHENS_TIERED_LOCAL_PATH"`
+	MaxSizeMB         int    `validate:"required" envconfig:"ATHENS_TIERED_MAX_SIZE_MB"`
+}
@@ -126,3 +126,19 @@ func (p *withpool) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCl
 	}
 	return zip, nil
 }
+
+func (p *withpool) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "pool.Sum"
+	var sum []byte
+	var err error
+	done := make(chan struct{}, 1)
+	p.jobCh <- func() {
+		sum, err = p.dp.Sum(ctx, mod, ver)
+		close(done)
+	}
+	<-done
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return sum, nil
+}
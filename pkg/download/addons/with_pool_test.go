@@ -89,6 +89,10 @@ func TestPoolWrapper(t *testing.T) {
 	if m.err.Error() != err.Error() {
 		t.Fatalf("dp.Zip: expected err to be `%v` but got `%v`", m.err, err)
 	}
+	_, err = dp.Sum(ctx, mod, ver)
+	if m.err.Error() != err.Error() {
+		t.Fatalf("dp.Sum: expected err to be `%v` but got `%v`", m.err, err)
+	}
 }
 
 type mockDP struct {
@@ -98,6 +102,7 @@ type mockDP struct {
 	latest   *storage.RevInfo
 	gomod    []byte
 	zip      storage.SizeReadCloser
+	sum      []byte
 	inputMod string
 	inputVer string
 	catalog  []paths.AllPathParams
@@ -152,6 +157,17 @@ func (m *mockDP) Zip(ctx context.Context, mod, ver string) (storage.SizeReadClos
 	return m.zip, m.err
 }
 
+// Sum implements GET /{module}/@v/{version}.sum
+func (m *mockDP) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	if m.inputMod != mod {
+		return nil, fmt.Errorf("expected mod input %v but got %v", m.inputMod, mod)
+	}
+	if m.inputVer != ver {
+		return nil, fmt.Errorf("expected ver input %v but got %v", m.inputVer, ver)
+	}
+	return m.sum, m.err
+}
+
 // Version is a helper method to get Info, GoMod, and Zip together.
 func (m *mockDP) Version(ctx context.Context, mod, ver string) (*storage.Version, error) {
 	panic("skipped")
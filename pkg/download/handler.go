@@ -1,14 +1,19 @@
 package download
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 
 	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/middleware"
+	"github.com/gomods/athens/pkg/storage"
 	"github.com/gorilla/mux"
+	"golang.org/x/mod/semver"
 )
 
 // ProtocolHandler is a function that takes all that it needs to return
@@ -21,8 +26,26 @@ type HandlerOpts struct {
 	Protocol     Protocol
 	Logger       *log.Logger
 	DownloadFile *mode.DownloadFile
+	// Downloads, if not nil, is told about every go.mod
+	// file and zip that is successfully downloaded.
+	Downloads DownloadRecorder
+	// ZipURLs, if not nil, gives out the URLs that zip
+	// downloads are redirected to, valid for ZipURLExpiry.
+	// Zips that it has no URL for are served as usual.
+	ZipURLs      storage.ZipURLer
+	ZipURLExpiry time.Duration
 }
 
+// DownloadRecorder records the go.mod files and zips
+// that are downloaded, such as to count them.
+type DownloadRecorder interface {
+	RecordGoMod(ctx context.Context, mod, ver string)
+	RecordZip(ctx context.Context, mod, ver string)
+}
+
+// recordFunc records a download of mod@ver.
+type recordFunc func(ctx context.Context, mod, ver string)
+
 // LogEntryHandler pulls a log entry from the request context. Thanks to the
 // LogEntryMiddleware, we should have a log entry stored in the context for each
 // request with request-specific fields. This will grab the entry and pass it to
@@ -51,9 +74,65 @@ func RegisterHandlers(r *mux.Router, opts *HandlerOpts) {
 	latestHandler := LogEntryHandler(LatestHandler, opts)
 	r.Handle(PathLatest, noCacheMw(latestHandler)).Methods(http.MethodGet)
 
+	var goModRecs, zipRecs []recordFunc
+	if opts.Downloads != nil {
+		goModRecs = append(goModRecs, opts.Downloads.RecordGoMod)
+		zipRecs = append(zipRecs, opts.Downloads.RecordZip)
+	}
+
 	r.Handle(PathVersionInfo, LogEntryHandler(InfoHandler, opts)).Methods(http.MethodGet)
-	r.Handle(PathVersionModule, LogEntryHandler(ModuleHandler, opts)).Methods(http.MethodGet)
-	r.Handle(PathVersionZip, LogEntryHandler(ZipHandler, opts)).Methods(http.MethodGet, http.MethodHead)
+	r.Handle(PathVersionModule, recordDownload(LogEntryHandler(ModuleHandler, opts), goModRecs...)).Methods(http.MethodGet)
+	zipHandler := LogEntryHandler(ZipHandler, opts)
+	if opts.ZipURLs != nil {
+		zipHandler = zipURLHandler(opts.ZipURLs, opts.ZipURLExpiry, zipHandler)
+	}
+	r.Handle(PathVersionZip, recordDownload(zipHandler, zipRecs...)).Methods(http.MethodGet, http.MethodHead)
+	r.Handle(PathVersionSum, LogEntryHandler(SumHandler, opts)).Methods(http.MethodGet)
+}
+
+// recordDownload calls every record func with the module versions
+// that h serves successfully, or redirects to storage, in response
+// to GET requests. Queries such as branch names are not recorded.
+func recordDownload(h http.Handler, recs ...recordFunc) http.Handler {
+	if len(recs) == 0 {
+		return h
+	}
+	const op errors.Op = "download.recordDownload"
+	f := func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		h.ServeHTTP(sw, r)
+		if r.Method != http.MethodGet || (sw.statusCode != http.StatusOK && sw.statusCode != http.StatusFound) {
+			return
+		}
+		mod, ver, err := getModuleParams(r, op)
+		if err != nil || !isVersion(ver) {
+			return
+		}
+		for _, rec := range recs {
+			rec(r.Context(), mod, ver)
+		}
+	}
+	return http.HandlerFunc(f)
+}
+
+// isVersion reports whether ver is a complete semantic version, such as
+// v1.2.3, a pseudo-version or v2.0.0+incompatible, rather than a query
+// such as a branch name or v1.2, which resolves to other versions over time.
+func isVersion(ver string) bool {
+	c := semver.Canonical(ver)
+	return semver.IsValid(ver) && (ver == c || ver == c+"+incompatible")
+}
+
+// statusWriter remembers the status code
+// written to a ResponseWriter.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	sw.statusCode = statusCode
+	sw.ResponseWriter.WriteHeader(statusCode)
 }
 
 func getRedirectURL(base, downloadPath string) (string, error) {
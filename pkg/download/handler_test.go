@@ -62,3 +62,53 @@ func (mp *mockProtocol) Zip(ctx context.Context, mod, ver string) (storage.SizeR
 	const op errors.Op = "mockProtocol.Zip"
 	return nil, errors.E(op, "not found", errors.KindRedirect)
 }
+
+type downloadRecorder struct {
+	goMods, zips int
+}
+
+func (dr *downloadRecorder) RecordGoMod(ctx context.Context, mod, ver string) { dr.goMods++ }
+
+func (dr *downloadRecorder) RecordZip(ctx context.Context, mod, ver string) { dr.zips++ }
+
+type goModProtocol struct {
+	Protocol
+}
+
+func (gp *goModProtocol) GoMod(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "goModProtocol.GoMod"
+	switch ver {
+	case "v1.0.0", "v2.0.0+incompatible", "master", "v1.0":
+	default:
+		return nil, errors.E(op, "not found", errors.KindNotFound)
+	}
+	return []byte("module " + mod), nil
+}
+
+func (gp *goModProtocol) Info(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "goModProtocol.Info"
+	return nil, errors.E(op, "not found", errors.KindNotFound)
+}
+
+func TestRecordDownload(t *testing.T) {
+	var downloads downloadRecorder
+	r := mux.NewRouter()
+	RegisterHandlers(r, &HandlerOpts{
+		Protocol:     &goModProtocol{},
+		Logger:       log.NoOpLogger(),
+		DownloadFile: &mode.DownloadFile{Mode: mode.Sync},
+		Downloads:    &downloads,
+	})
+	for _, path := range [...]string{
+		"/github.com/gomods/athens/@v/v1.0.0.mod",
+		"/github.com/gomods/athens/@v/v1.0.1.mod",
+		"/github.com/gomods/athens/@v/v2.0.0+incompatible.mod",
+		"/github.com/gomods/athens/@v/master.mod",
+		"/github.com/gomods/athens/@v/v1.0.mod",
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if downloads.goMods != 2 || downloads.zips != 0 {
+		t.Fatalf("expected only the successful download of a version to be counted but got %d go.mod and %d zip downloads", downloads.goMods, downloads.zips)
+	}
+}
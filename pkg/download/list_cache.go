@@ -0,0 +1,164 @@
+package download
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/trace"
+)
+
+// maxListCacheEntries is the number of modules whose
+// upstream version lists are cached. When it is reached,
+// the list that was fetched first is evicted.
+const maxListCacheEntries = 10000
+
+// listCache is an UpstreamLister that caches the version lists
+// and latest versions of modules, so that they are not listed
+// upstream on every request, and so that Athens keeps serving
+// them while upstream is down.
+//
+// A list is fresh for the TTL of its module, and then stale for
+// the stale window of its module: a stale list is still served,
+// but it is refreshed in the background. After that, the list is
+// fetched again before it is served, and if upstream fails, the
+// cached list is served anyway. See mode.DownloadFile.ListCache.
+type listCache struct {
+	lister     module.UpstreamLister
+	df         *mode.DownloadFile
+	ttl, stale time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*listEntry
+}
+
+type listEntry struct {
+	info       *storage.RevInfo
+	versions   []string
+	fetched    time.Time
+	refreshing bool
+}
+
+// newListCache returns a listCache in front of lister, with ttl
+// and stale as the defaults that df can override per module.
+func newListCache(lister module.UpstreamLister, df *mode.DownloadFile, ttl, stale time.Duration) *listCache {
+	return &listCache{
+		lister:  lister,
+		df:      df,
+		ttl:     ttl,
+		stale:   stale,
+		now:     time.Now,
+		entries: map[string]*listEntry{},
+	}
+}
+
+func (c *listCache) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "listCache.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	ttl, stale := c.df.ListCache(mod, c.ttl, c.stale)
+	if ttl <= 0 && stale <= 0 {
+		return c.lister.List(ctx, mod)
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[mod]
+	if ok {
+		age := c.now().Sub(e.fetched)
+		if age < ttl {
+			c.mu.Unlock()
+			return e.get()
+		}
+		if age < ttl+stale {
+			if !e.refreshing {
+				e.refreshing = true
+				go c.refresh(ctx, mod)
+			}
+			c.mu.Unlock()
+			return e.get()
+		}
+	}
+	c.mu.Unlock()
+
+	info, versions, err := c.fetch(ctx, mod)
+	if err != nil {
+		// a repository that is not found is not an outage:
+		// its versions are gone, and must not be served.
+		if ok && !errors.IsRepoNotFoundErr(err) {
+			log.EntryFromContext(ctx).SystemErr(errors.E(op, errors.M(mod), err, logrus.WarnLevel))
+			return e.get()
+		}
+		return nil, nil, errors.E(op, err)
+	}
+	return info, versions, nil
+}
+
+// refresh fetches the list of mod in the background. It keeps the
+// span and the logger of ctx, but not its deadline and cancelation,
+// since the request that triggered it is served without waiting.
+func (c *listCache) refresh(ctx context.Context, mod string) {
+	const op errors.Op = "listCache.refresh"
+	lggr := log.EntryFromContext(ctx)
+	ctx, cancel := context.WithTimeout(trace.NewContext(context.Background(), trace.FromContext(ctx)), time.Minute*5)
+	defer cancel()
+	ctx = log.SetEntryInContext(ctx, lggr)
+	if _, _, err := c.fetch(ctx, mod); err != nil {
+		lggr.SystemErr(errors.E(op, errors.M(mod), err, logrus.WarnLevel))
+	}
+}
+
+// fetch lists mod upstream and caches the result. If the repository
+// of mod is not found, its cached list is dropped. Other errors
+// keep the cached list, so that it can be served while upstream is down.
+func (c *listCache) fetch(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	info, versions, err := c.lister.List(ctx, mod)
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if errors.IsRepoNotFoundErr(err) {
+			delete(c.entries, mod)
+		} else if e, ok := c.entries[mod]; ok {
+			e.refreshing = false
+		}
+		return nil, nil, err
+	}
+	if _, ok := c.entries[mod]; !ok && len(c.entries) >= maxListCacheEntries {
+		c.evict()
+	}
+	e := &listEntry{info: info, versions: versions, fetched: now}
+	c.entries[mod] = e
+	return e.get()
+}
+
+// evict removes the list that was fetched first.
+// It must be called with c.mu held.
+func (c *listCache) evict() {
+	var oldest string
+	var oldestFetched time.Time
+	for mod, e := range c.entries {
+		if oldest == "" || e.fetched.Before(oldestFetched) {
+			oldest, oldestFetched = mod, e.fetched
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+// get returns copies of the cached list, so
+// that callers can modify them without a lock.
+func (e *listEntry) get() (*storage.RevInfo, []string, error) {
+	var info *storage.RevInfo
+	if e.info != nil {
+		cp := *e.info
+		info = &cp
+	}
+	return info, append([]string(nil), e.versions...), nil
+}
@@ -0,0 +1,104 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLister lists the versions it is set to,
+// and can be used from several goroutines.
+type countingLister struct {
+	mu       sync.Mutex
+	versions []string
+	err      error
+	calls    int
+}
+
+func (l *countingLister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls++
+	if l.err != nil {
+		return nil, nil, l.err
+	}
+	return &storage.RevInfo{Version: l.versions[len(l.versions)-1]}, l.versions, nil
+}
+
+func (l *countingLister) set(versions []string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.versions, l.err = versions, err
+}
+
+func (l *countingLister) numCalls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls
+}
+
+func TestListCache(t *testing.T) {
+	const mod = "github.com/gomods/athens"
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	lister := &countingLister{versions: []string{"v1.0.0"}}
+	c := newListCache(lister, &mode.DownloadFile{Mode: mode.Sync}, time.Minute, time.Hour)
+	c.now = func() time.Time { return now }
+	list := func() []string {
+		_, versions, err := c.List(ctx, mod)
+		require.NoError(t, err)
+		return versions
+	}
+
+	require.Equal(t, []string{"v1.0.0"}, list())
+	lister.set([]string{"v1.0.0", "v1.1.0"}, nil)
+	require.Equal(t, []string{"v1.0.0"}, list(), "a fresh list must be served from the cache")
+	require.Equal(t, 1, lister.numCalls())
+
+	now = now.Add(time.Minute)
+	require.Equal(t, []string{"v1.0.0"}, list(), "a stale list must be served while it is refreshed")
+	require.Eventually(t, func() bool { return lister.numCalls() == 2 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return !c.entries[mod].refreshing
+	}, time.Second, time.Millisecond)
+	require.Equal(t, []string{"v1.0.0", "v1.1.0"}, list())
+
+	now = now.Add(2 * time.Hour)
+	lister.set(nil, errors.New("github is down"))
+	require.Equal(t, []string{"v1.0.0", "v1.1.0"}, list(), "an expired list must be served if upstream fails")
+	require.Equal(t, 3, lister.numCalls())
+
+	lister.set(nil, errors.New("remote: Repository not found"))
+	_, _, err := c.List(ctx, mod)
+	require.Error(t, err, "the list of a repository that is gone must not be served")
+	_, _, err = c.List(ctx, mod)
+	require.Error(t, err)
+	require.Equal(t, 5, lister.numCalls())
+}
+
+func TestListCacheOverride(t *testing.T) {
+	ctx := context.Background()
+	lister := &countingLister{versions: []string{"v1.0.0"}}
+	df := &mode.DownloadFile{
+		Mode: mode.Sync,
+		Paths: []*mode.DownloadPath{
+			{Pattern: "github.com/gomods/*", Mode: mode.Sync, ListCacheTTL: "0s"},
+		},
+	}
+	c := newListCache(lister, df, time.Hour, 0)
+	for i := 0; i < 2; i++ {
+		_, _, err := c.List(ctx, "github.com/gomods/athens")
+		require.NoError(t, err)
+		_, _, err = c.List(ctx, "github.com/pkg/errors")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, lister.numCalls(), "only the modules that do not override the TTL must be cached")
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	athenserr "github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/notfound"
 	"github.com/gomods/athens/pkg/storage"
 	"github.com/gomods/athens/pkg/storage/mem"
 	"github.com/stretchr/testify/require"
@@ -103,9 +105,11 @@ var listMergeTests = []listMergeTest{
 type listerMock struct {
 	versions []string
 	err      error
+	calls    int
 }
 
 func (l *listerMock) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	l.calls++
 	return nil, l.versions, l.err
 }
 
@@ -128,7 +132,7 @@ func TestListMerge(t *testing.T) {
 				s.Save(ctx, testModName, v, bts, ioutil.NopCloser(bytes.NewReader(bts)), bts)
 			}
 			defer clearStorage(s, testModName, tc.strVersions)
-			dp := New(&Opts{s, nil, &listerMock{versions: tc.goVersions, err: tc.goErr}, nil})
+			dp := New(&Opts{Storage: s, Lister: &listerMock{versions: tc.goVersions, err: tc.goErr}})
 			list, err := dp.List(ctx, testModName)
 
 			if ok := testErrEq(tc.expectedErr, err); !ok {
@@ -156,3 +160,44 @@ func testErrEq(a, b error) bool {
 	}
 	return true
 }
+
+func TestListNotFoundCache(t *testing.T) {
+	ctx := context.Background()
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	lister := &listerMock{err: errors.New("remote: Repository not found")}
+	nf := notfound.NewMemory(time.Hour)
+	dp := New(&Opts{Storage: s, Lister: lister, NotFound: nf})
+
+	for i := 0; i < 2; i++ {
+		_, err = dp.List(ctx, testModName)
+		require.Equal(t, athenserr.KindNotFound, athenserr.Kind(err))
+		_, err = dp.Latest(ctx, testModName)
+		require.Equal(t, athenserr.KindNotFound, athenserr.Kind(err))
+	}
+	require.Equal(t, 1, lister.calls, "a repository that is not found must only be listed once")
+
+	// the stored versions are still listed
+	// when the repository is cached as not found.
+	bts := []byte("123")
+	require.NoError(t, s.Save(ctx, testModName, "v1.0.0", bts, ioutil.NopCloser(bytes.NewReader(bts)), bts))
+	list, err := dp.List(ctx, testModName)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.0.0"}, list)
+	require.Equal(t, 1, lister.calls)
+
+	require.NoError(t, nf.Invalidate(ctx, testModName, ""))
+	lister.err = nil
+	lister.versions = []string{"v1.0.1"}
+	list, err = dp.List(ctx, testModName)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"v1.0.0", "v1.0.1"}, list)
+	require.Equal(t, 2, lister.calls)
+
+	lister.err = errors.New("unexpected error")
+	_, err = dp.List(ctx, testModName)
+	require.Error(t, err)
+	_, err = dp.List(ctx, testModName)
+	require.Error(t, err)
+	require.Equal(t, 4, lister.calls, "only repositories that are not found must be cached")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/paths"
@@ -30,18 +31,26 @@ const (
 // DownloadFile represents a custom HCL format of
 // how to handle module@version requests that are
 // not found in storage.
+//
+// ListCacheTTL and ListCacheStale are durations, such as "10m",
+// that override how long the upstream version lists of modules
+// are cached for. See ListCache.
 type DownloadFile struct {
-	Mode        Mode            `hcl:"mode"`
-	DownloadURL string          `hcl:"downloadURL"`
-	Paths       []*DownloadPath `hcl:"download,block"`
+	Mode           Mode            `hcl:"mode"`
+	DownloadURL    string          `hcl:"downloadURL"`
+	ListCacheTTL   string          `hcl:"listCacheTTL,optional"`
+	ListCacheStale string          `hcl:"listCacheStale,optional"`
+	Paths          []*DownloadPath `hcl:"download,block"`
 }
 
 // DownloadPath represents a custom Mode for
 // a matching path.
 type DownloadPath struct {
-	Pattern     string `hcl:"pattern,label"`
-	Mode        Mode   `hcl:"mode"`
-	DownloadURL string `hcl:"downloadURL,optional"`
+	Pattern        string `hcl:"pattern,label"`
+	Mode           Mode   `hcl:"mode"`
+	DownloadURL    string `hcl:"downloadURL,optional"`
+	ListCacheTTL   string `hcl:"listCacheTTL,optional"`
+	ListCacheStale string `hcl:"listCacheStale,optional"`
 }
 
 // NewFile takes a mode and returns a DownloadFile.
@@ -101,12 +110,36 @@ func parseFile(file []byte) (*DownloadFile, error) {
 
 func (d *DownloadFile) validate() error {
 	const op errors.Op = "downloadMode.validate"
+	if err := validateDurations(d.ListCacheTTL, d.ListCacheStale); err != nil {
+		return errors.E(op, err)
+	}
 	for _, p := range d.Paths {
 		switch p.Mode {
 		case Sync, Async, Redirect, AsyncRedirect, None:
 		default:
 			return errors.E(op, fmt.Errorf("unrecognized mode for %v: %v", p.Pattern, p.Mode))
 		}
+		if err := validateDurations(p.ListCacheTTL, p.ListCacheStale); err != nil {
+			return errors.E(op, fmt.Errorf("%v: %v", p.Pattern, err))
+		}
+	}
+	return nil
+}
+
+// validateDurations returns an error if any
+// of durs is set but is not a valid duration.
+func validateDurations(durs ...string) error {
+	for _, dur := range durs {
+		if dur == "" {
+			continue
+		}
+		d, err := time.ParseDuration(dur)
+		if err != nil {
+			return err
+		}
+		if d < 0 {
+			return fmt.Errorf("negative duration: %v", dur)
+		}
 	}
 	return nil
 }
@@ -138,3 +171,45 @@ func (d *DownloadFile) URL(mod string) string {
 	}
 	return d.DownloadURL
 }
+
+// ListCache returns how long the upstream version list of the
+// given module is cached for: the list is fresh for ttl, and then
+// stale for another stale, during which it is still served while
+// it is refreshed in the background. Each of them is taken from
+// the first matching pattern that sets it, else from the top
+// level of the file, else the given defaults are returned.
+func (d *DownloadFile) ListCache(mod string, ttl, stale time.Duration) (time.Duration, time.Duration) {
+	var ttlSet, staleSet bool
+	for _, p := range d.Paths {
+		if !paths.MatchesPattern(p.Pattern, mod) {
+			continue
+		}
+		if !ttlSet {
+			ttlSet = parseDuration(p.ListCacheTTL, &ttl)
+		}
+		if !staleSet {
+			staleSet = parseDuration(p.ListCacheStale, &stale)
+		}
+	}
+	if !ttlSet {
+		parseDuration(d.ListCacheTTL, &ttl)
+	}
+	if !staleSet {
+		parseDuration(d.ListCacheStale, &stale)
+	}
+	return ttl, stale
+}
+
+// parseDuration sets d to dur and returns true
+// if dur is set and is a valid duration.
+func parseDuration(dur string, d *time.Duration) bool {
+	if dur == "" {
+		return false
+	}
+	parsed, err := time.ParseDuration(dur)
+	if err != nil {
+		return false
+	}
+	*d = parsed
+	return true
+}
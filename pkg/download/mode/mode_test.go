@@ -3,6 +3,7 @@ package mode
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 var testCases = []struct {
@@ -151,3 +152,59 @@ func TestNewFile_err(t *testing.T) {
 		})
 	}
 }
+
+func TestListCache(t *testing.T) {
+	file := &DownloadFile{
+		Mode:         Sync,
+		ListCacheTTL: "10m",
+		Paths: []*DownloadPath{
+			{Pattern: "github.com/gomods/athens", Mode: Sync, ListCacheStale: "1h"},
+			{Pattern: "github.com/gomods/*", Mode: Sync, ListCacheTTL: "0s"},
+			{Pattern: "github.com/pkg/*", Mode: Sync, ListCacheTTL: "1m", ListCacheStale: "24h"},
+		},
+	}
+	tests := []struct {
+		input         string
+		expectedTTL   time.Duration
+		expectedStale time.Duration
+	}{
+		{input: "github.com/gomods/athens", expectedTTL: 0, expectedStale: time.Hour},
+		{input: "github.com/gomods/other", expectedTTL: 0, expectedStale: time.Second},
+		{input: "github.com/pkg/errors", expectedTTL: time.Minute, expectedStale: 24 * time.Hour},
+		{input: "golang.org/x/mod", expectedTTL: 10 * time.Minute, expectedStale: time.Second},
+	}
+	for _, tc := range tests {
+		ttl, stale := file.ListCache(tc.input, time.Hour, time.Second)
+		if ttl != tc.expectedTTL || stale != tc.expectedStale {
+			t.Fatalf("expected %s to be cached for %v and stale for %v but got %v and %v", tc.input, tc.expectedTTL, tc.expectedStale, ttl, stale)
+		}
+	}
+}
+
+func TestParseListCache(t *testing.T) {
+	_, err := parseFile([]byte(`
+mode = "sync"
+downloadURL = ""
+listCacheTTL = "5m"
+
+download "github.com/gomods/*" {
+    mode = "sync"
+    listCacheStale = "1h"
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parseFile([]byte(`
+mode = "sync"
+downloadURL = ""
+
+download "github.com/gomods/*" {
+    mode = "sync"
+    listCacheTTL = "forever"
+}
+`))
+	if err == nil {
+		t.Fatal("expected an invalid listCacheTTL to fail parsing")
+	}
+}
@@ -2,16 +2,22 @@ package download
 
 import (
 	"context"
+	"fmt"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/gomods/athens/pkg/checksum"
 	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/notfound"
 	"github.com/gomods/athens/pkg/observ"
 	"github.com/gomods/athens/pkg/stash"
 	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
 )
 
 // Protocol is the download protocol which mirrors
@@ -31,6 +37,9 @@ type Protocol interface {
 
 	// Zip implements GET /{module}/@v/{version}.zip
 	Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error)
+
+	// Sum implements GET /{module}/@v/{version}.sum
+	Sum(ctx context.Context, mod, ver string) ([]byte, error)
 }
 
 // Wrapper helps extend the main protocol's functionality with addons.
@@ -42,6 +51,23 @@ type Opts struct {
 	Stasher      stash.Stasher
 	Lister       module.UpstreamLister
 	DownloadFile *mode.DownloadFile
+	// NotFound, if set, caches the modules that upstream
+	// reported as not found, so that they are not listed
+	// upstream again until they expire.
+	NotFound notfound.Cache
+	// ListCacheTTL and ListCacheStale are how long the upstream
+	// version lists of modules are cached for, unless DownloadFile
+	// overrides them. Lists are not cached if both are 0.
+	ListCacheTTL   time.Duration
+	ListCacheStale time.Duration
+	// Fs and TempDir are where zips are spooled to compute
+	// the sums of stored versions that have none. They
+	// default to the OS file system and its temp directory.
+	Fs      afero.Fs
+	TempDir string
+	// Verifier, if not nil, checks computed sums against a checksum
+	// database before they are saved. Without it, they are not saved.
+	Verifier *checksum.Verifier
 }
 
 // New returns a full implementation of the download.Protocol
@@ -53,7 +79,15 @@ func New(opts *Opts, wrappers ...Wrapper) Protocol {
 	if opts.DownloadFile == nil {
 		opts.DownloadFile = &mode.DownloadFile{Mode: mode.Sync}
 	}
-	var p Protocol = &protocol{opts.DownloadFile, opts.Storage, opts.Stasher, opts.Lister}
+	lister := opts.Lister
+	if lister != nil {
+		lister = newListCache(lister, opts.DownloadFile, opts.ListCacheTTL, opts.ListCacheStale)
+	}
+	fs := opts.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+	var p Protocol = &protocol{opts.DownloadFile, opts.Storage, opts.Stasher, lister, opts.NotFound, fs, opts.TempDir, opts.Verifier}
 	for _, w := range wrappers {
 		p = w(p)
 	}
@@ -66,6 +100,12 @@ type protocol struct {
 	storage storage.Backend
 	stasher stash.Stasher
 	lister  module.UpstreamLister
+	// notFound is nil if not found results are not cached.
+	notFound notfound.Cache
+	fs       afero.Fs
+	tempDir  string
+	// verifier is nil if computed sums are not saved.
+	verifier *checksum.Verifier
 }
 
 func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
@@ -85,7 +125,7 @@ func (p *protocol) List(ctx context.Context, mod string) ([]string, error) {
 
 	go func() {
 		defer wg.Done()
-		_, goList, goErr = p.lister.List(ctx, mod)
+		_, goList, goErr = p.listUpstream(ctx, mod)
 	}()
 
 	wg.Wait()
@@ -144,7 +184,7 @@ func (p *protocol) Latest(ctx context.Context, mod string) (*storage.RevInfo, er
 	const op errors.Op = "protocol.Latest"
 	ctx, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
-	lr, _, err := p.lister.List(ctx, mod)
+	lr, _, err := p.listUpstream(ctx, mod)
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
@@ -152,6 +192,33 @@ func (p *protocol) Latest(ctx context.Context, mod string) (*storage.RevInfo, er
 	return lr, nil
 }
 
+// listUpstream lists mod upstream, unless the repository
+// of mod is cached as not found. The repositories that
+// upstream reports as not found are added to the cache.
+func (p *protocol) listUpstream(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "protocol.listUpstream"
+	if p.notFound == nil {
+		return p.lister.List(ctx, mod)
+	}
+	lggr := log.EntryFromContext(ctx)
+	// the cache is an optimization: if it fails,
+	// the module is listed as if it was not there.
+	cached, err := p.notFound.Has(ctx, mod, "")
+	if err != nil {
+		lggr.SystemErr(errors.E(op, err))
+	}
+	if cached {
+		return nil, nil, errors.E(op, errors.M(mod), errors.RepoNotFound(fmt.Errorf("cached as not found upstream")), errors.KindNotFound)
+	}
+	info, versions, err := p.lister.List(ctx, mod)
+	if err != nil && errors.IsRepoNotFoundErr(err) {
+		if cacheErr := p.notFound.Add(ctx, mod, ""); cacheErr != nil {
+			lggr.SystemErr(errors.E(op, cacheErr))
+		}
+	}
+	return info, versions, err
+}
+
 func (p *protocol) Info(ctx context.Context, mod, ver string) ([]byte, error) {
 	const op errors.Op = "protocol.Info"
 	ctx, span := observ.StartSpan(ctx, op.String())
@@ -201,10 +268,105 @@ func (p *protocol) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCl
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
+	if isVersion(ver) {
+		zip = p.validateZip(ctx, mod, ver, zip)
+	}
 
 	return zip, nil
 }
 
+// validateZip completes the validators of the zip of mod@ver, for
+// backends that return none or only some: an ETag of the hash in its
+// stored sum, and the time of its .info file. A validator that cannot
+// be read is left out.
+func (p *protocol) validateZip(ctx context.Context, mod, ver string, zip storage.SizeReadCloser) storage.SizeReadCloser {
+	var etag string
+	var modtime time.Time
+	if v, ok := zip.(storage.Validators); ok {
+		etag, modtime = v.ETag(), v.ModTime()
+	}
+	if etag != "" && !modtime.IsZero() {
+		return zip
+	}
+	if sg, ok := p.storage.(storage.SumGetter); ok && etag == "" {
+		if sum, err := sg.Sum(ctx, mod, ver); err == nil {
+			if zipSum, _ := storage.ParseSumLines(sum, mod, ver); zipSum != "" {
+				etag = `"` + zipSum + `"`
+			}
+		}
+	}
+	if modtime.IsZero() {
+		if info, err := p.storage.Info(ctx, mod, ver); err == nil {
+			modtime = infoTime(info)
+		}
+	}
+	return storage.NewValidatedSizer(zip, zip.Size(), etag, modtime)
+}
+
+func (p *protocol) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "protocol.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sumGetter, ok := p.storage.(storage.SumGetter)
+	if !ok {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), "storage does not record sums", errors.KindNotImplemented)
+	}
+	sum, err := sumGetter.Sum(ctx, mod, ver)
+	if errors.IsNotFoundErr(err) {
+		exists, existsErr := storage.WithChecker(p.storage).Exists(ctx, mod, ver)
+		if existsErr != nil {
+			return nil, errors.E(op, existsErr)
+		}
+		if exists {
+			sum, err = p.hashSum(ctx, mod, ver)
+		} else {
+			err = p.processDownload(ctx, mod, ver, func(newVer string) error {
+				sum, err = sumGetter.Sum(ctx, mod, newVer)
+				if errors.IsNotFoundErr(err) {
+					sum, err = p.hashSum(ctx, mod, newVer)
+				}
+				return err
+			})
+		}
+	}
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return sum, nil
+}
+
+// hashSum computes the sum of a stored version that has none, either
+// because it was saved before sums were recorded or because saving its
+// sum failed. Stashing the version again would not help. The sum is only
+// saved once the checksum database confirms it, since a version damaged
+// in storage would otherwise get a sum that hides the damage.
+func (p *protocol) hashSum(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "protocol.hashSum"
+	sum, err := checksum.HashVersion(ctx, p.storage, p.fs, p.tempDir, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	if p.verifier == nil {
+		return sum, nil
+	}
+	zipHash, modHash := storage.ParseSumLines(sum, mod, ver)
+	if err := p.verifier.Verify(ctx, mod, ver, zipHash, modHash); err != nil {
+		if errors.Is(err, errors.KindChecksumMismatch) {
+			return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+		}
+		// the sum is still served, as it
+		// would be without a verifier.
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+		return sum, nil
+	}
+	if ss, ok := p.storage.(storage.SumSaver); ok {
+		if err := ss.SaveSum(ctx, mod, ver, sum); err != nil {
+			log.EntryFromContext(ctx).SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+		}
+	}
+	return sum, nil
+}
+
 func (p *protocol) processDownload(ctx context.Context, mod, ver string, f func(newVer string) error) error {
 	const op errors.Op = "protocol.processDownload"
 	switch p.df.Match(mod) {
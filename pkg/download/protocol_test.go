@@ -1,10 +1,14 @@
 package download
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http/httptest"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -12,6 +16,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gomods/athens/pkg/checksum"
 	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
@@ -22,6 +27,8 @@ import (
 	"github.com/gomods/athens/pkg/storage/mem"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -45,8 +52,8 @@ func getDP(t *testing.T) Protocol {
 	if err != nil {
 		t.Fatal(err)
 	}
-	st := stash.New(mf, s, nop.New())
-	return New(&Opts{s, st, module.NewVCSLister(goBin, conf.GoBinaryEnvVars, fs), nil})
+	st := stash.New(mf, s, nop.New(), nil)
+	return New(&Opts{Storage: s, Stasher: st, Lister: module.NewVCSLister(goBin, conf.GoBinaryEnvVars, fs)})
 }
 
 type listTest struct {
@@ -281,8 +288,8 @@ func TestDownloadProtocol(t *testing.T) {
 		t.Fatal(err)
 	}
 	mp := &mockFetcher{}
-	st := stash.New(mp, s, nop.New())
-	dp := New(&Opts{s, st, nil, nil})
+	st := stash.New(mp, s, nop.New(), nil)
+	dp := New(&Opts{Storage: s, Stasher: st})
 	ctx := context.Background()
 
 	var eg errgroup.Group
@@ -321,6 +328,161 @@ func (m *mockFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Vers
 	}, nil
 }
 
+func TestDownloadProtocolSum(t *testing.T) {
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	ctx := context.Background()
+	// stored before sums were recorded
+	oldMod := testMod{"github.com/athens-artifacts/sum-old", "v1.0.0"}
+	oldZip := &bytes.Buffer{}
+	zw := zip.NewWriter(oldZip)
+	w, err := zw.Create(oldMod.mod + "@" + oldMod.ver + "/go.mod")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("module " + oldMod.mod + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	err = s.Save(ctx, oldMod.mod, oldMod.ver, []byte("module "+oldMod.mod+"\n"), bytes.NewReader(oldZip.Bytes()), []byte("info"))
+	require.NoError(t, err)
+	defer s.Delete(ctx, oldMod.mod, oldMod.ver)
+
+	mf := &sumFetcher{}
+	tmpFs := afero.NewMemMapFs()
+	dp := New(&Opts{Storage: s, Stasher: stash.New(mf, s, nop.New(), nil), Fs: tmpFs, TempDir: "/tmp"})
+
+	newMod := testMod{"github.com/athens-artifacts/sum-new", "v1.0.0"}
+	sum, err := dp.Sum(ctx, newMod.mod, newMod.ver)
+	require.NoError(t, err)
+	defer s.Delete(ctx, newMod.mod, newMod.ver)
+	require.Equal(t, newMod.mod+" v1.0.0 h1:zip\n"+newMod.mod+" v1.0.0/go.mod h1:mod\n", string(sum))
+
+	sum, err = dp.Sum(ctx, oldMod.mod, oldMod.ver)
+	require.NoError(t, err)
+	zipHash, modHash := storage.ParseSumLines(sum, oldMod.mod, oldMod.ver)
+	wantZip, err := checksum.HashZip(bytes.NewReader(oldZip.Bytes()), int64(oldZip.Len()))
+	require.NoError(t, err)
+	wantMod, err := checksum.HashGoMod([]byte("module " + oldMod.mod + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, wantZip, zipHash)
+	require.Equal(t, wantMod, modHash)
+	require.Equal(t, 1, mf.calls, "versions without a sum must not be fetched again")
+
+	_, err = s.(storage.SumGetter).Sum(ctx, oldMod.mod, oldMod.ver)
+	require.True(t, errors.IsNotFoundErr(err), "computed sums must not be saved without a checksum database")
+	files, err := afero.ReadDir(tmpFs, "/tmp")
+	require.NoError(t, err)
+	require.Empty(t, files, "temporary zip files must be removed")
+}
+
+// plainZips returns zips without validators,
+// like the backends that cannot provide them.
+type plainZips struct {
+	storage.Backend
+	storage.SumGetter
+}
+
+func (pz plainZips) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	zip, err := pz.Backend.Zip(ctx, mod, ver)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewSizer(zip, zip.Size()), nil
+}
+
+func TestDownloadProtocolZipValidators(t *testing.T) {
+	const mod, ver = "github.com/athens-artifacts/validators", "v1.0.0"
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	ctx := context.Background()
+	info := []byte(`{"Version":"v1.0.0","Time":"2019-04-01T10:20:30Z"}`)
+	require.NoError(t, s.Save(ctx, mod, ver, []byte("module "+mod), strings.NewReader("zip"), info))
+	require.NoError(t, s.(storage.SumSaver).SaveSum(ctx, mod, ver, storage.SumLines(mod, ver, "h1:zip=", "h1:mod=")))
+
+	dp := New(&Opts{Storage: plainZips{s, s.(storage.SumGetter)}, Stasher: stash.New(&sumFetcher{}, s, nop.New(), nil)})
+	zip, err := dp.Zip(ctx, mod, ver)
+	require.NoError(t, err)
+	defer zip.Close()
+	v, ok := zip.(storage.Validators)
+	require.True(t, ok, "zips must have validators")
+	require.Equal(t, `"h1:zip="`, v.ETag())
+	require.Equal(t, time.Date(2019, 4, 1, 10, 20, 30, 0, time.UTC), v.ModTime())
+	content, err := ioutil.ReadAll(zip)
+	require.NoError(t, err)
+	require.Equal(t, "zip", string(content))
+}
+
+func TestDownloadProtocolSumVerified(t *testing.T) {
+	const goMod = "module github.com/athens-artifacts/verified\n"
+	good := testMod{"github.com/athens-artifacts/verified", "v1.0.0"}
+	damaged := testMod{"github.com/athens-artifacts/verified", "v1.1.0"}
+	zips := map[string][]byte{}
+	for _, m := range []testMod{good, damaged} {
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		w, err := zw.Create(m.mod + "@" + m.ver + "/go.mod")
+		require.NoError(t, err)
+		_, err = w.Write([]byte(goMod))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		zips[m.ver] = buf.Bytes()
+	}
+
+	// the checksum database records the hashes of the zips as published.
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.athens.test")
+	require.NoError(t, err)
+	modHash, err := checksum.HashGoMod([]byte(goMod))
+	require.NoError(t, err)
+	gosum := func(path, vers string) ([]byte, error) {
+		z, ok := zips[vers]
+		if !ok {
+			return nil, fmt.Errorf("no such version")
+		}
+		zipHash, err := checksum.HashZip(bytes.NewReader(z), int64(len(z)))
+		if err != nil {
+			return nil, err
+		}
+		return storage.SumLines(path, vers, zipHash, modHash), nil
+	}
+	srv := httptest.NewServer(sumdb.NewServer(sumdb.NewTestServer(skey, gosum)))
+	defer srv.Close()
+	verifier, err := checksum.NewVerifier(vkey+" "+srv.URL, nil, nil, srv.Client())
+	require.NoError(t, err)
+
+	s, err := mem.NewStorage()
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, s.Save(ctx, good.mod, good.ver, []byte(goMod), bytes.NewReader(zips[good.ver]), []byte("info")))
+	// damaged in storage after it was saved
+	require.NoError(t, s.Save(ctx, damaged.mod, damaged.ver, []byte(goMod), bytes.NewReader(zips[good.ver]), []byte("info")))
+
+	dp := New(&Opts{Storage: s, Stasher: stash.New(&sumFetcher{}, s, nop.New(), nil), Fs: afero.NewMemMapFs(), TempDir: "/tmp", Verifier: verifier})
+	sum, err := dp.Sum(ctx, good.mod, good.ver)
+	require.NoError(t, err)
+	saved, err := s.(storage.SumGetter).Sum(ctx, good.mod, good.ver)
+	require.NoError(t, err, "verified sums must be saved")
+	require.Equal(t, sum, saved)
+
+	_, err = dp.Sum(ctx, damaged.mod, damaged.ver)
+	require.Equal(t, errors.KindChecksumMismatch, errors.Kind(err))
+	_, err = s.(storage.SumGetter).Sum(ctx, damaged.mod, damaged.ver)
+	require.True(t, errors.IsNotFoundErr(err), "the sums of damaged versions must not be saved")
+}
+
+type sumFetcher struct {
+	calls int
+}
+
+func (m *sumFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	m.calls++
+	return &storage.Version{
+		Mod:      []byte("mod"),
+		Info:     []byte("info"),
+		Zip:      ioutil.NopCloser(strings.NewReader("zip")),
+		Semver:   ver,
+		Sum:      "h1:zip",
+		GoModSum: "h1:mod",
+	}, nil
+}
+
 func TestDownloadProtocolWhenFetchFails(t *testing.T) {
 	s, err := mem.NewStorage()
 	if err != nil {
@@ -333,8 +495,8 @@ func TestDownloadProtocolWhenFetchFails(t *testing.T) {
 		t.Fatal(err)
 	}
 	mp := &notFoundFetcher{}
-	st := stash.New(mp, s, nop.New())
-	dp := New(&Opts{s, st, nil, nil})
+	st := stash.New(mp, s, nop.New(), nil)
+	dp := New(&Opts{Storage: s, Stasher: st})
 	ctx := context.Background()
 	_, err = dp.GoMod(ctx, fakeMod.mod, fakeMod.ver)
 	if err != nil {
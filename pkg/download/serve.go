@@ -0,0 +1,113 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// immutableCacheControl is the Cache-Control of the .info, .mod
+// and .zip files of a version, which never change once it is
+// published. The files of queries such as branch names change.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// privateCacheControl replaces immutableCacheControl in the responses
+// to requests with credentials, which shared caches must not store and
+// serve to anyone else.
+const privateCacheControl = "private, max-age=31536000, immutable"
+
+// contentETag returns a strong ETag for b.
+func contentETag(b []byte) string {
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// infoTime returns the time in a .info file,
+// or the zero time if it has none.
+func infoTime(info []byte) time.Time {
+	var revInfo storage.RevInfo
+	if err := json.Unmarshal(info, &revInfo); err != nil {
+		return time.Time{}
+	}
+	return revInfo.Time
+}
+
+// serveContent serves content, a file of version ver which is size bytes
+// long, along with its validators. It answers conditional requests with
+// a 304 and, if content can seek, range requests. An empty etag or a zero
+// modtime is not sent. The responses to requests with credentials can
+// only be cached by clients.
+func serveContent(w http.ResponseWriter, r *http.Request, ver, contentType, etag string, modtime time.Time, content io.Reader, size int64) error {
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	if isVersion(ver) {
+		if r.Header.Get("Authorization") != "" {
+			h.Set("Cache-Control", privateCacheControl)
+		} else {
+			h.Set("Cache-Control", immutableCacheControl)
+		}
+	}
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if rs, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", modtime, rs)
+		return nil
+	}
+
+	if !modtime.IsZero() {
+		h.Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, modtime) {
+		h.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	// without seeking, ranges cannot be served,
+	// so the whole content is sent instead.
+	h.Set("Accept-Ranges", "none")
+	if size > 0 {
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	_, err := io.Copy(w, content)
+	return err
+}
+
+// notModified reports whether the conditional headers of r
+// match etag and modtime, as in RFC 7232 section 6.
+func notModified(r *http.Request, etag string, modtime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || modtime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modtime.Truncate(time.Second).After(t)
+}
@@ -0,0 +1,162 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	serveMod  = "github.com/gomods/athens"
+	serveVer  = "v0.4.0"
+	serveZip  = "0123456789"
+	serveInfo = `{"Version":"v0.4.0","Time":"2019-04-01T10:20:30Z"}`
+	zipHash   = "h1:zipHashzipHashzipHashzipHashzipHashzipHash="
+)
+
+var serveTime = time.Date(2019, 4, 1, 10, 20, 30, 0, time.UTC)
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+// serveProtocol serves a single version, with a zip
+// that can seek if seekable is true.
+type serveProtocol struct {
+	Protocol
+	seekable bool
+}
+
+func (sp *serveProtocol) Info(ctx context.Context, mod, ver string) ([]byte, error) {
+	return []byte(serveInfo), nil
+}
+
+func (sp *serveProtocol) GoMod(ctx context.Context, mod, ver string) ([]byte, error) {
+	return []byte("module " + mod), nil
+}
+
+func (sp *serveProtocol) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	return storage.SumLines(mod, ver, zipHash, "h1:modHash="), nil
+}
+
+func (sp *serveProtocol) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	if sp.seekable {
+		return storage.NewValidatedSizer(nopCloser{bytes.NewReader([]byte(serveZip))}, int64(len(serveZip)), `"`+zipHash+`"`, serveTime), nil
+	}
+	return storage.NewValidatedSizer(ioutil.NopCloser(bytes.NewReader([]byte(serveZip))), int64(len(serveZip)), `"`+zipHash+`"`, serveTime), nil
+}
+
+func TestServeContent(t *testing.T) {
+	zipPath := "/" + serveMod + "/@v/" + serveVer + ".zip"
+	modPath := "/" + serveMod + "/@v/" + serveVer + ".mod"
+	infoPath := "/" + serveMod + "/@v/" + serveVer + ".info"
+	tests := []struct {
+		name     string
+		seekable bool
+		path     string
+		header   map[string]string
+		code     int
+		body     string
+		etag     string
+		// mutable is true for queries, whose files are not cached.
+		mutable bool
+		// private is true for requests with credentials, whose
+		// responses must not be stored by shared caches.
+		private bool
+	}{
+		{name: "zip", path: zipPath, code: http.StatusOK, body: serveZip, etag: `"` + zipHash + `"`},
+		{name: "range", seekable: true, path: zipPath, header: map[string]string{"Range": "bytes=2-4"}, code: http.StatusPartialContent, body: "234"},
+		{name: "range without seeking", path: zipPath, header: map[string]string{"Range": "bytes=2-4"}, code: http.StatusOK, body: serveZip},
+		{name: "if-none-match", path: zipPath, header: map[string]string{"If-None-Match": `"other", "` + zipHash + `"`}, code: http.StatusNotModified},
+		{name: "if-none-match seekable", seekable: true, path: zipPath, header: map[string]string{"If-None-Match": `"` + zipHash + `"`}, code: http.StatusNotModified},
+		{name: "if-none-match mismatch", path: zipPath, header: map[string]string{"If-None-Match": `"other"`}, code: http.StatusOK, body: serveZip},
+		{name: "if-modified-since", path: zipPath, header: map[string]string{"If-Modified-Since": serveTime.Format(http.TimeFormat)}, code: http.StatusNotModified},
+		{name: "modified since", path: zipPath, header: map[string]string{"If-Modified-Since": serveTime.Add(-time.Hour).Format(http.TimeFormat)}, code: http.StatusOK, body: serveZip},
+		{name: "mod", path: modPath, code: http.StatusOK, body: "module " + serveMod, etag: contentETag([]byte("module " + serveMod))},
+		{name: "mod if-none-match", path: modPath, header: map[string]string{"If-None-Match": contentETag([]byte("module " + serveMod))}, code: http.StatusNotModified},
+		{name: "info", path: infoPath, code: http.StatusOK, body: serveInfo, etag: contentETag([]byte(serveInfo))},
+		{name: "branch info", path: "/" + serveMod + "/@v/master.info", code: http.StatusOK, body: serveInfo, etag: contentETag([]byte(serveInfo)), mutable: true},
+		{name: "branch mod", path: "/" + serveMod + "/@v/master.mod", code: http.StatusOK, body: "module " + serveMod, mutable: true},
+		{name: "incompatible", path: "/" + serveMod + "/@v/v2.0.0+incompatible.zip", code: http.StatusOK, body: serveZip},
+		{name: "credentials", path: zipPath, header: map[string]string{"Authorization": "Bearer token"}, code: http.StatusOK, body: serveZip, private: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := mux.NewRouter()
+			RegisterHandlers(r, &HandlerOpts{
+				Protocol:     &serveProtocol{seekable: tc.seekable},
+				Logger:       log.NoOpLogger(),
+				DownloadFile: &mode.DownloadFile{Mode: mode.Sync},
+			})
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.code, w.Code)
+			require.Equal(t, tc.body, w.Body.String())
+			if tc.etag != "" {
+				require.Equal(t, tc.etag, w.Header().Get("ETag"))
+			}
+			if tc.code != http.StatusNotModified {
+				require.Equal(t, serveTime.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+			}
+			if tc.mutable {
+				require.Empty(t, w.Header().Get("Cache-Control"))
+			} else if tc.private {
+				require.Equal(t, privateCacheControl, w.Header().Get("Cache-Control"))
+			} else if tc.code != http.StatusNotModified {
+				require.Equal(t, immutableCacheControl, w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+// zipURLer gives out URLs for serveVer only.
+type zipURLer struct{}
+
+func (zipURLer) ZipURL(ctx context.Context, mod, ver string, expiry time.Duration) (string, error) {
+	if ver != serveVer {
+		return "", errors.E("zipURLer.ZipURL", errors.KindNotFound)
+	}
+	return "https://storage.example.com/" + mod + "/" + ver + ".zip?expiry=" + expiry.String(), nil
+}
+
+func TestZipURL(t *testing.T) {
+	var downloads downloadRecorder
+	r := mux.NewRouter()
+	RegisterHandlers(r, &HandlerOpts{
+		Protocol:     &serveProtocol{},
+		Logger:       log.NoOpLogger(),
+		DownloadFile: &mode.DownloadFile{Mode: mode.Sync},
+		Downloads:    &downloads,
+		ZipURLs:      zipURLer{},
+		ZipURLExpiry: time.Minute,
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+serveMod+"/@v/"+serveVer+".zip", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "https://storage.example.com/"+serveMod+"/"+serveVer+".zip?expiry=1m0s", w.Header().Get("Location"))
+	require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	require.Equal(t, 1, downloads.zips, "redirected downloads must be counted")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+serveMod+"/@v/v0.5.0.zip", nil))
+	require.Equal(t, http.StatusOK, w.Code, "versions without a URL must be served by the proxy")
+	require.Equal(t, serveZip, w.Body.String())
+}
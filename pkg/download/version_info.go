@@ -1,6 +1,7 @@
 package download
 
 import (
+	"bytes"
 	"net/http"
 
 	"github.com/gomods/athens/pkg/download/mode"
@@ -36,9 +37,13 @@ func InfoHandler(dp Protocol, lggr log.Entry, df *mode.DownloadFile) http.Handle
 				return
 			}
 			w.WriteHeader(errors.Kind(err))
+			return
 		}
 
-		w.Write(info)
+		err = serveContent(w, r, ver, "application/json", contentETag(info), infoTime(info), bytes.NewReader(info), int64(len(info)))
+		if err != nil {
+			lggr.SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+		}
 	}
 	return http.HandlerFunc(f)
 }
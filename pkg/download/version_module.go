@@ -1,7 +1,9 @@
 package download
 
 import (
+	"bytes"
 	"net/http"
+	"time"
 
 	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
@@ -42,7 +44,15 @@ func ModuleHandler(dp Protocol, lggr log.Entry, df *mode.DownloadFile) http.Hand
 			return
 		}
 
-		w.Write(modBts)
+		// a .mod file was last modified when its version was published.
+		var modtime time.Time
+		if info, err := dp.Info(r.Context(), mod, ver); err == nil {
+			modtime = infoTime(info)
+		}
+		err = serveContent(w, r, ver, "text/plain; charset=utf-8", contentETag(modBts), modtime, bytes.NewReader(modBts), int64(len(modBts)))
+		if err != nil {
+			lggr.SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+		}
 	}
 	return http.HandlerFunc(f)
 }
@@ -0,0 +1,47 @@
+package download
+
+import (
+	"net/http"
+
+	"github.com/gomods/athens/pkg/download/mode"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+)
+
+// PathVersionSum URL.
+const PathVersionSum = "/{module:.+}/@v/{version}.sum"
+
+// SumHandler implements GET baseURL/module/@v/version.sum
+// by returning the go.sum lines recorded for the version.
+func SumHandler(dp Protocol, lggr log.Entry, df *mode.DownloadFile) http.Handler {
+	const op errors.Op = "download.SumHandler"
+	f := func(w http.ResponseWriter, r *http.Request) {
+		mod, ver, err := getModuleParams(r, op)
+		if err != nil {
+			lggr.SystemErr(err)
+			w.WriteHeader(errors.Kind(err))
+			return
+		}
+		sum, err := dp.Sum(r.Context(), mod, ver)
+		if err != nil {
+			severityLevel := errors.Expect(err, errors.KindNotFound, errors.KindRedirect, errors.KindNotImplemented)
+			lggr.SystemErr(errors.E(op, err, errors.M(mod), errors.V(ver), severityLevel))
+			if errors.Kind(err) == errors.KindRedirect {
+				url, err := getRedirectURL(df.URL(mod), r.URL.Path)
+				if err != nil {
+					lggr.SystemErr(err)
+					w.WriteHeader(errors.Kind(err))
+					return
+				}
+				http.Redirect(w, r, url, errors.KindRedirect)
+				return
+			}
+			w.WriteHeader(errors.Kind(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(sum)
+	}
+	return http.HandlerFunc(f)
+}
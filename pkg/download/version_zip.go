@@ -1,19 +1,25 @@
 package download
 
 import (
-	"io"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/storage"
 )
 
 // PathVersionZip URL.
 const PathVersionZip = "/{module:.+}/@v/{version}.zip"
 
 // ZipHandler implements GET baseURL/module/@v/version.zip
+//
+// Range requests are served if the storage backend returns
+// zips that can seek, and conditional requests if it returns
+// zips that implement storage.Validators, which the protocol does
+// for the zips of versions. See zipURLHandler for redirecting
+// zip downloads to storage instead.
 func ZipHandler(dp Protocol, lggr log.Entry, df *mode.DownloadFile) http.Handler {
 	const op errors.Op = "download.ZipHandler"
 	f := func(w http.ResponseWriter, r *http.Request) {
@@ -43,18 +49,45 @@ func ZipHandler(dp Protocol, lggr log.Entry, df *mode.DownloadFile) http.Handler
 		}
 		defer zip.Close()
 
-		w.Header().Set("Content-Type", "application/zip")
-		size := zip.Size()
-		if size > 0 {
-			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		// zips are only served conditionally if the
+		// backend got their validators along with them.
+		var etag string
+		var modtime time.Time
+		if v, ok := zip.(storage.Validators); ok {
+			etag, modtime = v.ETag(), v.ModTime()
 		}
-		if r.Method == http.MethodHead {
+		err = serveContent(w, r, ver, "application/zip", etag, modtime, zip, zip.Size())
+		if err != nil {
+			lggr.SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+		}
+	}
+	return http.HandlerFunc(f)
+}
+
+// zipURLHandler redirects zip downloads to the URLs given out by zu,
+// so that clients download zips from storage instead of through the
+// proxy. The versions that zu has no URL for, such as the ones that
+// are not in storage yet, are served by h.
+func zipURLHandler(zu storage.ZipURLer, expiry time.Duration, h http.Handler) http.Handler {
+	const op errors.Op = "download.zipURLHandler"
+	f := func(w http.ResponseWriter, r *http.Request) {
+		mod, ver, err := getModuleParams(r, op)
+		if err != nil {
+			h.ServeHTTP(w, r)
 			return
 		}
-		_, err = io.Copy(w, zip)
+		url, err := zu.ZipURL(r.Context(), mod, ver, expiry)
 		if err != nil {
-			lggr.SystemErr(errors.E(op, errors.M(mod), errors.V(ver), err))
+			if !errors.IsNotFoundErr(err) {
+				severityLevel := errors.Expect(err, errors.KindNotImplemented)
+				log.EntryFromContext(r.Context()).SystemErr(errors.E(op, err, errors.M(mod), errors.V(ver), severityLevel))
+			}
+			h.ServeHTTP(w, r)
+			return
 		}
+		// the URL expires, so the redirect must not be cached.
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, url, http.StatusFound)
 	}
 	return http.HandlerFunc(f)
 }
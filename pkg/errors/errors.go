@@ -18,6 +18,10 @@ const (
 	KindRateLimit      = http.StatusTooManyRequests
 	KindNotImplemented = http.StatusNotImplemented
 	KindRedirect       = http.StatusMovedPermanently
+	KindForbidden      = http.StatusForbidden
+	// KindChecksumMismatch is used when a module's
+	// contents do not match its recorded checksum.
+	KindChecksumMismatch = http.StatusUnprocessableEntity
 )
 
 // Error is an Athens system error.
@@ -96,3 +96,12 @@ func TestExpect(t *testing.T) {
 	severity = Expect(err, KindAlreadyExists, KindNotImplemented)
 	require.Equalf(t, severity, logrus.ErrorLevel, "expected an error level but got %v", severity)
 }
+
+func TestRepoNotFound(t *testing.T) {
+	err := errors.New("404 Not Found")
+	require.False(t, IsRepoNotFoundErr(err))
+
+	err = E("TestRepoNotFound", RepoNotFound(err), KindNotFound)
+	require.True(t, IsRepoNotFoundErr(err))
+	require.Equal(t, KindNotFound, Kind(err))
+}
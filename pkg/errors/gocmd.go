@@ -1,11 +1,24 @@
 package errors
 
 import (
+	"fmt"
 	"strings"
 )
 
+// repoNotFound is the hint that the Go command
+// prints when a repository does not exist.
+const repoNotFound = "remote: Repository not found"
+
 // IsRepoNotFoundErr returns true if the Go command line
 // hints at a repository not found.
 func IsRepoNotFoundErr(err error) bool {
-	return strings.Contains(err.Error(), "remote: Repository not found")
+	return strings.Contains(err.Error(), repoNotFound)
+}
+
+// RepoNotFound annotates err so that IsRepoNotFoundErr
+// returns true for it. This lets upstream listers that do
+// not run the Go command report a missing repository the
+// same way the Go command does.
+func RepoNotFound(err error) error {
+	return fmt.Errorf("%v: %s", err, repoNotFound)
 }
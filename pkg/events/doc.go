@@ -0,0 +1,10 @@
+// Package events notifies other systems, such as vulnerability
+// scanners and SBOM generators, when module versions are stashed
+// into or deleted from storage.
+//
+// Events are emitted to a Publisher, which queues them for each of
+// its sinks and delivers them in the background, so that emitting an
+// event never blocks a download. An event is dropped for a sink whose
+// queue is full. Sinks deliver events at least once: consumers can
+// use the ID of an event to ignore the ones they already handled.
+package events
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Type is the kind of change that an Event reports.
+type Type string
+
+const (
+	// Stashed is emitted when a module version is saved to storage.
+	Stashed Type = "stashed"
+	// Deleted is emitted when a module version is deleted from storage.
+	Deleted Type = "deleted"
+)
+
+// Event reports a change to a module version in storage.
+type Event struct {
+	ID      string    `json:"id"`
+	Type    Type      `json:"type"`
+	Module  string    `json:"module"`
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+	// Reason is why a version was deleted, if known.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Emitter receives the events of the components
+// that change storage. Emit must not block.
+type Emitter interface {
+	Emit(ctx context.Context, e *Event)
+}
+
+// Sink delivers events to another system.
+type Sink interface {
+	// Name identifies the sink in logs.
+	Name() string
+	// Send delivers e, and returns an error if it could not.
+	Send(ctx context.Context, e *Event) error
+}
+
+// newID returns a random event ID.
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// an ID is a convenience for consumers:
+		// the time is unique enough without one.
+		return time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(b)
+}
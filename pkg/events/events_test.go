@@ -0,0 +1,123 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// chanSink sends events to a channel, and
+// blocks until they are received.
+type chanSink chan *Event
+
+func (chanSink) Name() string { return "chan" }
+
+func (s chanSink) Send(ctx context.Context, e *Event) error {
+	s <- e
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink := make(chanSink)
+	p := NewPublisher(1, sink)
+
+	// with no one delivering, the queue fills up
+	// and the next events must be dropped.
+	p.Emit(ctx, &Event{Type: Stashed, Module: "mod", Version: "v1.0.0"})
+	done := make(chan struct{})
+	go func() {
+		p.Emit(ctx, &Event{Type: Stashed, Module: "mod", Version: "v1.1.0"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Emit must not block when the queue is full")
+	}
+
+	p.Start(ctx)
+	e := <-sink
+	require.Equal(t, "v1.0.0", e.Version)
+	require.NotEmpty(t, e.ID)
+	require.False(t, e.Time.IsZero())
+	select {
+	case e := <-sink:
+		t.Fatalf("expected %v to be dropped", e)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var statuses = []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK}
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, Sign("secret", body), r.Header.Get(SignatureHeader))
+		require.Equal(t, "stashed", r.Header.Get("X-Athens-Event"))
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, body)
+		w.WriteHeader(statuses[len(bodies)-1])
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(srv.URL, "secret", 2, srv.Client())
+	w.backoff = time.Millisecond
+	e := &Event{ID: "1", Type: Stashed, Module: "mod", Version: "v1.0.0"}
+	require.NoError(t, w.Send(context.Background(), e))
+	require.Len(t, bodies, 3, "failed requests must be retried")
+	var got Event
+	require.NoError(t, json.Unmarshal(bodies[2], &got))
+	require.Equal(t, *e, got)
+
+	bodies = nil
+	statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+	require.Error(t, w.Send(context.Background(), e))
+	require.Len(t, bodies, 3, "requests must not be retried more than the retries")
+
+	bodies = nil
+	statuses = []int{http.StatusBadRequest}
+	require.Error(t, w.Send(context.Background(), e))
+	require.Len(t, bodies, 1, "client errors must not be retried")
+}
+
+func TestFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	f, err := NewFile(fs, "/events.ndjson")
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, f.Send(ctx, &Event{ID: "1", Type: Stashed, Module: "mod", Version: "v1.0.0"}))
+	require.NoError(t, f.Close())
+
+	// the file is appended to, not truncated.
+	f, err = NewFile(fs, "/events.ndjson")
+	require.NoError(t, err)
+	require.NoError(t, f.Send(ctx, &Event{ID: "2", Type: Deleted, Module: "mod", Version: "v1.0.0", Reason: "corrupt"}))
+	require.NoError(t, f.Close())
+
+	r, err := fs.Open("/events.ndjson")
+	require.NoError(t, err)
+	defer r.Close()
+	var ids []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		ids = append(ids, e.ID)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []string{"1", "2"}, ids)
+}
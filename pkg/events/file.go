@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// File is a Sink that appends each event to a file as
+// a line of JSON, so that the file is newline delimited
+// JSON that can be tailed by other processes.
+type File struct {
+	mu sync.Mutex
+	f  afero.File
+}
+
+// NewFile returns a File that appends to the file at path,
+// which is created if it does not exist.
+func NewFile(fs afero.Fs, path string) (*File, error) {
+	const op errors.Op = "events.NewFile"
+	f, err := fs.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &File{f: f}, nil
+}
+
+// Name implements Sink.
+func (f *File) Name() string {
+	return "file"
+}
+
+// Send implements Sink.
+func (f *File) Send(ctx context.Context, e *Event) error {
+	const op errors.Op = "file.Send"
+	line, err := json.Marshal(e)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.f.Write(append(line, '\n')); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Close()
+}
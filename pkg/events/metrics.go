@@ -0,0 +1,35 @@
+package events
+
+import (
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+var (
+	mSent    = stats.Int64("athens/events/sent", "Number of events delivered to a sink", stats.UnitDimensionless)
+	mFailed  = stats.Int64("athens/events/failed", "Number of events that a sink failed to deliver", stats.UnitDimensionless)
+	mDropped = stats.Int64("athens/events/dropped", "Number of events dropped because a sink queue was full", stats.UnitDimensionless)
+)
+
+// Views are the stats views of the publisher,
+// to be registered with the stats exporter.
+var Views = []*view.View{
+	{
+		Name:        "athens/events/sent",
+		Description: mSent.Description(),
+		Measure:     mSent,
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "athens/events/failed",
+		Description: mFailed.Description(),
+		Measure:     mFailed,
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "athens/events/dropped",
+		Description: mDropped.Description(),
+		Measure:     mDropped,
+		Aggregation: view.Count(),
+	},
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/sirupsen/logrus"
+	"go.opencensus.io/stats"
+)
+
+// Publisher is an Emitter that delivers
+// events to sinks in the background.
+type Publisher struct {
+	queues []*queue
+}
+
+type queue struct {
+	sink   Sink
+	events chan *Event
+}
+
+// NewPublisher returns a Publisher that queues up to
+// queueSize events for each of sinks. Events are only
+// delivered once the Publisher is started.
+func NewPublisher(queueSize int, sinks ...Sink) *Publisher {
+	p := &Publisher{}
+	for _, s := range sinks {
+		p.queues = append(p.queues, &queue{sink: s, events: make(chan *Event, queueSize)})
+	}
+	return p
+}
+
+// Emit queues e for every sink, and drops it for
+// the sinks whose queue is full. It sets the ID
+// and the time of e if they are not set.
+func (p *Publisher) Emit(ctx context.Context, e *Event) {
+	const op errors.Op = "events.Emit"
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	for _, q := range p.queues {
+		select {
+		case q.events <- e:
+		default:
+			stats.Record(ctx, mDropped.M(1))
+			msg := fmt.Sprintf("%s queue is full, dropping %s event", q.sink.Name(), e.Type)
+			log.EntryFromContext(ctx).SystemErr(errors.E(op, errors.M(e.Module), errors.V(e.Version), msg, logrus.WarnLevel))
+		}
+	}
+}
+
+// Start delivers the queued events to each sink, one at a
+// time, until ctx is done. Errors are logged using the entry
+// in ctx.
+func (p *Publisher) Start(ctx context.Context) {
+	for _, q := range p.queues {
+		go q.deliver(ctx)
+	}
+}
+
+func (q *queue) deliver(ctx context.Context) {
+	const op errors.Op = "events.deliver"
+	lggr := log.EntryFromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-q.events:
+			if err := q.sink.Send(ctx, e); err != nil {
+				stats.Record(ctx, mFailed.M(1))
+				lggr.SystemErr(errors.E(op, errors.M(e.Module), errors.V(e.Version), fmt.Errorf("%s: %v", q.sink.Name(), err)))
+				continue
+			}
+			stats.Record(ctx, mSent.M(1))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+)
+
+// SignatureHeader is the header of webhook requests that carries
+// the HMAC of their body, if the webhook has a secret. See Sign.
+const SignatureHeader = "X-Athens-Signature"
+
+// Webhook is a Sink that posts each event as JSON to a URL.
+// Requests that fail with a network error, a 429 or a 5xx
+// status are retried with an exponential backoff.
+type Webhook struct {
+	url     string
+	secret  string
+	retries int
+	client  *http.Client
+	backoff time.Duration
+}
+
+// NewWebhook returns a Webhook that posts events to url, signed with
+// secret if it is not empty, and retried up to retries times.
+func NewWebhook(url, secret string, retries int, client *http.Client) *Webhook {
+	return &Webhook{url: url, secret: secret, retries: retries, client: client, backoff: time.Second}
+}
+
+// Sign returns the value of the SignatureHeader of a webhook
+// request whose body is body: the hex encoded HMAC-SHA256 of
+// body keyed with secret, prefixed with "sha256=".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Name implements Sink.
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Send implements Sink.
+func (w *Webhook) Send(ctx context.Context, e *Event) error {
+	const op errors.Op = "webhook.Send"
+	body, err := json.Marshal(e)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, e, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.retries {
+			return errors.E(op, err)
+		}
+		select {
+		case <-time.After(w.backoff << uint(attempt)):
+		case <-ctx.Done():
+			return errors.E(op, ctx.Err())
+		}
+	}
+}
+
+// post makes a single request, and reports
+// whether it should be retried if it failed.
+func (w *Webhook) post(ctx context.Context, e *Event, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Athens-Event", string(e.Type))
+	req.Header.Set("X-Athens-Delivery", e.ID)
+	if w.secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.secret, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%s responded with %s", w.url, resp.Status)
+}
@@ -0,0 +1,60 @@
+// Package filewatch reloads configuration files, such as
+// the filter file, when they change or on a signal.
+package filewatch
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/gomods/athens/pkg/log"
+)
+
+// Watch calls load whenever the file at path changes, which is checked
+// every interval, or whenever a value is received from signals, until
+// ctx is done. A zero interval disables the checks. loaded is the file
+// as it was last loaded, and may be nil.
+//
+// load must keep the current version of the file if the new one is
+// broken. Its errors are logged once per version of the file.
+func Watch(ctx context.Context, path string, loaded os.FileInfo, interval time.Duration, signals <-chan os.Signal, load func() error) {
+	lggr := log.EntryFromContext(ctx)
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	// seen is the last version of the file that was loaded,
+	// so that a broken file is reported only once.
+	var seenMod time.Time
+	var seenSize int64
+	if loaded != nil {
+		seenMod, seenSize = loaded.ModTime(), loaded.Size()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		case <-tick:
+			fi, err := os.Stat(path)
+			if err != nil {
+				if !seenMod.IsZero() {
+					lggr.Warnf("%s: %v, keeping the current version", path, err)
+				}
+				seenMod, seenSize = time.Time{}, 0
+				continue
+			}
+			if fi.ModTime().Equal(seenMod) && fi.Size() == seenSize {
+				continue
+			}
+			seenMod, seenSize = fi.ModTime(), fi.Size()
+		}
+		if err := load(); err != nil {
+			lggr.Errorf("reloading %s, keeping the current version: %v", path, err)
+			continue
+		}
+		lggr.Infof("reloaded %s", path)
+	}
+}
@@ -101,6 +101,23 @@ func RunTests(t *testing.T, indexer index.Indexer, clearIndex func() error) {
 			},
 			limit: 2000,
 		},
+		{
+			name: "delete",
+			desc: "a deleted module version must not be returned, and deleting it again must return a KindNotFound",
+			preTest: func(t *testing.T) ([]*index.Line, time.Time) {
+				lines := seed(t, indexer, 3)
+				err := indexer.Delete(context.Background(), lines[1].Path, lines[1].Version)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = indexer.Delete(context.Background(), lines[1].Path, lines[1].Version)
+				if !errors.Is(err, errors.KindNotFound) {
+					t.Fatalf("expected an error of kind NotFound but got %s", errors.KindText(err))
+				}
+				return []*index.Line{lines[0], lines[2]}, time.Time{}
+			},
+			limit: 2000,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
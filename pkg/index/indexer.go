@@ -23,4 +23,8 @@ type Indexer interface {
 	// Lines returns the module@version lines given the time and limit
 	// constraints
 	Lines(ctx context.Context, since time.Time, limit int) ([]*Line, error)
+
+	// Delete removes the module@version from the index backend.
+	// Implementer must return a KindNotFound error if it was never indexed.
+	Delete(ctx context.Context, mod, ver string) error
 }
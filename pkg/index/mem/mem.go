@@ -55,3 +55,16 @@ func (i *indexer) Lines(ctx context.Context, since time.Time, limit int) ([]*ind
 	}
 	return lines, nil
 }
+
+func (i *indexer) Delete(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "mem.Delete"
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for idx, l := range i.lines {
+		if l.Path == mod && l.Version == ver {
+			i.lines = append(i.lines[:idx], i.lines[idx+1:]...)
+			return nil
+		}
+	}
+	return errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+}
@@ -96,6 +96,22 @@ func (i *indexer) Lines(ctx context.Context, since time.Time, limit int) ([]*ind
 	return lines, nil
 }
 
+func (i *indexer) Delete(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "mysql.Delete"
+	res, err := i.db.ExecContext(ctx, `DELETE FROM indexes WHERE path = ? AND version = ?`, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if n == 0 {
+		return errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return nil
+}
+
 func getMySQLSource(cfg *config.MySQL) string {
 	c := mysql.NewConfig()
 	c.Net = cfg.Protocol
@@ -20,3 +20,7 @@ func (indexer) Index(ctx context.Context, mod, ver string) error {
 func (indexer) Lines(ctx context.Context, since time.Time, limit int) ([]*index.Line, error) {
 	return []*index.Line{}, nil
 }
+
+func (indexer) Delete(ctx context.Context, mod, ver string) error {
+	return nil
+}
@@ -95,6 +95,22 @@ func (i *indexer) Lines(ctx context.Context, since time.Time, limit int) ([]*ind
 	return lines, nil
 }
 
+func (i *indexer) Delete(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "postgres.Delete"
+	res, err := i.db.ExecContext(ctx, `DELETE FROM indexes WHERE path = $1 AND version = $2`, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if n == 0 {
+		return errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return nil
+}
+
 func getPostgresSource(cfg *config.Postgres) string {
 	args := []string{}
 	args = append(args, "host="+cfg.Host)
@@ -0,0 +1,72 @@
+// Package spool copies module zips to temporary files, so that they
+// can be hashed or read at random before being stored or served.
+package spool
+
+import (
+	"io"
+	"os"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// File is a spooled temporary file,
+// which is removed once it is closed.
+type File struct {
+	afero.File
+	fs afero.Fs
+	// Size is the number of bytes in the file.
+	Size int64
+}
+
+// New copies r into a temporary file in dir whose name starts with
+// prefix, and returns the file positioned at its start.
+func New(fs afero.Fs, dir, prefix string, r io.Reader) (*File, error) {
+	const op errors.Op = "spool.New"
+	file, err := afero.TempFile(fs, dir, prefix)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	f := &File{File: file, fs: fs}
+	f.Size, err = io.Copy(file, r)
+	if err != nil {
+		f.Close()
+		return nil, errors.E(op, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, errors.E(op, err)
+	}
+	return f, nil
+}
+
+// Reuse returns rc positioned at its start if it is already a File,
+// such as the zip of a version that a previous fetcher spooled, and
+// otherwise spools it with New and closes it. rc is closed if an
+// error is returned.
+func Reuse(fs afero.Fs, dir, prefix string, rc io.ReadCloser) (*File, error) {
+	const op errors.Op = "spool.Reuse"
+	if f, ok := rc.(*File); ok {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return nil, errors.E(op, err)
+		}
+		return f, nil
+	}
+	defer rc.Close()
+	f, err := New(fs, dir, prefix, rc)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return f, nil
+}
+
+// Close closes and removes f.
+func (f *File) Close() error {
+	f.File.Close()
+	err := f.fs.Remove(f.Name())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -3,16 +3,20 @@ package middleware
 import (
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/module"
 	"github.com/gomods/athens/pkg/paths"
 	"github.com/gorilla/mux"
 )
 
 // NewFilterMiddleware builds a middleware function that implements the
-// filters configured in the filter file.
+// filters configured in the filter file. Excluded modules are answered
+// with the status of their rule, 403 by default, and a plain text reason
+// that the go command shows to users.
 func NewFilterMiddleware(mf *module.Filter, upstreamEndpoint string) mux.MiddlewareFunc {
 	const op errors.Op = "actions.NewFilterMiddleware"
 	return func(h http.Handler) http.Handler {
@@ -27,11 +31,28 @@ func NewFilterMiddleware(mf *module.Filter, upstreamEndpoint string) mux.Middlew
 			if err != nil {
 				ver = ""
 			}
-			rule := mf.Rule(mod, ver)
-			switch rule {
+			m := mf.Match(mod, ver)
+			lggr := log.EntryFromContext(r.Context()).WithFields(map[string]interface{}{
+				"module":  mod,
+				"version": ver,
+				"rule":    m.Line,
+			})
+			switch m.Rule {
 			case module.Exclude:
 				// Exclude: ignore request for this module
-				w.WriteHeader(http.StatusForbidden)
+				status := m.Status
+				if status == 0 {
+					status = http.StatusForbidden
+				}
+				reason := m.Reason
+				if reason == "" {
+					reason = "excluded by the filter rule " + strconv.Quote(m.Line)
+				}
+				lggr.WithFields(map[string]interface{}{
+					"status": status,
+					"reason": reason,
+				}).Infof("filter excluded module")
+				block(w, status, modVer(mod, ver)+": "+reason)
 				return
 			case module.Include:
 				// Include: please handle this module in a usual way
@@ -40,6 +61,7 @@ func NewFilterMiddleware(mf *module.Filter, upstreamEndpoint string) mux.Middlew
 			case module.Direct:
 				// Direct: do not store modules locally, use upstream proxy
 				newURL := redirectToUpstreamURL(upstreamEndpoint, r.URL)
+				lggr.WithFields(map[string]interface{}{"location": newURL}).Debugf("filter redirected module upstream")
 				http.Redirect(w, r, newURL, http.StatusSeeOther)
 				return
 			}
@@ -49,6 +71,20 @@ func NewFilterMiddleware(mf *module.Filter, upstreamEndpoint string) mux.Middlew
 	}
 }
 
+// block answers a request for a module that is not
+// served with status and the reason as plain text.
+func block(w http.ResponseWriter, status int, reason string) {
+	http.Error(w, reason, status)
+}
+
+// modVer formats a module and a version, which may be empty.
+func modVer(mod, ver string) string {
+	if ver == "" {
+		return mod
+	}
+	return mod + "@" + ver
+}
+
 func redirectToUpstreamURL(upstreamEndpoint string, u *url.URL) string {
 	return strings.TrimSuffix(upstreamEndpoint, "/") + u.Path
 }
@@ -7,10 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	ht "github.com/gobuffalo/httptest"
 	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/validation"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -92,10 +94,10 @@ func Test_FilterMiddleware(t *testing.T) {
 	r.Equal(http.StatusOK, res.Code)
 }
 
-func hookFilterApp(hook string) *mux.Router {
+func hookFilterApp(hook string, cache *validation.Cache) *mux.Router {
 	h := func(w http.ResponseWriter, r *http.Request) {}
 	r := mux.NewRouter()
-	r.Use(NewValidationMiddleware(http.DefaultClient, hook))
+	r.Use(NewValidationMiddleware(http.DefaultClient, hook, cache))
 
 	r.HandleFunc(pathList, h)
 	r.HandleFunc(pathVersionInfo, h)
@@ -106,11 +108,13 @@ type hookMock struct {
 	invoked bool
 	params  validationParams
 	resCode int
+	message string
 }
 
 func (m *hookMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.invoked = true
 	w.WriteHeader(m.resCode)
+	w.Write([]byte(m.message))
 	decoder := json.NewDecoder(r.Body)
 	decoder.Decode(&m.params)
 }
@@ -124,12 +128,13 @@ type HookTestsSuite struct {
 
 func (suite *HookTestsSuite) SetupSuite() {
 	suite.server = ht.NewServer(&suite.mock)
-	suite.w = ht.New(hookFilterApp(suite.server.URL))
+	suite.w = ht.New(hookFilterApp(suite.server.URL, nil))
 }
 
 func (suite *HookTestsSuite) SetupTest() {
 	suite.mock.invoked = false
 	suite.mock.resCode = 0
+	suite.mock.message = ""
 }
 
 func (suite *HookTestsSuite) TearDownSuite() {
@@ -166,6 +171,19 @@ func (suite *HookTestsSuite) TestHookBlocks() {
 	res := suite.w.JSON("/github.com/athens-artifacts/happy-path/@v/v1.0.0.info").Get()
 	r.True(suite.mock.invoked)
 	r.Equal(http.StatusForbidden, res.Code)
+	r.Equal("github.com/athens-artifacts/happy-path@v1.0.0: rejected by the validator\n", res.Body.String())
+}
+
+func (suite *HookTestsSuite) TestHookBlocksWithReason() {
+	r := suite.Require()
+
+	// hit, the hook blocks and tells why
+	suite.mock.resCode = http.StatusForbidden
+	suite.mock.message = "license GPL-3.0 is not allowed"
+	res := suite.w.JSON("/github.com/athens-artifacts/happy-path/@v/v1.0.0.info").Get()
+	r.Equal(http.StatusForbidden, res.Code)
+	r.Equal("text/plain; charset=utf-8", res.Header().Get("Content-Type"))
+	r.Equal("github.com/athens-artifacts/happy-path@v1.0.0: license GPL-3.0 is not allowed\n", res.Body.String())
 }
 
 func (suite *HookTestsSuite) TestHookUnexpectedError() {
@@ -177,3 +195,61 @@ func (suite *HookTestsSuite) TestHookUnexpectedError() {
 	r.True(suite.mock.invoked)
 	r.Equal(http.StatusInternalServerError, res.Code)
 }
+
+func TestHookCache(t *testing.T) {
+	r := require.New(t)
+	calls := 0
+	srv := ht.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	w := ht.New(hookFilterApp(srv.URL, validation.NewCache(time.Minute)))
+
+	for i := 0; i < 2; i++ {
+		res := w.JSON("/github.com/athens-artifacts/happy-path/@v/v1.0.0.info").Get()
+		r.Equal(http.StatusForbidden, res.Code)
+	}
+	r.Equal(1, calls, "the verdict should be cached")
+
+	res := w.JSON("/github.com/athens-artifacts/happy-path/@v/v1.0.1.info").Get()
+	r.Equal(http.StatusForbidden, res.Code)
+	r.Equal(2, calls, "verdicts are cached per version")
+}
+
+func TestFilterMiddlewareBlocks(t *testing.T) {
+	r := require.New(t)
+	filter, err := ioutil.TempFile(os.TempDir(), "filter-")
+	r.NoError(err)
+	defer os.Remove(filter.Name())
+	_, err = filter.WriteString(`- github.com/a
+- github.com/b : 404 not mirrored here
+- github.com/c v1 : 410 v1 is retired, upgrade to v2
+`)
+	r.NoError(err)
+	r.NoError(filter.Close())
+	mf, err := module.NewFilter(filter.Name())
+	r.NoError(err)
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	router := mux.NewRouter()
+	router.Use(NewFilterMiddleware(mf, "https://proxy.golang.org"))
+	router.HandleFunc(pathList, h)
+	router.HandleFunc(pathVersionInfo, h)
+	w := ht.New(router)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/github.com/a/@v/list", http.StatusForbidden, "github.com/a: excluded by the filter rule \"- github.com/a\"\n"},
+		{"/github.com/b/@v/v1.0.0.info", http.StatusNotFound, "github.com/b@v1.0.0: not mirrored here\n"},
+		{"/github.com/c/@v/v1.2.0.info", http.StatusGone, "github.com/c@v1.2.0: v1 is retired, upgrade to v2\n"},
+		{"/github.com/c/@v/v2.0.0.info", http.StatusOK, ""},
+	}
+	for _, tc := range tests {
+		res := w.JSON(tc.path).Get()
+		r.Equal(tc.status, res.Code, tc.path)
+		r.Equal(tc.body, res.Body.String(), tc.path)
+	}
+}
@@ -6,16 +6,19 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/validation"
 	"github.com/gorilla/mux"
 )
 
 // NewValidationMiddleware builds a middleware function that performs validation checks by calling
-// an external webhook
-func NewValidationMiddleware(client *http.Client, validatorHook string) mux.MiddlewareFunc {
+// an external webhook. The verdicts of the webhook are kept in cache, which may be nil. Rejected
+// modules are answered with a 403 and the message of the webhook as a plain text reason.
+func NewValidationMiddleware(client *http.Client, validatorHook string, cache *validation.Cache) mux.MiddlewareFunc {
 	const op errors.Op = "actions.NewValidationMiddleware"
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -30,20 +33,34 @@ func NewValidationMiddleware(client *http.Client, validatorHook string) mux.Midd
 			// i.e. list requests path is like /{module:.+}/@v/list with no version parameter
 			version, _ := paths.GetVersion(r)
 			if version != "" {
-				response, err := validate(ctx, client, validatorHook, mod, version)
-				if err != nil {
-					entry := log.EntryFromContext(ctx)
-					entry.SystemErr(err)
-					w.WriteHeader(http.StatusInternalServerError)
-					return
+				verdict, ok := cache.Get(mod, version)
+				if !ok {
+					response, err := validate(ctx, client, validatorHook, mod, version)
+					if err != nil {
+						entry := log.EntryFromContext(ctx)
+						entry.SystemErr(err)
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					verdict = &validation.Verdict{Valid: response.Valid, Message: string(response.Message)}
+					cache.Set(mod, version, verdict)
 				}
 
-				maybeLogValidationReason(r.Context(), string(response.Message), mod, version)
-
-				if !response.Valid {
-					w.WriteHeader(http.StatusForbidden)
+				if !verdict.Valid {
+					reason := strings.TrimSpace(verdict.Message)
+					if reason == "" {
+						reason = "rejected by the validator"
+					}
+					log.EntryFromContext(ctx).WithFields(map[string]interface{}{
+						"module":  mod,
+						"version": version,
+						"status":  http.StatusForbidden,
+						"reason":  reason,
+					}).Infof("validator rejected module")
+					block(w, http.StatusForbidden, modVer(mod, version)+": "+reason)
 					return
 				}
+				maybeLogValidationReason(ctx, verdict.Message, mod, version)
 			}
 			h.ServeHTTP(w, r)
 		})
@@ -52,8 +69,11 @@ func NewValidationMiddleware(client *http.Client, validatorHook string) mux.Midd
 
 func maybeLogValidationReason(context context.Context, message string, mod string, version string) {
 	if len(message) > 0 {
-		entry := log.EntryFromContext(context)
-		entry.Warnf("error validating %s@%s %s", mod, version, message)
+		entry := log.EntryFromContext(context).WithFields(map[string]interface{}{
+			"module":  mod,
+			"version": version,
+		})
+		entry.Warnf("validator accepted module with a message: %s", message)
 	}
 }
 
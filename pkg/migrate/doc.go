@@ -0,0 +1,4 @@
+// Package migrate copies every module version from one storage
+// backend to another, checkpointing its progress so that an
+// interrupted migration can be resumed.
+package migrate
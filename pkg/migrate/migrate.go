@@ -0,0 +1,311 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+const defaultPageSize = 100
+
+// Opts specifies the options of a migration.
+type Opts struct {
+	// Source is the backend to copy from. It
+	// must implement storage.Cataloger.
+	Source storage.Backend
+	// Dest is the backend to copy to.
+	Dest storage.Backend
+	// Checkpoint, if not empty, is the file in Fs where the
+	// catalog token of the next page to copy, and the versions
+	// that failed to copy so far, are recorded after every page.
+	// A migration retries the failed versions in Checkpoint and
+	// resumes from its token, and removes it once it is complete.
+	Checkpoint string
+	Fs         afero.Fs
+	// PageSize is the number of versions to read from the
+	// source Cataloger at once.
+	PageSize int
+}
+
+// Failure is a module version that could not be copied.
+type Failure struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Error   string `json:"error"`
+}
+
+// Result is the outcome of a migration.
+type Result struct {
+	Copied  int       `json:"copied"`
+	Skipped int       `json:"skipped"`
+	Failed  []Failure `json:"failed"`
+}
+
+// Run copies every module version of opts.Source that does not
+// exist in opts.Dest yet. Versions that fail to copy are reported
+// in the Result and do not stop the migration. A resumed migration
+// retries the versions that failed before its checkpoint, and running
+// it again without a checkpoint retries them all, as the versions that
+// were copied are skipped.
+func Run(ctx context.Context, opts *Opts) (*Result, error) {
+	const op errors.Op = "migrate.Run"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	lggr := log.EntryFromContext(ctx)
+	cataloger, ok := opts.Source.(storage.Cataloger)
+	if !ok {
+		return nil, errors.E(op, "source storage does not implement a catalog", errors.KindNotImplemented)
+	}
+	fs := opts.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	token, failed, err := readCheckpoint(fs, opts.Checkpoint)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if token != "" {
+		lggr.Infof("resuming migration from checkpoint %q, retrying %d failed versions", token, len(failed))
+	}
+
+	m := &migration{opts: opts, checker: storage.WithChecker(opts.Dest), res: &Result{Failed: []Failure{}}}
+	res := m.res
+	for _, p := range failed {
+		if err := m.copy(ctx, p); err != nil {
+			return res, errors.E(op, err)
+		}
+	}
+	for {
+		page, next, err := cataloger.Catalog(ctx, token, pageSize)
+		if err != nil {
+			return res, errors.E(op, err)
+		}
+		for _, p := range page {
+			if err := m.copy(ctx, p); err != nil {
+				return res, errors.E(op, err)
+			}
+		}
+		if next == "" {
+			break
+		}
+		token = next
+		if err := writeCheckpoint(fs, opts.Checkpoint, token, res.Failed); err != nil {
+			return res, errors.E(op, err)
+		}
+		lggr.Infof("migration checkpoint: %d copied, %d skipped, %d failed", res.Copied, res.Skipped, len(res.Failed))
+	}
+	if opts.Checkpoint != "" {
+		if err := fs.Remove(opts.Checkpoint); err != nil && !os.IsNotExist(err) {
+			return res, errors.E(op, err)
+		}
+	}
+	return res, nil
+}
+
+// migration is the state of a running migration.
+type migration struct {
+	opts    *Opts
+	checker storage.Checker
+	res     *Result
+}
+
+// copy copies p unless it already exists in the destination, and
+// records the outcome in m.res. It only returns an error if the
+// migration was interrupted.
+func (m *migration) copy(ctx context.Context, p paths.AllPathParams) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	exists, err := m.checker.Exists(ctx, p.Module, p.Version)
+	if err == nil && exists {
+		m.res.Skipped++
+		return nil
+	}
+	if err == nil {
+		err = CopyVersion(ctx, m.opts.Source, m.opts.Dest, p.Module, p.Version)
+	}
+	if err != nil {
+		log.EntryFromContext(ctx).SystemErr(err)
+		m.res.Failed = append(m.res.Failed, Failure{Module: p.Module, Version: p.Version, Error: err.Error()})
+		return nil
+	}
+	m.res.Copied++
+	return nil
+}
+
+// CopyVersion copies a version, along with its go.sum lines, from src
+// to dst and checks that the sizes of the files in dst match the ones
+// read from src. A version whose sizes do not match is deleted from dst.
+func CopyVersion(ctx context.Context, src, dst storage.Backend, mod, ver string) error {
+	const op errors.Op = "migrate.CopyVersion"
+	info, err := src.Info(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	goMod, err := src.GoMod(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	zip, err := src.Zip(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	cr := &countingReader{r: zip}
+	err = dst.Save(ctx, mod, ver, goMod, cr, info)
+	zip.Close()
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if err := copySum(ctx, src, dst, mod, ver); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if err := verify(ctx, dst, mod, ver, int64(len(info)), int64(len(goMod)), cr.n); err != nil {
+		if delErr := dst.Delete(ctx, mod, ver); delErr != nil {
+			log.EntryFromContext(ctx).SystemErr(errors.E(op, delErr, errors.M(mod), errors.V(ver)))
+		}
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
+
+// copySum copies the go.sum lines of a version, if
+// src has them and dst is able to store them.
+func copySum(ctx context.Context, src, dst storage.Backend, mod, ver string) error {
+	const op errors.Op = "migrate.copySum"
+	sg, ok := src.(storage.SumGetter)
+	if !ok {
+		return nil
+	}
+	ss, ok := dst.(storage.SumSaver)
+	if !ok {
+		return nil
+	}
+	sum, err := sg.Sum(ctx, mod, ver)
+	if errors.IsNotFoundErr(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if err := ss.SaveSum(ctx, mod, ver, sum); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// verify reads a version back from s and checks the sizes of its files.
+func verify(ctx context.Context, s storage.Backend, mod, ver string, infoSize, modSize, zipSize int64) error {
+	const op errors.Op = "migrate.verify"
+	info, err := s.Info(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if int64(len(info)) != infoSize {
+		return errors.E(op, fmt.Sprintf("copied .info is %d bytes, want %d", len(info), infoSize))
+	}
+	goMod, err := s.GoMod(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if int64(len(goMod)) != modSize {
+		return errors.E(op, fmt.Sprintf("copied .mod is %d bytes, want %d", len(goMod), modSize))
+	}
+	zip, err := s.Zip(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer zip.Close()
+	n, err := io.Copy(ioutil.Discard, zip)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if n != zipSize {
+		return errors.E(op, fmt.Sprintf("copied .zip is %d bytes, want %d", n, zipSize))
+	}
+	return nil
+}
+
+// readCheckpoint returns the catalog token and the failed versions
+// recorded in the checkpoint at path. The token is on the first line,
+// followed by a module@version line for every failed version.
+func readCheckpoint(fs afero.Fs, path string) (string, []paths.AllPathParams, error) {
+	const op errors.Op = "migrate.readCheckpoint"
+	if path == "" {
+		return "", nil, nil
+	}
+	b, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, errors.E(op, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	var failed []paths.AllPathParams
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		i := strings.LastIndex(line, "@")
+		if i <= 0 || i == len(line)-1 {
+			return "", nil, errors.E(op, fmt.Sprintf("checkpoint line %q is not a module@version", line))
+		}
+		failed = append(failed, paths.AllPathParams{Module: line[:i], Version: line[i+1:]})
+	}
+	return strings.TrimSpace(lines[0]), failed, nil
+}
+
+// writeCheckpoint replaces the checkpoint atomically, so that
+// an interruption never leaves a partial token behind.
+func writeCheckpoint(fs afero.Fs, path, token string, failed []Failure) error {
+	const op errors.Op = "migrate.writeCheckpoint"
+	if path == "" {
+		return nil
+	}
+	f, err := afero.TempFile(fs, filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return errors.E(op, err)
+	}
+	var b strings.Builder
+	b.WriteString(token + "\n")
+	for _, fl := range failed {
+		fmt.Fprintf(&b, "%s@%s\n", fl.Module, fl.Version)
+	}
+	_, err = f.WriteString(b.String())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fs.Rename(f.Name(), path)
+	}
+	if err != nil {
+		fs.Remove(f.Name())
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
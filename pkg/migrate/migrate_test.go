@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const testMod = "github.com/athens-artifacts/migrate"
+
+func newStorage(t *testing.T, versions int) storage.Backend {
+	t.Helper()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	for i := 0; i < versions; i++ {
+		ver := fmt.Sprintf("v1.%d.0", i)
+		err := s.Save(context.Background(), testMod, ver, []byte("module "+testMod), bytes.NewReader([]byte("zip of "+ver)), []byte(`{"Version":"`+ver+`"}`))
+		require.NoError(t, err)
+	}
+	return s
+}
+
+// interruptingStorage cancels a migration after a number of saves,
+// truncates the zips of the versions in truncate, and fails to save
+// the versions in fail.
+type interruptingStorage struct {
+	storage.Backend
+	cancel   func()
+	saves    int
+	truncate map[string]bool
+	fail     map[string]bool
+}
+
+func (s *interruptingStorage) Save(ctx context.Context, mod, ver string, goMod []byte, zip io.Reader, info []byte) error {
+	s.saves--
+	if s.saves == 0 {
+		s.cancel()
+	}
+	if s.fail[ver] {
+		return fmt.Errorf("could not save %s", ver)
+	}
+	if s.truncate[ver] {
+		b, err := ioutil.ReadAll(zip)
+		if err != nil {
+			return err
+		}
+		zip = bytes.NewReader(b[:len(b)/2])
+	}
+	return s.Backend.Save(ctx, mod, ver, goMod, zip, info)
+}
+
+func TestRunResumes(t *testing.T) {
+	src := newStorage(t, 5)
+	dst := newStorage(t, 0)
+	tmpFs := afero.NewMemMapFs()
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &Opts{
+		Source:     src,
+		Dest:       &interruptingStorage{Backend: dst, cancel: cancel, saves: 3},
+		Checkpoint: "/migrate.checkpoint",
+		Fs:         tmpFs,
+		PageSize:   2,
+	}
+	res, err := Run(ctx, opts)
+	require.Error(t, err, "the migration must stop when interrupted")
+	require.Equal(t, 3, res.Copied)
+	token, err := afero.ReadFile(tmpFs, opts.Checkpoint)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	opts.Dest = dst
+	res, err = Run(context.Background(), opts)
+	require.NoError(t, err)
+	require.Empty(t, res.Failed)
+	require.Equal(t, 2, res.Copied)
+	require.Equal(t, 1, res.Skipped, "the version copied on the interrupted page must be skipped")
+	exists, err := afero.Exists(tmpFs, opts.Checkpoint)
+	require.NoError(t, err)
+	require.False(t, exists, "the checkpoint must be removed once the migration is complete")
+
+	versions, err := dst.List(context.Background(), testMod)
+	require.NoError(t, err)
+	require.Len(t, versions, 5)
+	zip, err := dst.Zip(context.Background(), testMod, "v1.4.0")
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(zip)
+	zip.Close()
+	require.NoError(t, err)
+	require.Equal(t, "zip of v1.4.0", string(b))
+}
+
+func TestRunRetriesFailures(t *testing.T) {
+	src := newStorage(t, 5)
+	dst := newStorage(t, 0)
+	tmpFs := afero.NewMemMapFs()
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &Opts{
+		Source:     src,
+		Dest:       &interruptingStorage{Backend: dst, cancel: cancel, saves: 3, fail: map[string]bool{"v1.0.0": true}},
+		Checkpoint: "/migrate.checkpoint",
+		Fs:         tmpFs,
+		PageSize:   2,
+	}
+	res, err := Run(ctx, opts)
+	require.Error(t, err, "the migration must stop when interrupted")
+	require.Equal(t, 2, res.Copied)
+	require.Len(t, res.Failed, 1)
+	checkpoint, err := afero.ReadFile(tmpFs, opts.Checkpoint)
+	require.NoError(t, err)
+	require.Contains(t, string(checkpoint), testMod+"@v1.0.0", "the checkpoint must record the failed version")
+
+	opts.Dest = dst
+	res, err = Run(context.Background(), opts)
+	require.NoError(t, err)
+	require.Empty(t, res.Failed)
+	require.Equal(t, 3, res.Copied, "the version that failed before the checkpoint must be retried")
+	require.Equal(t, 1, res.Skipped)
+
+	versions, err := dst.List(context.Background(), testMod)
+	require.NoError(t, err)
+	require.Len(t, versions, 5)
+}
+
+func TestRunVerifiesSizes(t *testing.T) {
+	src := newStorage(t, 3)
+	dst := newStorage(t, 0)
+	res, err := Run(context.Background(), &Opts{
+		Source: src,
+		Dest:   &interruptingStorage{Backend: dst, truncate: map[string]bool{"v1.1.0": true}},
+		Fs:     afero.NewMemMapFs(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Copied)
+	require.Len(t, res.Failed, 1)
+	require.Equal(t, "v1.1.0", res.Failed[0].Version)
+	require.Contains(t, res.Failed[0].Error, "copied .zip is")
+	exists, err := storage.WithChecker(dst).Exists(context.Background(), testMod, "v1.1.0")
+	require.NoError(t, err)
+	require.False(t, exists, "a version whose sizes do not match must be deleted")
+}
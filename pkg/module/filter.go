@@ -2,11 +2,21 @@ package module
 
 import (
 	"bufio"
+	"context"
+	"fmt"
+	"net/http"
 	"os"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/filewatch"
+	"github.com/gomods/athens/pkg/paths"
+	"golang.org/x/mod/semver"
 )
 
 var (
@@ -14,14 +24,39 @@ var (
 	versionSeparator = "."
 )
 
-// Filter is a filter of modules
+// Filter is a filter of modules. It is safe for concurrent use:
+// its rules are replaced atomically when they are reloaded.
 type Filter struct {
-	root     ruleNode
 	filePath string
+
+	// mu serializes the writers of state.
+	mu    sync.Mutex
+	state atomic.Value // *filterState
+}
+
+// filterState is a set of rules. It is never
+// modified once it is stored in a Filter.
+type filterState struct {
+	root ruleNode
+	// globs are the rules whose paths are
+	// glob patterns, in the order they were added.
+	globs    []globRule
+	rules    []string
+	loadedAt time.Time
+	// file is the filter file as it was loaded.
+	file os.FileInfo
+}
+
+// globRule is a rule whose path is a glob pattern.
+type globRule struct {
+	pattern    string
+	depth      int
+	rule       FilterRule
+	qualifiers []string
+	ruleInfo
 }
 
 // NewFilter creates new filter based on rules defined in a configuration file
-// WARNING: this is not concurrently safe
 // Configuration consists of two operations: + for include and - for exclude
 // e.g.
 //    - github.com/a
@@ -31,6 +66,17 @@ type Filter struct {
 //   -
 //   + github.com/a
 // will exclude all items from communication except github.com/a
+//
+// Paths may be glob patterns, as in GOPRIVATE, such as github.com/*/internal-*,
+// and rules may be restricted to versions that match one of a comma separated
+// list of constraints, such as v1.2,~v1.3.0 or >=v1.0.0 <v2.0.0, see matches.
+// When several rules apply to a module, the one with the longest path wins,
+// a plain path wins over a glob pattern of the same length, and the last
+// of the glob patterns of the same length wins.
+//
+// Exclude rules may end with a colon followed by the status of the requests
+// they block, 403, 404 or 410, and a reason sent back to clients, e.g.
+//   - golang.org/x/crypto <0.1.0 : 410 upgrade to v0.1.0 or later
 func NewFilter(filterFilePath string) (*Filter, error) {
 	// Do not return an error if the file path is empty
 	// Do not attempt to parse it as well.
@@ -44,17 +90,96 @@ func NewFilter(filterFilePath string) (*Filter, error) {
 
 // AddRule adds rule for specified path
 func (f *Filter) AddRule(path string, qualifiers []string, rule FilterRule) {
-	f.ensurePath(path)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st := f.load().clone()
+	line := formatRule(path, qualifiers, rule)
+	st.addRule(path, qualifiers, rule, ruleInfo{line: line})
+	st.rules = append(st.rules, line)
+	f.state.Store(st)
+}
+
+// Rule returns the filter rule to be applied to the given path
+func (f *Filter) Rule(path, version string) FilterRule {
+	return f.Match(path, version).Rule
+}
+
+// Match returns the filter rule to be applied to the given
+// path, along with how it was written and why it applies.
+func (f *Filter) Match(path, version string) Match {
+	segs := getPathSegments(path)
+	m := f.load().getAssociatedRule(version, segs...)
+	if m.Rule == Default {
+		m.Rule = Include
+	}
+
+	return m
+}
+
+// Rules returns the rules of the filter, in the
+// syntax of the filter file, and when they were loaded.
+func (f *Filter) Rules() ([]string, time.Time) {
+	st := f.load()
+	return append([]string(nil), st.rules...), st.loadedAt
+}
+
+// Reload reads the filter file again. If the file cannot be read
+// or parsed, the filter keeps its current rules and an error is returned.
+func (f *Filter) Reload() error {
+	const op errors.Op = "module.Reload"
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	st, err := parseFilter(f.filePath)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	f.state.Store(st)
+	return nil
+}
+
+// Watch reloads the filter whenever its file changes, which is checked
+// every interval, or whenever a value is received from reload, until ctx
+// is done. A zero interval disables the checks. Failures to reload are
+// logged, and the previous rules are kept until the file is fixed.
+func (f *Filter) Watch(ctx context.Context, interval time.Duration, reload <-chan os.Signal) {
+	filewatch.Watch(ctx, f.filePath, f.load().file, interval, reload, f.Reload)
+}
+
+func (f *Filter) load() *filterState {
+	return f.state.Load().(*filterState)
+}
+
+func (s *filterState) clone() *filterState {
+	c := *s
+	c.root = s.root.clone()
+	c.globs = append([]globRule(nil), s.globs...)
+	c.rules = append([]string(nil), s.rules...)
+	return &c
+}
+
+func (s *filterState) addRule(path string, qualifiers []string, rule FilterRule, info ruleInfo) {
+	if isGlob(path) {
+		s.globs = append(s.globs, globRule{
+			pattern:    strings.Trim(strings.TrimSpace(path), pathSeparator),
+			depth:      len(getPathSegments(path)),
+			rule:       rule,
+			qualifiers: qualifiers,
+			ruleInfo:   info,
+		})
+		return
+	}
+	s.ensurePath(path)
 
 	segments := getPathSegments(path)
 
 	if len(segments) == 0 {
-		f.root.rule = rule
+		s.root.rule = rule
+		s.root.ruleInfo = info
 		return
 	}
 
 	// look for latest node in a path
-	latest := f.root
+	latest := s.root
 	for _, p := range segments[:len(segments)-1] {
 		latest = latest.next[p]
 	}
@@ -64,22 +189,12 @@ func (f *Filter) AddRule(path string, qualifiers []string, rule FilterRule) {
 	rn := latest.next[last]
 	rn.rule = rule
 	rn.qualifiers = qualifiers
+	rn.ruleInfo = info
 	latest.next[last] = rn
 }
 
-// Rule returns the filter rule to be applied to the given path
-func (f *Filter) Rule(path, version string) FilterRule {
-	segs := getPathSegments(path)
-	rule := f.getAssociatedRule(version, segs...)
-	if rule == Default {
-		rule = Include
-	}
-
-	return rule
-}
-
-func (f *Filter) ensurePath(path string) {
-	latest := f.root.next
+func (s *filterState) ensurePath(path string) {
+	latest := s.root.next
 	pathSegments := getPathSegments(path)
 
 	for _, p := range pathSegments {
@@ -90,56 +205,100 @@ func (f *Filter) ensurePath(path string) {
 	}
 }
 
-func (f *Filter) getAssociatedRule(version string, path ...string) FilterRule {
+func (s *filterState) getAssociatedRule(version string, path ...string) Match {
 	if len(path) == 0 {
-		return f.root.rule
+		return s.root.match(s.root.rule)
 	}
 
-	rules := make([]FilterRule, 0, len(path))
-	rn := f.root
-	for _, p := range path {
+	m, depth := s.trieRule(version, path)
+	literal := m.Rule != Default
+	if len(s.globs) > 0 {
+		mod := strings.Join(path, pathSeparator)
+		for _, g := range s.globs {
+			if g.depth < depth || (g.depth == depth && literal) {
+				continue
+			}
+			if !paths.MatchesPattern(g.pattern, mod) || !applies(g.qualifiers, g.rule, version, true) {
+				continue
+			}
+			m, depth, literal = g.match(g.rule), g.depth, false
+		}
+	}
+
+	if m.Rule == Default {
+		return s.root.match(s.root.rule)
+	}
+	return m
+}
+
+// trieRule returns the most specific rule of the plain paths that
+// applies to version of the module at path, and the number of
+// segments of its path, or Default if there is none.
+func (s *filterState) trieRule(version string, path []string) (Match, int) {
+	var m Match
+	depth := 0
+	rn := s.root
+	for i, p := range path {
 		if _, ok := rn.next[p]; !ok {
 			break
 		}
 		rn = rn.next[p]
-		// default to true if no version filter, false otherwise
-		match := len(rn.qualifiers) == 0
-		for _, q := range rn.qualifiers {
-			if matches(version, q) {
-				match = true
-				break
-			}
-		}
-		if match || version == "" {
-			rules = append(rules, rn.rule)
+		if rn.rule != Default && applies(rn.qualifiers, rn.rule, version, false) {
+			m, depth = rn.match(rn.rule), i+1
 		}
 	}
+	return m, depth
+}
 
-	if len(rules) == 0 {
-		return f.root.rule
+// applies reports whether a rule restricted to the versions matching
+// one of qualifiers applies to version. Requests without a version,
+// such as lists, are not excluded by the rules that only exclude some
+// versions of a module, unless the rule is written in the original
+// syntax, a plain path with legacy qualifiers, which always applied
+// to them.
+func applies(qualifiers []string, rule FilterRule, version string, glob bool) bool {
+	if len(qualifiers) == 0 {
+		return true
 	}
-
-	for i := len(rules) - 1; i >= 0; i-- {
-		if rules[i] != Default {
-			return rules[i]
+	if version == "" {
+		return rule != Exclude || (!glob && allLegacy(qualifiers))
+	}
+	for _, q := range qualifiers {
+		if matches(version, q) {
+			return true
 		}
 	}
-
-	return f.root.rule
+	return false
 }
 
 func initFromConfig(filePath string) (*Filter, error) {
-	const op errors.Op = "module.initFromConfig"
-	lines, err := getConfigLines(filePath)
+	st, err := parseFilter(filePath)
 	if err != nil {
 		return nil, err
 	}
-
-	rn := newRule(Default)
 	f := &Filter{
 		filePath: filePath,
 	}
-	f.root = rn
+	f.state.Store(st)
+	return f, nil
+}
+
+func parseFilter(filePath string) (*filterState, error) {
+	const op errors.Op = "module.parseFilter"
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	lines, err := getConfigLines(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &filterState{
+		root:     newRule(Default),
+		loadedAt: time.Now(),
+		file:     fi,
+	}
 
 	for idx, line := range lines {
 
@@ -151,12 +310,20 @@ func initFromConfig(filePath string) (*Filter, error) {
 			continue
 		}
 
-		split := strings.Split(line, " ")
-		if len(split) > 3 {
+		info := ruleInfo{line: line}
+		if i := strings.Index(line, ":"); i >= 0 {
+			info.status, info.reason, err = parseBlock(line[i+1:])
+			if err != nil {
+				return nil, errors.E(op, "Invalid status found in filter file at the line "+strconv.Itoa(idx+1)+": "+err.Error())
+			}
+			line = line[:i]
+		}
+		split := strings.Fields(line)
+		if len(split) == 0 {
 			return nil, errors.E(op, "Invalid configuration found in filter file at the line "+strconv.Itoa(idx+1))
 		}
 
-		ruleSign := strings.TrimSpace(split[0])
+		ruleSign := split[0]
 		rule := Default
 		switch ruleSign {
 		case "+":
@@ -168,36 +335,212 @@ func initFromConfig(filePath string) (*Filter, error) {
 		default:
 			return nil, errors.E(op, "Invalid configuration found in filter file at the line "+strconv.Itoa(idx+1))
 		}
+		if rule != Exclude && info != (ruleInfo{line: info.line}) {
+			return nil, errors.E(op, "Only exclude rules can have a status and a reason, found at the line "+strconv.Itoa(idx+1))
+		}
+		st.rules = append(st.rules, info.line)
 		// is root config
 		if len(split) == 1 {
-			f.AddRule("", nil, rule)
+			st.addRule("", nil, rule, info)
 			continue
 		}
 		var qual []string
-		if len(split) == 3 {
-			qual = strings.Split(split[2], ",")
-			for i := range qual {
-				qual[i] = strings.TrimRight(qual[i], "*")
-				if qual[i][len(qual[i])-1] != '.' && strings.Count(qual[i], ".") < 2 {
-					qual[i] += "."
-				}
+		if len(split) > 2 {
+			qual, err = parseQualifiers(strings.Join(split[2:], " "))
+			if err != nil {
+				return nil, errors.E(op, "Invalid versions found in filter file at the line "+strconv.Itoa(idx+1)+": "+err.Error())
 			}
 		}
 
-		path := strings.TrimSpace(split[1])
-		f.AddRule(path, qual, rule)
+		if _, err := path.Match(split[1], ""); err != nil {
+			return nil, errors.E(op, "Invalid path pattern found in filter file at the line "+strconv.Itoa(idx+1))
+		}
+		st.addRule(split[1], qual, rule, info)
 	}
-	return f, nil
+	return st, nil
+}
+
+// formatRule formats a rule in the syntax of the filter file.
+func formatRule(path string, qualifiers []string, rule FilterRule) string {
+	var sign string
+	switch rule {
+	case Include:
+		sign = "+"
+	case Exclude:
+		sign = "-"
+	case Direct:
+		sign = "D"
+	}
+	if path == "" {
+		return sign
+	}
+	if len(qualifiers) == 0 {
+		return sign + " " + path
+	}
+	return sign + " " + path + " " + strings.Join(qualifiers, ",")
+}
+
+// parseBlock parses the end of an exclude rule, after the colon: an
+// optional status, which must be 403, 404 or 410, and a reason. The
+// first word is the status if it is a number of three digits.
+func parseBlock(s string) (int, string, error) {
+	s = strings.TrimSpace(s)
+	word := s
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		word = s[:i]
+	}
+	status, err := strconv.Atoi(word)
+	if err != nil || len(word) != 3 {
+		// no status, only a reason
+		return 0, s, nil
+	}
+	switch status {
+	case http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return status, strings.TrimSpace(s[len(word):]), nil
+	}
+	return 0, "", fmt.Errorf("status %d is not one of 403, 404 or 410", status)
+}
+
+// parseQualifiers parses a comma separated list of version
+// constraints, normalizing the plain version prefixes.
+func parseQualifiers(s string) ([]string, error) {
+	alts := strings.Split(s, ",")
+	qual := make([]string, 0, len(alts))
+	for _, alt := range alts {
+		terms := strings.Fields(alt)
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("empty version constraint")
+		}
+		for i, t := range terms {
+			op, v := splitOperator(t)
+			switch {
+			case len(terms) == 1 && isLegacy(t) && op != "":
+				// kept as is, the original syntax did not check them
+			case t == "*" || t[0] == '+':
+			case op != "":
+				if !semver.IsValid(addV(v)) {
+					return nil, fmt.Errorf("invalid version %q", v)
+				}
+			default:
+				// a plain version prefix: v1 and v1.* both mean v1.
+				t = strings.TrimRight(t, "*")
+				if t == "" {
+					return nil, fmt.Errorf("invalid version constraint %q", terms[i])
+				}
+				if t[len(t)-1] != '.' && strings.Count(t, ".") < 2 {
+					t += "."
+				}
+				terms[i] = t
+			}
+		}
+		qual = append(qual, strings.Join(terms, " "))
+	}
+	return qual, nil
 }
 
 // matches checks if the given version matches the given qualifier.
-// Qualifiers can be:
+// A qualifier is a space separated list of constraints, all of which
+// the version must match. Constraints can be:
 // - plain versions
 // - v1.2.3 enables v1.2.3
 // - ~1.2.3: enables 1.2.x  which are at least 1.2.3
 // - ^1.2.3: enables 1.x.x which are at least 1.2.3
-// - <1.2.3: enables everything lower than 1.2.3 includes 1.2.2 and 0.58.9 as well
+// - <1.2.3, <=1.2.3, >1.2.3, >=1.2.3, =1.2.3 and !=1.2.3 compare semantic versions,
+//   and 1.2 stands for 1.2.0
+// - +incompatible enables the versions with this build suffix
+// - * enables every version
+// Legacy qualifiers keep the meaning they had before the others
+// were added, see matchesLegacy.
 func matches(version, qualifier string) bool {
+	if isLegacy(qualifier) {
+		return matchesLegacy(version, qualifier)
+	}
+	terms := strings.Fields(qualifier)
+	if len(terms) == 0 || len(version) < 1 {
+		return false
+	}
+	for _, t := range terms {
+		if !matchesTerm(version, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTerm(version, term string) bool {
+	if term == "*" {
+		return true
+	}
+
+	op, q := splitOperator(term)
+	if op == "" {
+		// v1.2.3 means we accept every version starting with v1.2.3
+		if len(term) >= 2 && term[0] == 'v' && term[1] >= '0' && term[1] <= '9' {
+			return strings.HasPrefix(version, term)
+		}
+		if term[0] == '+' {
+			return semver.IsValid(version) && semver.Build(version) == term
+		}
+		return false
+	}
+
+	q = addV(q)
+	if !semver.IsValid(version) || !semver.IsValid(q) {
+		return false
+	}
+	c := semver.Compare(version, q)
+	switch op {
+	case "~":
+		return c >= 0 && semver.MajorMinor(version) == semver.MajorMinor(q)
+	case "^":
+		return c >= 0 && semver.Major(version) == semver.Major(q)
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	}
+	return false
+}
+
+// isLegacy reports whether qualifier is written in the original syntax
+// of the filter file: a plain version prefix such as v1.2., or ~, ^
+// or < directly followed by a version with its v, such as <v1.2.3.
+func isLegacy(qualifier string) bool {
+	if qualifier == "" || qualifier == "*" || strings.ContainsAny(qualifier, " \t") {
+		return false
+	}
+	op, v := splitOperator(qualifier)
+	switch op {
+	case "":
+		return qualifier[0] != '+'
+	case "~", "^", "<":
+		return strings.HasPrefix(v, "v")
+	}
+	return false
+}
+
+// allLegacy reports whether all of qualifiers are legacy qualifiers.
+func allLegacy(qualifiers []string) bool {
+	for _, q := range qualifiers {
+		if !isLegacy(q) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesLegacy checks if the given version matches a legacy qualifier.
+// It only compares the major, minor and patch numbers of releases,
+// and <v1.2.3 enables v1.2.3 itself.
+func matchesLegacy(version, qualifier string) bool {
 	if len(qualifier) < 2 || len(version) < 1 {
 		return false
 	}
@@ -258,6 +601,33 @@ func matches(version, qualifier string) bool {
 	return false
 }
 
+// operators are the version comparison operators,
+// with the longer ones first.
+var operators = []string{"<=", ">=", "!=", "<", ">", "=", "~", "^"}
+
+// splitOperator splits the comparison operator off a constraint.
+func splitOperator(term string) (op, version string) {
+	for _, op := range operators {
+		if strings.HasPrefix(term, op) {
+			return op, term[len(op):]
+		}
+	}
+	return "", term
+}
+
+// addV adds the v prefix of semantic versions, which may be omitted in constraints.
+func addV(v string) string {
+	if strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
+
+// isGlob reports whether path is a glob pattern.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
 func getPathSegments(path string) []string {
 	return getSegments(path, pathSeparator)
 }
@@ -14,3 +14,16 @@ const (
 	// Direct filter rule forces the package to be fetched directly from upstream proxy
 	Direct
 )
+
+// Match is the rule of a filter that applies to a module version.
+type Match struct {
+	Rule FilterRule
+	// Line is the rule in the syntax of the filter
+	// file, or empty if no rule applies.
+	Line string
+	// Status is the HTTP status of the requests that an Exclude
+	// rule blocks, or 0 if the rule does not set one, and Reason
+	// is the explanation to send along with it, if any.
+	Status int
+	Reason string
+}
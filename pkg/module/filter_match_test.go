@@ -0,0 +1,271 @@
+package module
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		version   string
+		qualifier string
+		want      bool
+	}{
+		// plain version prefixes
+		{"v1.2.3", "v1.", true},
+		{"v10.0.0", "v1.", false},
+		{"v1.2.3", "v1.2.3", true},
+		{"v1.2.3", "abcd", false},
+		// operators
+		{"v0.0.9", "<0.1.0", true},
+		{"v0.1.0", "<0.1.0", false},
+		{"v0.1.0", "<=v0.1.0", true},
+		{"v0.1.0-pre", "<0.1.0", true},
+		{"v0.1.1", ">v0.1.0", true},
+		{"v0.1.0", ">v0.1.0", false},
+		{"v0.1.0", ">=v0.1", true},
+		{"v0.1.0", "=v0.1.0", true},
+		{"v0.1.1", "!=v0.1.0", true},
+		{"v0.1.0", "!=v0.1.0", false},
+		{"v1.0.0", "<1.2", true},
+		{"v0.0.0-20200101000000-abcdefabcdef", "<0.1.0", true},
+		{"master", "<0.1.0", false},
+		// legacy qualifiers keep their original meaning
+		{"v0.0.9", "<v0.1.0", true},
+		{"v0.1.0", "<v0.1.0", true},
+		{"v0.1.1", "<v0.1.0", false},
+		{"v0.1.0-pre", "<v0.1.0", false},
+		{"v2.3.40", "<v2.3.40", true},
+		{"v1.2.4-pre", "~v1.2.3", false},
+		{"v1.2.4-pre", "~1.2.3", true},
+		// tilde and caret
+		{"v1.2.4", "~v1.2.3", true},
+		{"v1.3.0", "~v1.2.3", false},
+		{"v1.2.4", "~1.2.3", true},
+		{"v1.9.0", "^v1.2.3", true},
+		{"v2.0.0", "^v1.2.3", false},
+		{"v1.2.2", "^v1.2.3", false},
+		// ranges, where < is always strict
+		{"v1.5.0", ">=v1.2.0 <v2.0.0", true},
+		{"v2.0.0", ">=v1.2.0 <v2.0.0", false},
+		{"v1.1.0", ">=v1.2.0 <v2.0.0", false},
+		{"v1.5.0", "v1. !=v1.5.0", false},
+		// build suffixes and wildcards
+		{"v2.0.0+incompatible", "+incompatible", true},
+		{"v2.0.0", "+incompatible", false},
+		{"v3.1.0+incompatible", ">=v3.0.0 +incompatible", true},
+		{"v1.0.0", "*", true},
+		{"", "*", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version+" "+tc.qualifier, func(t *testing.T) {
+			require.Equal(t, tc.want, matches(tc.version, tc.qualifier))
+		})
+	}
+}
+
+func TestParseQualifiers(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "v1,v2.3.4,v3.2.*", want: []string{"v1.", "v2.3.4", "v3.2."}},
+		{in: "~v1.2.3,^1.4.0", want: []string{"~v1.2.3", "^1.4.0"}},
+		{in: ">=v1.2.0 <v2.0.0, =v3.0.0", want: []string{">=v1.2.0 <v2.0.0", "=v3.0.0"}},
+		{in: "+incompatible,*", want: []string{"+incompatible", "*"}},
+		{in: "<v1.x", want: []string{"<v1.x"}},
+		{in: "<1.x", wantErr: true},
+		{in: "<=v1.x", wantErr: true},
+		{in: ">=v1.0.0 <v1.x", wantErr: true},
+		{in: ">=", wantErr: true},
+		{in: "v1,,v2", wantErr: true},
+		{in: "v1,**", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := parseQualifiers(tc.in)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFilterFileRules(t *testing.T) {
+	type check struct {
+		mod, ver string
+		want     FilterRule
+	}
+	tests := []struct {
+		name   string
+		file   string
+		checks []check
+	}{
+		{
+			name: "exclude old versions",
+			file: "- golang.org/x/crypto <0.1.0\n",
+			checks: []check{
+				{"golang.org/x/crypto", "v0.0.0-20200622213623-75b288015ac9", Exclude},
+				{"golang.org/x/crypto", "v0.1.0", Include},
+				{"golang.org/x/crypto", "", Include},
+				{"golang.org/x/net", "v0.0.1", Include},
+			},
+		},
+		{
+			name: "legacy less than",
+			file: "- golang.org/x/crypto <v0.1.0\n",
+			checks: []check{
+				{"golang.org/x/crypto", "v0.0.9", Exclude},
+				{"golang.org/x/crypto", "v0.1.0", Exclude},
+				{"golang.org/x/crypto", "v0.1.1", Include},
+			},
+		},
+		{
+			name: "legacy excludes apply to lists",
+			file: "- github.com/a/b v1.2,~v1.3.0\n- github.com/c/d <v1.0.0\n- github.com/e/f >=1.0.0\n- github.com/*/g v1\n",
+			checks: []check{
+				{"github.com/a/b", "v1.2.5", Exclude},
+				{"github.com/a/b", "v1.4.0", Include},
+				{"github.com/a/b", "", Exclude},
+				{"github.com/c/d", "", Exclude},
+				{"github.com/e/f", "", Include},
+				{"github.com/x/g", "", Include},
+			},
+		},
+		{
+			name: "exclude incompatible versions",
+			file: "- * +incompatible\n+ github.com/legacy/mod\n",
+			checks: []check{
+				{"github.com/a/b", "v2.0.0+incompatible", Exclude},
+				{"github.com/a/b", "v1.0.0", Include},
+				{"github.com/a/b", "", Include},
+				{"github.com/legacy/mod", "v2.0.0+incompatible", Include},
+			},
+		},
+		{
+			name: "glob paths",
+			file: "- github.com/*/internal-*\nD github.com/corp/*\n",
+			checks: []check{
+				{"github.com/a/internal-tools", "v1.0.0", Exclude},
+				{"github.com/a/internal-tools/sub", "", Exclude},
+				{"github.com/a/tools", "v1.0.0", Include},
+				// the last of the globs of the same length wins
+				{"github.com/corp/internal-x", "v1.0.0", Direct},
+				{"github.com/corp/lib", "v1.0.0", Direct},
+			},
+		},
+		{
+			name: "longer paths win",
+			file: "-\n+ github.com/*\n- github.com/a\nD github.com/a/*\n+ github.com/a/b\n",
+			checks: []check{
+				{"github.com/x", "", Include},
+				{"github.com/a", "", Exclude},
+				{"github.com/a/c", "", Direct},
+				{"github.com/a/b", "", Include},
+				{"gitlab.com/a", "", Exclude},
+			},
+		},
+		{
+			name: "plain paths win over globs of the same length",
+			file: "- github.com/a/*\n+ github.com/a/b\n- github.com/c/d\n+ github.com/c/*\n",
+			checks: []check{
+				{"github.com/a/b", "", Include},
+				{"github.com/a/c", "", Exclude},
+				{"github.com/c/d", "", Exclude},
+				{"github.com/c/e", "", Include},
+			},
+		},
+		{
+			name: "version ranges",
+			file: "-\n+ github.com/a/b >=v1.2.0 <v2.0.0, v3.1\n",
+			checks: []check{
+				{"github.com/a/b", "v1.4.0", Include},
+				{"github.com/a/b", "v2.1.0", Exclude},
+				{"github.com/a/b", "v3.1.7", Include},
+				{"github.com/a/b", "", Include},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := ioutil.TempFile("", "filter-")
+			require.NoError(t, err)
+			defer os.Remove(file.Name())
+			_, err = file.WriteString(tc.file)
+			require.NoError(t, err)
+			require.NoError(t, file.Close())
+
+			f, err := NewFilter(file.Name())
+			require.NoError(t, err)
+			for _, c := range tc.checks {
+				require.Equal(t, c.want, f.Rule(c.mod, c.ver), "%s@%s", c.mod, c.ver)
+			}
+		})
+	}
+}
+
+func TestFilterFileInvalid(t *testing.T) {
+	for _, content := range []string{
+		"- github.com/a >=v1.x\n",
+		"- github.com/[a\n",
+		"? github.com/a\n",
+	} {
+		file, err := ioutil.TempFile("", "filter-")
+		require.NoError(t, err)
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(content)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		_, err = NewFilter(file.Name())
+		require.Error(t, err, content)
+	}
+}
+
+func TestFilterFileBlocks(t *testing.T) {
+	file, err := ioutil.TempFile("", "filter-")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(`- : 404 this proxy only serves approved modules
++ github.com/a
+- golang.org/x/crypto <0.1.0 : 410 upgrade to v0.1.0 or later
+- github.com/*/internal-* :internal modules are not served
+- github.com/b : 2020 audit failed
+- github.com/c
+`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	f, err := NewFilter(file.Name())
+	require.NoError(t, err)
+
+	tests := []struct {
+		mod, ver string
+		want     Match
+	}{
+		{"gitlab.com/a", "v1.0.0", Match{Exclude, "- : 404 this proxy only serves approved modules", 404, "this proxy only serves approved modules"}},
+		{"github.com/a", "v1.0.0", Match{Include, "+ github.com/a", 0, ""}},
+		{"golang.org/x/crypto", "v0.0.1", Match{Exclude, "- golang.org/x/crypto <0.1.0 : 410 upgrade to v0.1.0 or later", 410, "upgrade to v0.1.0 or later"}},
+		{"github.com/x/internal-y", "", Match{Exclude, "- github.com/*/internal-* :internal modules are not served", 0, "internal modules are not served"}},
+		{"github.com/b", "", Match{Exclude, "- github.com/b : 2020 audit failed", 0, "2020 audit failed"}},
+		{"github.com/c", "", Match{Exclude, "- github.com/c", 0, ""}},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.want, f.Match(tc.mod, tc.ver), "%s@%s", tc.mod, tc.ver)
+	}
+
+	for _, content := range []string{
+		"+ github.com/a : 404 not here\n",
+		"- github.com/a : 500 broken\n",
+		": 404\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(file.Name(), []byte(content), 0644))
+		_, err = NewFilter(file.Name())
+		require.Error(t, err, content)
+	}
+}
@@ -4,4 +4,27 @@ type ruleNode struct {
 	next       map[string]ruleNode
 	rule       FilterRule
 	qualifiers []string
+	ruleInfo
+}
+
+// ruleInfo is how a rule was written in the filter
+// file, and how it answers the requests it excludes.
+type ruleInfo struct {
+	line   string
+	status int
+	reason string
+}
+
+func (i ruleInfo) match(rule FilterRule) Match {
+	return Match{Rule: rule, Line: i.line, Status: i.status, Reason: i.reason}
+}
+
+// clone returns a deep copy of rn.
+func (rn ruleNode) clone() ruleNode {
+	c := rn
+	c.next = make(map[string]ruleNode, len(rn.next))
+	for k, v := range rn.next {
+		c.next[k] = v.clone()
+	}
+	return c
 }
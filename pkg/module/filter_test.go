@@ -1,10 +1,14 @@
 package module
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -182,6 +186,7 @@ func (t *FilterTests) Test_versionFilterLess() {
 	r.Equal(Include, f.Rule("github.com/a/b", "v1.4.2"))
 	r.Equal(Include, f.Rule("github.com/a/b", "v1.2.1"))
 	r.Equal(Include, f.Rule("github.com/a/b", "v2.3.39"))
+	r.Equal(Include, f.Rule("github.com/a/b", "v2.3.40"))
 	r.Equal(Include, f.Rule("github.com/a/b", "v2.2.45"))
 	r.Equal(Exclude, f.Rule("github.com/a/b", "v2.4.1"))
 }
@@ -227,6 +232,109 @@ func (t *FilterTests) Test_initFromConfig() {
 	r.NoError(err)
 }
 
+func (t *FilterTests) Test_Reload() {
+	r := t.Require()
+	filterFile := tempFilterFile(t.T())
+	defer os.Remove(filterFile)
+
+	r.NoError(ioutil.WriteFile(filterFile, []byte("- github.com/a/b\n"), 0644))
+	f, err := NewFilter(filterFile)
+	r.NoError(err)
+	rules, loadedAt := f.Rules()
+	r.Equal([]string{"- github.com/a/b"}, rules)
+	r.False(loadedAt.IsZero())
+	r.Equal(Exclude, f.Rule("github.com/a/b", ""))
+
+	r.NoError(ioutil.WriteFile(filterFile, []byte("+ github.com/a/b\n- github.com/c/d v1\n"), 0644))
+	r.NoError(f.Reload())
+	rules, _ = f.Rules()
+	r.Equal([]string{"+ github.com/a/b", "- github.com/c/d v1"}, rules)
+	r.Equal(Include, f.Rule("github.com/a/b", ""))
+	r.Equal(Exclude, f.Rule("github.com/c/d", "v1.0.0"))
+
+	// a broken file keeps the current rules
+	r.NoError(ioutil.WriteFile(filterFile, []byte("- github.com/a/b\nsome_random_line\n"), 0644))
+	r.Error(f.Reload())
+	rules, _ = f.Rules()
+	r.Equal([]string{"+ github.com/a/b", "- github.com/c/d v1"}, rules)
+	r.Equal(Include, f.Rule("github.com/a/b", ""))
+
+	f.AddRule("github.com/e", []string{"v2."}, Direct)
+	rules, _ = f.Rules()
+	r.Equal("D github.com/e v2.", rules[len(rules)-1])
+}
+
+func (t *FilterTests) Test_Watch() {
+	r := t.Require()
+	filterFile := tempFilterFile(t.T())
+	defer os.Remove(filterFile)
+
+	r.NoError(ioutil.WriteFile(filterFile, []byte("- github.com/a/b\n"), 0644))
+	f, err := NewFilter(filterFile)
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reload := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		f.Watch(ctx, 10*time.Millisecond, reload)
+		close(done)
+	}()
+
+	// the file changes
+	r.NoError(ioutil.WriteFile(filterFile, []byte("+ github.com/a/b\n- github.com/c\n"), 0644))
+	r.Eventually(func() bool {
+		return f.Rule("github.com/c", "") == Exclude
+	}, time.Second, 5*time.Millisecond)
+	r.Equal(Include, f.Rule("github.com/a/b", ""))
+
+	// a broken file is ignored
+	r.NoError(ioutil.WriteFile(filterFile, []byte("- github.com/a/b\n? github.com/c\n"), 0644))
+	time.Sleep(50 * time.Millisecond)
+	r.Equal(Include, f.Rule("github.com/a/b", ""))
+
+	// the file is reloaded on a signal, even when it did not change
+	f.AddRule("github.com/d", nil, Exclude)
+	r.NoError(ioutil.WriteFile(filterFile, []byte("- github.com/a/b\n"), 0644))
+	reload <- syscall.SIGHUP
+	r.Eventually(func() bool {
+		return f.Rule("github.com/a/b", "") == Exclude
+	}, time.Second, 5*time.Millisecond)
+	r.Equal(Include, f.Rule("github.com/d", ""))
+
+	cancel()
+	<-done
+}
+
+func (t *FilterTests) Test_ConcurrentReload() {
+	r := t.Require()
+	filterFile := tempFilterFile(t.T())
+	defer os.Remove(filterFile)
+
+	r.NoError(ioutil.WriteFile(filterFile, []byte("-\n+ github.com/a\n"), 0644))
+	f, err := NewFilter(filterFile)
+	r.NoError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				f.Rule("github.com/a/b", "v1.0.0")
+				f.Rules()
+			}
+		}()
+	}
+	for j := 0; j < 20; j++ {
+		r.NoError(f.Reload())
+		f.AddRule("github.com/b", nil, Direct)
+	}
+	wg.Wait()
+	r.Equal(Include, f.Rule("github.com/a/b", "v1.0.0"))
+}
+
 func tempFilterFile(t *testing.T) (path string) {
 	filter, err := ioutil.TempFile(os.TempDir(), "filter-")
 	if err != nil {
@@ -85,6 +85,8 @@ func (g *goGetFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Ver
 
 	var storageVer storage.Version
 	storageVer.Semver = m.Version
+	storageVer.Sum = m.Sum
+	storageVer.GoModSum = m.GoModSum
 	info, err := afero.ReadFile(g.fs, m.Info)
 	if err != nil {
 		return nil, errors.E(op, err)
@@ -0,0 +1,125 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"golang.org/x/mod/module"
+)
+
+const (
+	proxyDirect = "direct"
+	proxyOff    = "off"
+)
+
+// proxyEntry is a single element of a GOPROXY list.
+type proxyEntry struct {
+	url string
+	// fallBackOnError is true when the entry was followed by a pipe,
+	// meaning the next entry should be tried on any error and not
+	// just on a 404 or 410.
+	fallBackOnError bool
+}
+
+// parseGoProxy parses a GOPROXY formatted list such as
+// "https://proxy.golang.org,direct" or "https://a.com|https://b.com".
+// Entries separated by a comma only fall back on not found errors,
+// while entries separated by a pipe fall back on any error. This
+// mirrors the semantics of the go command.
+func parseGoProxy(goproxy string) ([]proxyEntry, error) {
+	const op errors.Op = "module.parseGoProxy"
+	var entries []proxyEntry
+	for goproxy != "" {
+		var entry proxyEntry
+		if i := strings.IndexAny(goproxy, ",|"); i >= 0 {
+			entry.url = goproxy[:i]
+			entry.fallBackOnError = goproxy[i] == '|'
+			goproxy = goproxy[i+1:]
+		} else {
+			entry.url = goproxy
+			goproxy = ""
+		}
+		entry.url = strings.TrimSpace(entry.url)
+		if entry.url == "" {
+			continue
+		}
+		switch entry.url {
+		case proxyDirect, proxyOff:
+		default:
+			if !strings.HasPrefix(entry.url, "http://") && !strings.HasPrefix(entry.url, "https://") {
+				return nil, errors.E(op, fmt.Sprintf("invalid GOPROXY entry %q: must be direct, off or an http(s) URL", entry.url))
+			}
+			entry.url = strings.TrimSuffix(entry.url, "/")
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, errors.E(op, "GOPROXY list is empty")
+	}
+	return entries, nil
+}
+
+// shouldFallBack reports whether the next entry of a GOPROXY list
+// should be tried after the given entry returned err.
+func shouldFallBack(entry proxyEntry, err error) bool {
+	return entry.fallBackOnError || errors.IsNotFoundErr(err)
+}
+
+// errProxyOff returns the error reported when
+// the GOPROXY list reaches an "off" entry.
+func errProxyOff(op errors.Op, mod string) error {
+	return errors.E(op, errors.M(mod), "module lookup disabled by GOPROXY=off", errors.KindNotFound)
+}
+
+// proxyGet makes a GET request to the given upstream proxy for
+// the given module and path suffix such as "@v/list". The module path
+// is escaped according to the download protocol. The caller must
+// close the response body when the returned error is nil.
+func proxyGet(ctx context.Context, client *http.Client, baseURL, mod, suffix string) (*http.Response, error) {
+	const op errors.Op = "module.proxyGet"
+	escMod, err := module.EscapePath(mod)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), err, errors.KindBadRequest)
+	}
+	u := baseURL + "/" + escMod + "/" + suffix
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	msg := fmt.Sprintf("%s: %s %s", u, resp.Status, strings.TrimSpace(string(body)))
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return nil, errors.E(op, errors.M(mod), msg, errors.KindNotFound)
+	case http.StatusTooManyRequests:
+		return nil, errors.E(op, errors.M(mod), msg, errors.KindRateLimit)
+	}
+	return nil, errors.E(op, errors.M(mod), msg)
+}
+
+// proxyGetBytes is like proxyGet but reads the full response body.
+func proxyGetBytes(ctx context.Context, client *http.Client, baseURL, mod, suffix string) ([]byte, error) {
+	const op errors.Op = "module.proxyGetBytes"
+	resp, err := proxyGet(ctx, client, baseURL, mod, suffix)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), err)
+	}
+	return body, nil
+}
@@ -0,0 +1,120 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/module"
+)
+
+type goProxyFetcher struct {
+	proxies []proxyEntry
+	client  *http.Client
+	direct  Fetcher
+}
+
+// NewGoProxyFetcher creates a fetcher which speaks the download protocol
+// directly to the upstream proxies listed in goproxy. The list follows the
+// same format as the GOPROXY environment variable, e.g.
+// "https://proxy.golang.org,direct". When the list reaches a "direct" entry
+// the request is handed over to the direct Fetcher, which may only be nil
+// if the list does not contain "direct".
+func NewGoProxyFetcher(goproxy string, client *http.Client, direct Fetcher) (Fetcher, error) {
+	const op errors.Op = "module.NewGoProxyFetcher"
+	proxies, err := parseGoProxy(goproxy)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	for _, p := range proxies {
+		if p.url == proxyDirect && direct == nil {
+			return nil, errors.E(op, "a direct fetcher is required when GOPROXY contains direct")
+		}
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &goProxyFetcher{
+		proxies: proxies,
+		client:  client,
+		direct:  direct,
+	}, nil
+}
+
+// Fetch downloads the .info, .mod, and .zip files of the given module
+// from the first upstream proxy that has it.
+func (g *goProxyFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "goProxyFetcher.Fetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	var err error
+	for _, p := range g.proxies {
+		var v *storage.Version
+		switch p.url {
+		case proxyOff:
+			return nil, errProxyOff(op, mod)
+		case proxyDirect:
+			v, err = g.direct.Fetch(ctx, mod, ver)
+		default:
+			v, err = g.fetchFrom(ctx, p.url, mod, ver)
+		}
+		if err == nil {
+			return v, nil
+		}
+		if !shouldFallBack(p, err) {
+			break
+		}
+	}
+	return nil, errors.E(op, err)
+}
+
+func (g *goProxyFetcher) fetchFrom(ctx context.Context, baseURL, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "goProxyFetcher.fetchFrom"
+	escVer, err := module.EscapeVersion(ver)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err, errors.KindBadRequest)
+	}
+
+	// the .info endpoint resolves queries such as branch names
+	// or commit hashes into a canonical version, which is then
+	// used to retrieve the .mod and .zip files.
+	info, err := proxyGetBytes(ctx, g.client, baseURL, mod, "@v/"+escVer+".info")
+	if err != nil {
+		return nil, errors.E(op, errors.V(ver), err)
+	}
+	var rev storage.RevInfo
+	if err := json.Unmarshal(info, &rev); err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err)
+	}
+	if rev.Version == "" {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), "upstream .info response is missing a version")
+	}
+	escSemver, err := module.EscapeVersion(rev.Version)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(rev.Version), err)
+	}
+
+	gomod, err := proxyGetBytes(ctx, g.client, baseURL, mod, "@v/"+escSemver+".mod")
+	if err != nil {
+		return nil, errors.E(op, errors.V(rev.Version), err)
+	}
+
+	// note: the zip is streamed straight from the upstream response
+	// so that it does not have to be buffered in memory or on disk.
+	// The caller is responsible for closing it.
+	zip, err := proxyGet(ctx, g.client, baseURL, mod, "@v/"+escSemver+".zip")
+	if err != nil {
+		return nil, errors.E(op, errors.V(rev.Version), err)
+	}
+
+	return &storage.Version{
+		Semver: rev.Version,
+		Info:   info,
+		Mod:    gomod,
+		Zip:    zip.Body,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package module
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+func (s *ModuleSuite) mockProxyPaths() map[string][]byte {
+	zipBytes, err := ioutil.ReadFile("test_data/mockmod.xyz@v1.2.3.zip")
+	s.Require().NoError(err)
+	return map[string][]byte{
+		"/mockmod.xyz/@v/v1.2.3.info": []byte(`{"Version":"v1.2.3"}`),
+		"/mockmod.xyz/@v/master.info": []byte(`{"Version":"v1.2.3"}`),
+		"/mockmod.xyz/@v/v1.2.3.mod":  []byte("module mockmod.xyz"),
+		"/mockmod.xyz/@v/v1.2.3.zip":  zipBytes,
+	}
+}
+
+func (s *ModuleSuite) TestGoProxyFetcherFetch() {
+	r := s.Require()
+	mp := &mockProxy{paths: s.mockProxyPaths()}
+	proxyAddr, close := s.getProxy(mp)
+	defer close()
+
+	fetcher, err := NewGoProxyFetcher(proxyAddr, nil, nil)
+	r.NoError(err)
+	ver, err := fetcher.Fetch(ctx, "mockmod.xyz", "master")
+	r.NoError(err)
+	defer ver.Zip.Close()
+
+	r.Equal("v1.2.3", ver.Semver)
+	r.Equal("module mockmod.xyz", string(ver.Mod))
+	zipBytes, err := ioutil.ReadAll(ver.Zip)
+	r.NoError(err)
+	r.Equal(mp.paths["/mockmod.xyz/@v/v1.2.3.zip"], zipBytes)
+}
+
+func (s *ModuleSuite) TestGoProxyFetcherFallback() {
+	r := s.Require()
+	empty, closeEmpty := s.getProxy(&mockProxy{})
+	defer closeEmpty()
+	broken, closeBroken := s.getProxy(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer closeBroken()
+	full, closeFull := s.getProxy(&mockProxy{paths: s.mockProxyPaths()})
+	defer closeFull()
+
+	tests := []struct {
+		name     string
+		goproxy  string
+		direct   Fetcher
+		wantErr  bool
+		wantKind int
+	}{
+		{name: "comma falls back on not found", goproxy: empty + "," + full},
+		{name: "comma stops on unexpected errors", goproxy: broken + "," + full, wantErr: true, wantKind: errors.KindUnexpected},
+		{name: "pipe falls back on any error", goproxy: broken + "|" + full},
+		{name: "off stops the lookup", goproxy: empty + ",off," + full, wantErr: true, wantKind: errors.KindNotFound},
+		{name: "direct", goproxy: empty + ",direct", direct: &mockFetcher{ver: "v1.2.3"}},
+		{name: "all not found", goproxy: empty + "," + empty, wantErr: true, wantKind: errors.KindNotFound},
+	}
+	for _, tc := range tests {
+		s.Run(tc.name, func() {
+			fetcher, err := NewGoProxyFetcher(tc.goproxy, nil, tc.direct)
+			r.NoError(err)
+			ver, err := fetcher.Fetch(ctx, "mockmod.xyz", "v1.2.3")
+			if tc.wantErr {
+				r.Error(err)
+				r.Equal(tc.wantKind, errors.Kind(err))
+				return
+			}
+			r.NoError(err)
+			defer ver.Zip.Close()
+			r.Equal("v1.2.3", ver.Semver)
+		})
+	}
+}
+
+func (s *ModuleSuite) TestNewGoProxyFetcherErrors() {
+	r := s.Require()
+	_, err := NewGoProxyFetcher("", nil, nil)
+	r.Error(err)
+	_, err = NewGoProxyFetcher("proxy.golang.org", nil, nil)
+	r.Error(err)
+	_, err = NewGoProxyFetcher("https://proxy.golang.org,direct", nil, nil)
+	r.Error(err, "direct requires a direct fetcher")
+}
+
+func (s *ModuleSuite) TestParseGoProxy() {
+	r := s.Require()
+	entries, err := parseGoProxy("https://a.com/,https://b.com|direct, off")
+	r.NoError(err)
+	r.Equal([]proxyEntry{
+		{url: "https://a.com", fallBackOnError: false},
+		{url: "https://b.com", fallBackOnError: true},
+		{url: "direct", fallBackOnError: false},
+		{url: "off", fallBackOnError: false},
+	}, entries)
+}
+
+type mockFetcher struct {
+	ver string
+}
+
+func (m *mockFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	return &storage.Version{
+		Semver: m.ver,
+		Zip:    ioutil.NopCloser(nil),
+	}, nil
+}
@@ -0,0 +1,172 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+type goProxyLister struct {
+	proxies []proxyEntry
+	client  *http.Client
+	direct  UpstreamLister
+}
+
+// NewGoProxyLister creates an UpstreamLister which queries the /@v/list
+// and /@latest endpoints of the upstream proxies listed in goproxy, using
+// the same format as the GOPROXY environment variable. The results of
+// all proxies are merged, up to a proxy that fails without falling back
+// to the next one, whose error is only returned if no proxy before it
+// knew the module. A "direct" entry is only consulted through the
+// direct UpstreamLister when no proxy before it knows the module, so that
+// the Go command is not run for modules the proxies already serve.
+func NewGoProxyLister(goproxy string, client *http.Client, direct UpstreamLister) (UpstreamLister, error) {
+	const op errors.Op = "module.NewGoProxyLister"
+	proxies, err := parseGoProxy(goproxy)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	for _, p := range proxies {
+		if p.url == proxyDirect && direct == nil {
+			return nil, errors.E(op, "a direct lister is required when GOPROXY contains direct")
+		}
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &goProxyLister{
+		proxies: proxies,
+		client:  client,
+		direct:  direct,
+	}, nil
+}
+
+func (l *goProxyLister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "goProxyLister.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	var (
+		latest   *storage.RevInfo
+		versions []string
+		found    bool
+		lastErr  error
+	)
+loop:
+	for _, p := range l.proxies {
+		var rev *storage.RevInfo
+		var vers []string
+		var err error
+		switch p.url {
+		case proxyOff:
+			if !found {
+				return nil, nil, errProxyOff(op, mod)
+			}
+			break loop
+		case proxyDirect:
+			if found {
+				break loop
+			}
+			rev, vers, err = l.direct.List(ctx, mod)
+		default:
+			rev, vers, err = l.listFrom(ctx, p.url, mod)
+		}
+		if err != nil {
+			lastErr = err
+			if !shouldFallBack(p, err) {
+				// the go command would stop at this proxy, but
+				// the ones before it already knew the module.
+				if found {
+					break loop
+				}
+				return nil, nil, errors.E(op, err)
+			}
+			continue
+		}
+		found = true
+		versions = mergeVersions(versions, vers)
+		if latest == nil || semver.Compare(rev.Version, latest.Version) > 0 {
+			latest = rev
+		}
+	}
+	if found {
+		return latest, versions, nil
+	}
+	if errors.IsNotFoundErr(lastErr) {
+		return nil, nil, errors.E(op, errors.M(mod), errors.RepoNotFound(lastErr), errors.KindNotFound)
+	}
+	return nil, nil, errors.E(op, lastErr)
+}
+
+// listFrom returns the latest version and the list of
+// versions that a single upstream proxy knows about.
+func (l *goProxyLister) listFrom(ctx context.Context, baseURL, mod string) (*storage.RevInfo, []string, error) {
+	const op errors.Op = "goProxyLister.listFrom"
+	list, listErr := proxyGetBytes(ctx, l.client, baseURL, mod, "@v/list")
+	if listErr != nil && !errors.IsNotFoundErr(listErr) {
+		return nil, nil, errors.E(op, listErr)
+	}
+	var versions []string
+	for _, v := range strings.Split(string(list), "\n") {
+		if v = strings.TrimSpace(v); v != "" {
+			versions = append(versions, v)
+		}
+	}
+
+	rev, err := l.revInfo(ctx, baseURL, mod, "@latest")
+	if errors.IsNotFoundErr(err) && len(versions) > 0 {
+		// not every proxy implements @latest, in which case
+		// the highest listed version is the latest one.
+		latest := versions[0]
+		for _, v := range versions[1:] {
+			if semver.Compare(v, latest) > 0 {
+				latest = v
+			}
+		}
+		escVer, escErr := module.EscapeVersion(latest)
+		if escErr != nil {
+			return nil, nil, errors.E(op, errors.M(mod), errors.V(latest), escErr)
+		}
+		rev, err = l.revInfo(ctx, baseURL, mod, "@v/"+escVer+".info")
+	}
+	if err != nil {
+		return nil, nil, errors.E(op, err)
+	}
+	return rev, versions, nil
+}
+
+func (l *goProxyLister) revInfo(ctx context.Context, baseURL, mod, suffix string) (*storage.RevInfo, error) {
+	const op errors.Op = "goProxyLister.revInfo"
+	info, err := proxyGetBytes(ctx, l.client, baseURL, mod, suffix)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var rev storage.RevInfo
+	if err := json.Unmarshal(info, &rev); err != nil {
+		return nil, errors.E(op, errors.M(mod), err)
+	}
+	return &rev, nil
+}
+
+// mergeVersions appends the versions of list2 that
+// are not already in list1.
+func mergeVersions(list1, list2 []string) []string {
+	seen := make(map[string]struct{}, len(list1))
+	for _, v := range list1 {
+		seen[v] = struct{}{}
+	}
+	for _, v := range list2 {
+		if _, ok := seen[v]; !ok {
+			list1 = append(list1, v)
+			seen[v] = struct{}{}
+		}
+	}
+	return list1
+}
@@ -0,0 +1,96 @@
+package module
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+func (s *ModuleSuite) TestGoProxyListerList() {
+	r := s.Require()
+	a, closeA := s.getProxy(&mockProxy{paths: map[string][]byte{
+		"/github.com/!azure/mod/@v/list": []byte("v1.0.0\nv1.1.0\n"),
+		"/github.com/!azure/mod/@latest": []byte(`{"Version":"v1.1.0","Time":"2020-01-01T00:00:00Z"}`),
+	}})
+	defer closeA()
+	// b does not implement @latest, so the highest
+	// listed version's .info is used instead.
+	b, closeB := s.getProxy(&mockProxy{paths: map[string][]byte{
+		"/github.com/!azure/mod/@v/list":        []byte("v1.1.0\nv1.2.0"),
+		"/github.com/!azure/mod/@v/v1.2.0.info": []byte(`{"Version":"v1.2.0","Time":"2020-02-01T00:00:00Z"}`),
+	}})
+	defer closeB()
+	empty, closeEmpty := s.getProxy(&mockProxy{})
+	defer closeEmpty()
+
+	lister, err := NewGoProxyLister(a+","+empty+","+b, nil, nil)
+	r.NoError(err)
+	rev, versions, err := lister.List(ctx, "github.com/Azure/mod")
+	r.NoError(err)
+	r.Equal([]string{"v1.0.0", "v1.1.0", "v1.2.0"}, versions)
+	r.Equal("v1.2.0", rev.Version)
+	r.Equal(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), rev.Time)
+}
+
+func (s *ModuleSuite) TestGoProxyListerErrors() {
+	r := s.Require()
+	empty, closeEmpty := s.getProxy(&mockProxy{})
+	defer closeEmpty()
+	broken, closeBroken := s.getProxy(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer closeBroken()
+	pseudo, closePseudo := s.getProxy(&mockProxy{paths: map[string][]byte{
+		"/mockmod.xyz/@v/list": []byte(""),
+		"/mockmod.xyz/@latest": []byte(`{"Version":"v0.0.0-20200101000000-abcdefabcdef"}`),
+	}})
+	defer closePseudo()
+
+	tests := []struct {
+		name         string
+		goproxy      string
+		direct       UpstreamLister
+		wantErr      bool
+		wantKind     int
+		wantNotFound bool
+		wantLatest   string
+	}{
+		{name: "not found", goproxy: empty, wantErr: true, wantKind: errors.KindNotFound, wantNotFound: true},
+		{name: "unexpected error", goproxy: broken + "," + pseudo, wantErr: true, wantKind: errors.KindUnexpected},
+		{name: "unexpected error after a proxy knew the module", goproxy: pseudo + "," + broken, wantLatest: "v0.0.0-20200101000000-abcdefabcdef"},
+		{name: "pipe falls back", goproxy: broken + "|" + pseudo, wantLatest: "v0.0.0-20200101000000-abcdefabcdef"},
+		{name: "off", goproxy: "off," + pseudo, wantErr: true, wantKind: errors.KindNotFound},
+		{name: "direct only when needed", goproxy: pseudo + ",direct", direct: &mockLister{err: errors.E("mockLister.List", "should not be called")}, wantLatest: "v0.0.0-20200101000000-abcdefabcdef"},
+		{name: "direct", goproxy: empty + ",direct", direct: &mockLister{latest: "v1.0.0"}, wantLatest: "v1.0.0"},
+	}
+	for _, tc := range tests {
+		s.Run(tc.name, func() {
+			lister, err := NewGoProxyLister(tc.goproxy, nil, tc.direct)
+			r.NoError(err)
+			rev, _, err := lister.List(ctx, "mockmod.xyz")
+			if tc.wantErr {
+				r.Error(err)
+				r.Equal(tc.wantKind, errors.Kind(err))
+				r.Equal(tc.wantNotFound, errors.IsRepoNotFoundErr(err))
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.wantLatest, rev.Version)
+		})
+	}
+}
+
+type mockLister struct {
+	latest string
+	err    error
+}
+
+func (m *mockLister) List(ctx context.Context, mod string) (*storage.RevInfo, []string, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	return &storage.RevInfo{Version: m.latest}, []string{m.latest}, nil
+}
@@ -0,0 +1,47 @@
+package notfound
+
+import (
+	"context"
+	"strings"
+)
+
+// Cache remembers, for a limited time, the modules
+// and versions that upstream reported as not found.
+//
+// An empty version stands for the module itself,
+// that is for its list of versions and its latest version.
+type Cache interface {
+	// Has reports whether mod@ver is cached as not found.
+	Has(ctx context.Context, mod, ver string) (bool, error)
+
+	// Add caches mod@ver as not found until the TTL of the cache expires.
+	Add(ctx context.Context, mod, ver string) error
+
+	// Invalidate removes mod@ver from the cache. An empty ver
+	// removes the module and all of its versions, and an empty
+	// mod removes everything.
+	Invalidate(ctx context.Context, mod, ver string) error
+}
+
+// key returns the key of mod@ver. The key of a module
+// is the prefix of the keys of all of its versions.
+func key(mod, ver string) string {
+	return mod + "@" + ver
+}
+
+// prefix returns the prefix of the keys
+// that Invalidate(mod, ver) removes.
+func prefix(mod, ver string) string {
+	if mod == "" {
+		return ""
+	}
+	return key(mod, ver)
+}
+
+// matches reports whether k is removed by Invalidate(mod, ver).
+func matches(k, mod, ver string) bool {
+	if ver != "" {
+		return k == key(mod, ver)
+	}
+	return strings.HasPrefix(k, prefix(mod, ver))
+}
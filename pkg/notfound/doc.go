@@ -0,0 +1,10 @@
+// Package notfound provides a negative cache of the modules
+// and versions that upstream reported as not found.
+//
+// Without it, every go command that asks for a module or version
+// that does not exist makes the proxy run the Go command or ask the
+// upstream proxy again, which is slow and hammers VCS hosts.
+// Entries expire after a TTL so that modules and versions that
+// are published later are picked up, and they can be invalidated
+// earlier through the admin API.
+package notfound
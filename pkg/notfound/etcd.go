@@ -0,0 +1,84 @@
+package notfound
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"go.etcd.io/etcd/clientv3"
+	"golang.org/x/sync/errgroup"
+)
+
+// etcdNamespace prefixes the keys of the cache in etcd.
+const etcdNamespace = "athens/notfound/"
+
+// NewEtcd returns a Cache in an etcd cluster, so that it can be
+// shared by several Athens instances. Entries expire after ttl,
+// rounded up to a second. If it cannot connect to any of the
+// endpoints, it will return an error.
+func NewEtcd(endpoints []string, ttl time.Duration) (Cache, error) {
+	const op errors.Op = "notfound.NewEtcd"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	c, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: time.Second * 5,
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var eg errgroup.Group
+	for _, ep := range endpoints {
+		ep := ep
+		eg.Go(func() error {
+			_, err := c.Status(ctx, ep)
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	ttlSeconds := int64((ttl + time.Second - 1) / time.Second)
+	return &etcdCache{client: c, ttl: ttlSeconds}, nil
+}
+
+type etcdCache struct {
+	client *clientv3.Client
+	ttl    int64
+}
+
+func (c *etcdCache) Has(ctx context.Context, mod, ver string) (bool, error) {
+	const op errors.Op = "etcdCache.Has"
+	resp, err := c.client.Get(ctx, etcdNamespace+key(mod, ver), clientv3.WithCountOnly())
+	if err != nil {
+		return false, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return resp.Count > 0, nil
+}
+
+func (c *etcdCache) Add(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "etcdCache.Add"
+	lease, err := c.client.Grant(ctx, c.ttl)
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	_, err = c.client.Put(ctx, etcdNamespace+key(mod, ver), "", clientv3.WithLease(lease.ID))
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
+
+func (c *etcdCache) Invalidate(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "etcdCache.Invalidate"
+	var err error
+	if ver != "" {
+		_, err = c.client.Delete(ctx, etcdNamespace+key(mod, ver))
+	} else {
+		_, err = c.client.Delete(ctx, etcdNamespace+prefix(mod, ver), clientv3.WithPrefix())
+	}
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package notfound
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewMemory returns an in-memory Cache whose entries expire after ttl.
+// Expired entries are removed lazily, at most once every ttl.
+func NewMemory(ttl time.Duration) Cache {
+	return &memCache{ttl: ttl, now: time.Now, expiries: map[string]time.Time{}}
+}
+
+type memCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	expiries  map[string]time.Time
+	lastSweep time.Time
+}
+
+func (c *memCache) Has(ctx context.Context, mod, ver string) (bool, error) {
+	k := key(mod, ver)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiry, ok := c.expiries[k]
+	if !ok {
+		return false, nil
+	}
+	if !c.now().Before(expiry) {
+		delete(c.expiries, k)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c *memCache) Add(ctx context.Context, mod, ver string) error {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for k, expiry := range c.expiries {
+			if !now.Before(expiry) {
+				delete(c.expiries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.expiries[key(mod, ver)] = now.Add(c.ttl)
+	return nil
+}
+
+func (c *memCache) Invalidate(ctx context.Context, mod, ver string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.expiries {
+		if matches(k, mod, ver) {
+			delete(c.expiries, k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package notfound
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMemory(time.Minute).(*memCache)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Add(ctx, "example.com/mod", "v1.0.0"))
+	has, err := c.Has(ctx, "example.com/mod", "v1.0.0")
+	require.NoError(t, err)
+	require.True(t, has)
+	has, err = c.Has(ctx, "example.com/mod", "")
+	require.NoError(t, err)
+	require.False(t, has, "a version must not stand for its module")
+
+	now = now.Add(time.Minute)
+	has, err = c.Has(ctx, "example.com/mod", "v1.0.0")
+	require.NoError(t, err)
+	require.False(t, has)
+	require.Empty(t, c.expiries)
+}
+
+func TestMemoryInvalidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		mod, ver  string
+		remaining []string
+	}{
+		{name: "version", mod: "example.com/mod", ver: "v1.0.0", remaining: []string{"example.com/mod@", "example.com/mod@v1.1.0", "example.com/modx@v1.0.0"}},
+		{name: "module", mod: "example.com/mod", remaining: []string{"example.com/modx@v1.0.0"}},
+		{name: "everything"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := NewMemory(time.Minute).(*memCache)
+			for _, mv := range [][2]string{
+				{"example.com/mod", ""},
+				{"example.com/mod", "v1.0.0"},
+				{"example.com/mod", "v1.1.0"},
+				{"example.com/modx", "v1.0.0"},
+			} {
+				require.NoError(t, c.Add(ctx, mv[0], mv[1]))
+			}
+			require.NoError(t, c.Invalidate(ctx, tc.mod, tc.ver))
+			var remaining []string
+			for k := range c.expiries {
+				remaining = append(remaining, k)
+			}
+			require.ElementsMatch(t, tc.remaining, remaining)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package notfound
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/gomods/athens/pkg/errors"
+)
+
+// redisNamespace prefixes the keys of the cache in redis.
+const redisNamespace = "athens:notfound:"
+
+// NewRedis returns a Cache in redis, so that it can be shared by
+// several Athens instances. Entries expire after ttl.
+// If it cannot connect, it will return an error.
+func NewRedis(endpoint, password string, ttl time.Duration) (Cache, error) {
+	const op errors.Op = "notfound.NewRedis"
+	client := redis.NewClient(&redis.Options{
+		Network:  "tcp",
+		Addr:     endpoint,
+		Password: password,
+	})
+	if _, err := client.Ping().Result(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &redisCache{client: client, ttl: ttl}, nil
+}
+
+type redisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func (c *redisCache) Has(ctx context.Context, mod, ver string) (bool, error) {
+	const op errors.Op = "redisCache.Has"
+	n, err := c.client.WithContext(ctx).Exists(redisNamespace + key(mod, ver)).Result()
+	if err != nil {
+		return false, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return n > 0, nil
+}
+
+func (c *redisCache) Add(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "redisCache.Add"
+	err := c.client.WithContext(ctx).Set(redisNamespace+key(mod, ver), 1, c.ttl).Err()
+	if err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
+
+func (c *redisCache) Invalidate(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "redisCache.Invalidate"
+	client := c.client.WithContext(ctx)
+	if ver != "" {
+		if err := client.Del(redisNamespace + key(mod, ver)).Err(); err != nil {
+			return errors.E(op, err, errors.M(mod), errors.V(ver))
+		}
+		return nil
+	}
+	iter := client.Scan(0, redisNamespace+escapeGlob(prefix(mod, ver))+"*", 100).Iterator()
+	for iter.Next() {
+		if err := client.Del(iter.Val()).Err(); err != nil {
+			return errors.E(op, err, errors.M(mod))
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return errors.E(op, err, errors.M(mod))
+	}
+	return nil
+}
+
+// escapeGlob escapes the characters that are
+// special in the patterns of the redis SCAN command.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,216 @@
+// Package pin keeps the module versions that operators pinned, so that
+// they are kept in storage whatever the retention rules, and are neither
+// deleted nor replaced by the admin API or the scrubber.
+//
+// Pins are kept in a file with a module@version per line. Blank lines
+// and lines starting with # are ignored.
+package pin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/filewatch"
+	"github.com/gomods/athens/pkg/paths"
+)
+
+// Store is the set of pinned module versions of a pin file.
+// It is safe for concurrent use.
+type Store struct {
+	path string
+
+	mu   sync.RWMutex
+	pins map[paths.AllPathParams]bool
+	// file is the pin file as it was last loaded or
+	// written, and is nil if it did not exist.
+	file os.FileInfo
+}
+
+// NewStore loads the pin file at path.
+// A file that does not exist has no pins.
+func NewStore(path string) (*Store, error) {
+	const op errors.Op = "pin.NewStore"
+	s := &Store{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return s, nil
+}
+
+// Pinned reports whether mod@ver is pinned.
+func (s *Store) Pinned(mod, ver string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pins[paths.AllPathParams{Module: mod, Version: ver}]
+}
+
+// List returns the pinned versions, sorted by module and version.
+func (s *Store) List() []paths.AllPathParams {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sorted(s.pins)
+}
+
+// Pin pins mod@ver and writes the pin file.
+// Pinning a pinned version does nothing.
+func (s *Store) Pin(mod, ver string) error {
+	const op errors.Op = "pin.Pin"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := paths.AllPathParams{Module: mod, Version: ver}
+	if s.pins[p] {
+		return nil
+	}
+	pins := s.clone()
+	pins[p] = true
+	if err := s.write(pins); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
+
+// Unpin unpins mod@ver and writes the pin file. It
+// returns an error of KindNotFound if it is not pinned.
+func (s *Store) Unpin(mod, ver string) error {
+	const op errors.Op = "pin.Unpin"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := paths.AllPathParams{Module: mod, Version: ver}
+	if !s.pins[p] {
+		return errors.E(op, "version is not pinned", errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	pins := s.clone()
+	delete(pins, p)
+	if err := s.write(pins); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
+
+// Reload reads the pin file again. If it cannot be read or
+// is invalid, the current pins are kept and an error is returned.
+func (s *Store) Reload() error {
+	const op errors.Op = "pin.Reload"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fi, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.pins, s.file = map[paths.AllPathParams]bool{}, nil
+		return nil
+	}
+	if err != nil {
+		return errors.E(op, err)
+	}
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	pins, err := parse(b)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	s.pins, s.file = pins, fi
+	return nil
+}
+
+// Watch reloads the pin file whenever it changes, which is checked
+// every interval, or whenever a value is received from reload, until
+// ctx is done. A zero interval disables the checks.
+func (s *Store) Watch(ctx context.Context, interval time.Duration, reload <-chan os.Signal) {
+	s.mu.RLock()
+	file := s.file
+	s.mu.RUnlock()
+	filewatch.Watch(ctx, s.path, file, interval, reload, s.Reload)
+}
+
+func (s *Store) clone() map[paths.AllPathParams]bool {
+	pins := make(map[paths.AllPathParams]bool, len(s.pins)+1)
+	for p := range s.pins {
+		pins[p] = true
+	}
+	return pins
+}
+
+// write replaces the pin file with pins, through a temporary
+// file so that the file is never seen half written, and then
+// makes pins the current ones. s.mu must be held.
+func (s *Store) write(pins map[paths.AllPathParams]bool) error {
+	const op errors.Op = "pin.write"
+	buf := &bytes.Buffer{}
+	for _, p := range sorted(pins) {
+		fmt.Fprintf(buf, "%s@%s\n", p.Module, p.Version)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.E(op, err)
+	}
+	mode := os.FileMode(0644)
+	if s.file != nil {
+		mode = s.file.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return errors.E(op, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errors.E(op, err)
+	}
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	s.pins, s.file = pins, fi
+	return nil
+}
+
+func sorted(pins map[paths.AllPathParams]bool) []paths.AllPathParams {
+	list := make([]paths.AllPathParams, 0, len(pins))
+	for p := range pins {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Module != list[j].Module {
+			return list[i].Module < list[j].Module
+		}
+		return list[i].Version < list[j].Version
+	})
+	return list
+}
+
+// parse parses the content of a pin file.
+func parse(b []byte) (map[paths.AllPathParams]bool, error) {
+	pins := map[paths.AllPathParams]bool{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, "@")
+		if i <= 0 || i == len(line)-1 || strings.ContainsAny(line, " \t") {
+			return nil, fmt.Errorf("line %d: %q is not a module@version", n, line)
+		}
+		pins[paths.AllPathParams{Module: line[:i], Version: line[i+1:]}] = true
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
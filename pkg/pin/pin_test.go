@@ -0,0 +1,70 @@
+package pin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athens-pin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pins")
+
+	s, err := NewStore(path)
+	require.NoError(t, err, "a missing pin file has no pins")
+	require.Empty(t, s.List())
+
+	require.NoError(t, s.Pin("github.com/pkg/errors", "v0.8.1"))
+	require.NoError(t, s.Pin("github.com/Azure/azure-sdk", "v1.0.0"))
+	require.NoError(t, s.Pin("github.com/pkg/errors", "v0.8.1"))
+	require.True(t, s.Pinned("github.com/pkg/errors", "v0.8.1"))
+	require.False(t, s.Pinned("github.com/pkg/errors", "v0.9.0"))
+	require.Equal(t, []paths.AllPathParams{
+		{Module: "github.com/Azure/azure-sdk", Version: "v1.0.0"},
+		{Module: "github.com/pkg/errors", Version: "v0.8.1"},
+	}, s.List())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "github.com/Azure/azure-sdk@v1.0.0\ngithub.com/pkg/errors@v0.8.1\n", string(b))
+
+	require.NoError(t, s.Unpin("github.com/Azure/azure-sdk", "v1.0.0"))
+	err = s.Unpin("github.com/Azure/azure-sdk", "v1.0.0")
+	require.Equal(t, errors.KindNotFound, errors.Kind(err))
+
+	reopened, err := NewStore(path)
+	require.NoError(t, err)
+	require.Equal(t, s.List(), reopened.List())
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "temporary files must be removed")
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athens-pin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pins")
+	require.NoError(t, ioutil.WriteFile(path, []byte("# pinned for the release\ngithub.com/pkg/errors@v0.8.1\n\n"), 0644))
+
+	s, err := NewStore(path)
+	require.NoError(t, err)
+	require.True(t, s.Pinned("github.com/pkg/errors", "v0.8.1"))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("github.com/pkg/errors\n"), 0644))
+	require.Error(t, s.Reload())
+	require.True(t, s.Pinned("github.com/pkg/errors", "v0.8.1"), "an invalid file must keep the current pins")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("golang.org/x/mod@v0.2.0\n"), 0644))
+	require.NoError(t, s.Reload())
+	require.False(t, s.Pinned("github.com/pkg/errors", "v0.8.1"))
+	require.True(t, s.Pinned("golang.org/x/mod", "v0.2.0"))
+}
@@ -0,0 +1,4 @@
+// Package prewarm stashes a list of module versions read from a go.sum,
+// a go.mod or a plain module@version list, so that a new Athens can be
+// seeded with the modules a build needs before clients are pointed at it.
+package prewarm
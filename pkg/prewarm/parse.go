@@ -0,0 +1,149 @@
+package prewarm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Format is the format of a list of module versions.
+type Format string
+
+const (
+	// Auto detects the format from the contents of the list.
+	Auto Format = ""
+	// GoSum is the format of a go.sum file.
+	GoSum Format = "gosum"
+	// GoMod is the format of a go.mod file. Every required
+	// module is imported, along with the replacements
+	// that point to another module version.
+	GoMod Format = "gomod"
+	// List is one module@version per line. Empty lines
+	// and lines starting with # are ignored.
+	List Format = "list"
+)
+
+// Entry is a module version to import.
+type Entry struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+// Parse returns the module versions in data, without duplicates
+// and in the order they first appear.
+func Parse(format Format, data []byte) ([]Entry, error) {
+	const op errors.Op = "prewarm.Parse"
+	if format == Auto {
+		format = detect(data)
+	}
+	var (
+		entries []Entry
+		err     error
+	)
+	switch format {
+	case GoSum:
+		entries, err = parseGoSum(data)
+	case GoMod:
+		entries, err = parseGoMod(data)
+	case List:
+		entries, err = parseList(data)
+	default:
+		return nil, errors.E(op, fmt.Sprintf("unknown format %q", format), errors.KindBadRequest)
+	}
+	if err != nil {
+		return nil, errors.E(op, err, errors.KindBadRequest)
+	}
+	return dedupe(entries), nil
+}
+
+// detect guesses the format of data: a go.sum has a hash as the
+// third field of every line, and a go.mod has a module directive.
+func detect(data []byte) Format {
+	lines := 0
+	sums := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) == 0 {
+			continue
+		}
+		lines++
+		if len(f) == 3 && strings.HasPrefix(f[2], "h1:") {
+			sums++
+		}
+	}
+	if lines > 0 && lines == sums {
+		return GoSum
+	}
+	if f, err := modfile.ParseLax("go.mod", data, nil); err == nil && f.Module != nil {
+		return GoMod
+	}
+	return List
+}
+
+func parseGoSum(data []byte) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		f := strings.Fields(sc.Text())
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			return nil, fmt.Errorf("line %d: malformed go.sum line %q", n, sc.Text())
+		}
+		entries = append(entries, Entry{Module: f[0], Version: strings.TrimSuffix(f[1], "/go.mod")})
+	}
+	return entries, sc.Err()
+}
+
+func parseGoMod(data []byte) ([]Entry, error) {
+	f, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, r := range f.Require {
+		entries = append(entries, Entry{Module: r.Mod.Path, Version: r.Mod.Version})
+	}
+	for _, r := range f.Replace {
+		// replacements without a version point to a directory
+		if r.New.Version != "" {
+			entries = append(entries, Entry{Module: r.New.Path, Version: r.New.Version})
+		}
+	}
+	return entries, nil
+}
+
+func parseList(data []byte) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, "@")
+		if i <= 0 || i == len(line)-1 {
+			return nil, fmt.Errorf("line %d: %q is not of the form module@version", n, line)
+		}
+		entries = append(entries, Entry{Module: line[:i], Version: line[i+1:]})
+	}
+	return entries, sc.Err()
+}
+
+func dedupe(entries []Entry) []Entry {
+	seen := make(map[Entry]bool, len(entries))
+	res := []Entry{}
+	for _, e := range entries {
+		if !seen[e] {
+			seen[e] = true
+			res = append(res, e)
+		}
+	}
+	return res
+}
@@ -0,0 +1,106 @@
+package prewarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testGoSum = `github.com/pkg/errors v0.8.1 h1:iURUrRGxPUNPdy5/HRSm+Yj6okJ6UtLINN0Q9M4+h3I=
+github.com/pkg/errors v0.8.1/go.mod h1:bwawxfHBFNV+L2hUp1rHADufV3IMtnDRdf1r5NINEl0=
+golang.org/x/mod v0.2.0/go.mod h1:s0Qsj1ACt9ePp/hMypM3fl4fZqREWJwdYDEqhRiZZUA=
+`
+
+const testGoMod = `module example.com/app
+
+go 1.14
+
+require (
+	github.com/pkg/errors v0.8.1
+	golang.org/x/mod v0.2.0 // indirect
+)
+
+replace golang.org/x/mod => github.com/fork/mod v0.2.1
+
+replace example.com/local => ../local
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		data    string
+		want    []Entry
+		wantErr bool
+	}{
+		{
+			name:   "go.sum",
+			format: GoSum,
+			data:   testGoSum,
+			want:   []Entry{{"github.com/pkg/errors", "v0.8.1"}, {"golang.org/x/mod", "v0.2.0"}},
+		},
+		{
+			name:   "go.mod",
+			format: GoMod,
+			data:   testGoMod,
+			want: []Entry{
+				{"github.com/pkg/errors", "v0.8.1"},
+				{"golang.org/x/mod", "v0.2.0"},
+				{"github.com/fork/mod", "v0.2.1"},
+			},
+		},
+		{
+			name:   "list",
+			format: List,
+			data:   "# seed\ngithub.com/pkg/errors@v0.8.1\n\n  golang.org/x/mod@v0.2.0  \ngithub.com/pkg/errors@v0.8.1\n",
+			want:   []Entry{{"github.com/pkg/errors", "v0.8.1"}, {"golang.org/x/mod", "v0.2.0"}},
+		},
+		{
+			name: "detect go.sum",
+			data: testGoSum,
+			want: []Entry{{"github.com/pkg/errors", "v0.8.1"}, {"golang.org/x/mod", "v0.2.0"}},
+		},
+		{
+			name: "detect go.mod",
+			data: testGoMod,
+			want: []Entry{
+				{"github.com/pkg/errors", "v0.8.1"},
+				{"golang.org/x/mod", "v0.2.0"},
+				{"github.com/fork/mod", "v0.2.1"},
+			},
+		},
+		{
+			name: "detect list",
+			data: "github.com/pkg/errors@v0.8.1",
+			want: []Entry{{"github.com/pkg/errors", "v0.8.1"}},
+		},
+		{
+			name:    "malformed list",
+			format:  List,
+			data:    "github.com/pkg/errors v0.8.1",
+			wantErr: true,
+		},
+		{
+			name:    "malformed go.sum",
+			format:  GoSum,
+			data:    "github.com/pkg/errors v0.8.1",
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			format:  "gopkg",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := Parse(tc.format, []byte(tc.data))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, entries)
+		})
+	}
+}
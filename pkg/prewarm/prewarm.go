@@ -0,0 +1,104 @@
+package prewarm
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/stash"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// Failure is an entry that could not be imported.
+type Failure struct {
+	Entry
+	Error string `json:"error"`
+}
+
+// Report is the outcome of an import.
+type Report struct {
+	Total   int       `json:"total"`
+	Stashed int       `json:"stashed"`
+	Skipped int       `json:"skipped"`
+	Failed  []Failure `json:"failed"`
+}
+
+// Importer stashes lists of module versions.
+type Importer struct {
+	stasher stash.Stasher
+	checker storage.Checker
+	workers int
+}
+
+// New returns an Importer that stashes at most workers
+// versions at a time through st. Versions that checker
+// reports as already stored are skipped.
+func New(st stash.Stasher, checker storage.Checker, workers int) *Importer {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Importer{stasher: st, checker: checker, workers: workers}
+}
+
+// Import stashes every entry and reports which ones failed.
+// An entry failing does not stop the others from being imported.
+func (i *Importer) Import(ctx context.Context, entries []Entry) *Report {
+	const op errors.Op = "prewarm.Import"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, i.workers)
+		rep = &Report{Total: len(entries), Failed: []Failure{}}
+	)
+	for _, e := range entries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(e Entry) {
+			defer func() { <-sem; wg.Done() }()
+			stashed, err := i.importOne(ctx, e)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				rep.Failed = append(rep.Failed, Failure{Entry: e, Error: err.Error()})
+			case stashed:
+				rep.Stashed++
+			default:
+				rep.Skipped++
+			}
+		}(e)
+	}
+	wg.Wait()
+	sort.Slice(rep.Failed, func(a, b int) bool {
+		if rep.Failed[a].Module != rep.Failed[b].Module {
+			return rep.Failed[a].Module < rep.Failed[b].Module
+		}
+		return rep.Failed[a].Version < rep.Failed[b].Version
+	})
+	return rep
+}
+
+// importOne stashes e unless it is already stored,
+// and reports whether it was stashed.
+func (i *Importer) importOne(ctx context.Context, e Entry) (bool, error) {
+	const op errors.Op = "prewarm.importOne"
+	if err := ctx.Err(); err != nil {
+		return false, errors.E(op, err)
+	}
+	exists, err := i.checker.Exists(ctx, e.Module, e.Version)
+	if err != nil {
+		return false, errors.E(op, err, errors.M(e.Module), errors.V(e.Version))
+	}
+	if exists {
+		return false, nil
+	}
+	if _, err := i.stasher.Stash(ctx, e.Module, e.Version); err != nil {
+		return false, errors.E(op, err, errors.M(e.Module), errors.V(e.Version))
+	}
+	return true, nil
+}
@@ -0,0 +1,68 @@
+package prewarm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockStasher struct {
+	mu      sync.Mutex
+	running int
+	max     int
+	stashed []Entry
+}
+
+func (m *mockStasher) Stash(ctx context.Context, mod, ver string) (string, error) {
+	m.mu.Lock()
+	m.running++
+	if m.running > m.max {
+		m.max = m.running
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.running--
+		m.mu.Unlock()
+	}()
+	if mod == "example.com/broken" {
+		return "", fmt.Errorf("%s@%s does not exist", mod, ver)
+	}
+	m.mu.Lock()
+	m.stashed = append(m.stashed, Entry{mod, ver})
+	m.mu.Unlock()
+	return ver, nil
+}
+
+type mockChecker map[Entry]bool
+
+func (m mockChecker) Exists(ctx context.Context, mod, ver string) (bool, error) {
+	return m[Entry{mod, ver}], nil
+}
+
+func TestImport(t *testing.T) {
+	entries := []Entry{
+		{"example.com/a", "v1.0.0"},
+		{"example.com/b", "v1.0.0"},
+		{"example.com/broken", "v1.0.0"},
+		{"example.com/stored", "v1.0.0"},
+	}
+	for i := 0; i < 20; i++ {
+		entries = append(entries, Entry{"example.com/c", fmt.Sprintf("v1.%d.0", i)})
+	}
+	st := &mockStasher{}
+	checker := mockChecker{{"example.com/stored", "v1.0.0"}: true}
+	rep := New(st, checker, 3).Import(context.Background(), entries)
+
+	require.Equal(t, len(entries), rep.Total)
+	require.Equal(t, len(entries)-2, rep.Stashed)
+	require.Equal(t, 1, rep.Skipped)
+	require.Len(t, rep.Failed, 1)
+	require.Equal(t, Entry{"example.com/broken", "v1.0.0"}, rep.Failed[0].Entry)
+	require.Contains(t, rep.Failed[0].Error, "does not exist")
+	require.Len(t, st.stashed, len(entries)-2)
+	require.LessOrEqual(t, st.max, 3, "no more than 3 stashes may run at once")
+}
@@ -0,0 +1,5 @@
+// Package retention provides a Retainer that periodically deletes the
+// module versions in a storage backend that match retention rules,
+// such as versions that have not been downloaded for a while, which it
+// learns from the download counts of a usage.Store.
+package retention
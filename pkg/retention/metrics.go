@@ -0,0 +1,35 @@
+package retention
+
+import (
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+var (
+	mChecked = stats.Int64("athens/retention/checked", "Number of module versions checked against the retention rules", stats.UnitDimensionless)
+	mDeleted = stats.Int64("athens/retention/deleted", "Number of module versions deleted by the retention rules", stats.UnitDimensionless)
+	mErrors  = stats.Int64("athens/retention/errors", "Number of module versions that could not be deleted", stats.UnitDimensionless)
+)
+
+// Views are the stats views of the retainer,
+// to be registered with the stats exporter.
+var Views = []*view.View{
+	{
+		Name:        "athens/retention/checked",
+		Description: mChecked.Description(),
+		Measure:     mChecked,
+		Aggregation: view.Sum(),
+	},
+	{
+		Name:        "athens/retention/deleted",
+		Description: mDeleted.Description(),
+		Measure:     mDeleted,
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "athens/retention/errors",
+		Description: mErrors.Description(),
+		Measure:     mErrors,
+		Aggregation: view.Count(),
+	},
+}
@@ -0,0 +1,314 @@
+package retention
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/index"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/usage"
+	"go.opencensus.io/stats"
+)
+
+const defaultPageSize = 100
+
+// Opts specifies the options of a Retainer.
+type Opts struct {
+	// Storage is the backend to delete versions from.
+	// It must implement storage.Cataloger.
+	Storage storage.Backend
+	// Indexer, if not nil, has the deleted versions removed too.
+	Indexer index.Indexer
+	// Usage knows when versions were last downloaded.
+	// It is required by rules with a MaxIdle. Versions
+	// it has no downloads of are treated as if they were
+	// last downloaded when the Retainer was created, so
+	// that nothing looks idle just because it was never
+	// counted.
+	Usage usage.Store
+	// Events, if not nil, receives an event
+	// for every deleted version.
+	Events events.Emitter
+	// Pins, if not nil, has the versions that
+	// are kept whatever the rules select.
+	Pins *pin.Store
+	// Rules select the versions to delete.
+	Rules []Rule
+	// DryRun reports the versions that the periodic
+	// runs would delete without deleting them.
+	DryRun bool
+	// PageSize is the number of versions to read from the
+	// Cataloger at once.
+	PageSize int
+}
+
+// Entry describes a module version that was deleted,
+// or could not be, and why.
+type Entry struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+}
+
+// Result is the outcome of a single retention run. In a dry
+// run, Deleted lists the versions that would have been deleted.
+type Result struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	DryRun   bool      `json:"dryRun"`
+	Checked  int       `json:"checked"`
+	// Pinned is the number of versions that the
+	// rules selected but were kept as they are pinned.
+	Pinned  int     `json:"pinned"`
+	Deleted []Entry `json:"deleted"`
+	Errors  []Entry `json:"errors"`
+}
+
+// Status is the state of a Retainer.
+type Status struct {
+	Running bool    `json:"running"`
+	Last    *Result `json:"last,omitempty"`
+}
+
+// Retainer walks a storage backend and deletes the module
+// versions that its rules select, so that the storage does
+// not grow forever.
+type Retainer struct {
+	strg      storage.Backend
+	cataloger storage.Cataloger
+	indexer   index.Indexer
+	usage     usage.Store
+	since     time.Time
+	events    events.Emitter
+	pins      *pin.Store
+	rules     []Rule
+	dryRun    bool
+	pageSize  int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	last    *Result
+}
+
+// New returns a Retainer for opts.Storage.
+func New(opts *Opts) (*Retainer, error) {
+	const op errors.Op = "retention.New"
+	cataloger, ok := opts.Storage.(storage.Cataloger)
+	if !ok {
+		return nil, errors.E(op, "storage does not implement a catalog", errors.KindNotImplemented)
+	}
+	for _, r := range opts.Rules {
+		if r.MaxIdle > 0 && opts.Usage == nil {
+			return nil, errors.E(op, "a usage store is required to delete idle versions")
+		}
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	return &Retainer{
+		strg:      opts.Storage,
+		cataloger: cataloger,
+		indexer:   opts.Indexer,
+		usage:     opts.Usage,
+		since:     time.Now(),
+		events:    opts.Events,
+		pins:      opts.Pins,
+		rules:     opts.Rules,
+		dryRun:    opts.DryRun,
+		pageSize:  pageSize,
+		now:       time.Now,
+	}, nil
+}
+
+// Start applies the rules every interval until ctx is done,
+// in dry run mode if the Retainer was created with DryRun.
+// Errors are logged using the entry in ctx.
+func (r *Retainer) Start(ctx context.Context, interval time.Duration) {
+	lggr := log.EntryFromContext(ctx)
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				res, err := r.Run(ctx, r.dryRun)
+				if err != nil {
+					lggr.SystemErr(err)
+					continue
+				}
+				if len(res.Deleted) > 0 {
+					if res.DryRun {
+						lggr.Infof("retention would delete %d module versions out of %d", len(res.Deleted), res.Checked)
+					} else {
+						lggr.Infof("retention deleted %d module versions out of %d", len(res.Deleted), res.Checked)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Status returns whether a run is in progress
+// and the result of the last one.
+func (r *Retainer) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Status{Running: r.running, Last: r.last}
+}
+
+// Run applies the rules to the whole storage once. If dryRun
+// is true, nothing is deleted. Only one run can be in progress
+// at a time.
+func (r *Retainer) Run(ctx context.Context, dryRun bool) (*Result, error) {
+	const op errors.Op = "retention.Run"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil, errors.E(op, "a retention run is already in progress", errors.KindAlreadyExists)
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	res := &Result{Started: r.now(), DryRun: dryRun, Deleted: []Entry{}, Errors: []Entry{}}
+	err := r.run(ctx, res)
+	res.Finished = r.now()
+
+	r.mu.Lock()
+	r.running = false
+	if err == nil {
+		r.last = res
+	}
+	r.mu.Unlock()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return res, nil
+}
+
+func (r *Retainer) run(ctx context.Context, res *Result) error {
+	const op errors.Op = "retention.run"
+	// rules such as KeepNewest need all the versions
+	// of a module, which may span several pages.
+	versions, err := r.catalog(ctx)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	mods := make([]string, 0, len(versions))
+	for mod := range versions {
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+
+	now := r.now()
+	for _, mod := range mods {
+		res.Checked += len(versions[mod])
+		stats.Record(ctx, mChecked.M(int64(len(versions[mod]))))
+		rule, ok := firstMatch(r.rules, mod)
+		if !ok {
+			continue
+		}
+		lastAccess := func(mod, ver string) time.Time { return time.Time{} }
+		if rule.MaxIdle > 0 {
+			last, err := r.lastAccess(ctx, mod)
+			if err != nil {
+				return errors.E(op, err)
+			}
+			lastAccess = func(mod, ver string) time.Time {
+				if t, ok := last[ver]; ok {
+					return t
+				}
+				return r.since
+			}
+		}
+		expired := rule.expired(mod, versions[mod], lastAccess, now)
+		for _, ver := range versions[mod] {
+			reason, ok := expired[ver]
+			if !ok {
+				continue
+			}
+			if r.pins != nil && r.pins.Pinned(mod, ver) {
+				res.Pinned++
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return errors.E(op, err)
+			}
+			if !res.DryRun {
+				if err := r.delete(ctx, mod, ver, reason); err != nil {
+					res.Errors = append(res.Errors, Entry{Module: mod, Version: ver, Reason: err.Error()})
+					stats.Record(ctx, mErrors.M(1))
+					continue
+				}
+				stats.Record(ctx, mDeleted.M(1))
+			}
+			res.Deleted = append(res.Deleted, Entry{Module: mod, Version: ver, Reason: reason})
+		}
+	}
+	return nil
+}
+
+// catalog returns the versions of every module in storage.
+func (r *Retainer) catalog(ctx context.Context) (map[string][]string, error) {
+	const op errors.Op = "retention.catalog"
+	versions := map[string][]string{}
+	token := ""
+	for {
+		page, next, err := r.cataloger.Catalog(ctx, token, r.pageSize)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, p := range page {
+			versions[p.Module] = append(versions[p.Module], p.Version)
+		}
+		if next == "" {
+			return versions, nil
+		}
+		token = next
+	}
+}
+
+// lastAccess returns when the versions of mod
+// that have downloads were last downloaded.
+func (r *Retainer) lastAccess(ctx context.Context, mod string) (map[string]time.Time, error) {
+	const op errors.Op = "retention.lastAccess"
+	counts, err := r.usage.Top(ctx, &usage.Query{Module: mod, Limit: math.MaxInt32})
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod))
+	}
+	last := make(map[string]time.Time, len(counts))
+	for _, c := range counts {
+		last[c.Version] = c.LastAccess
+	}
+	return last, nil
+}
+
+// delete deletes a version from storage and the index.
+func (r *Retainer) delete(ctx context.Context, mod, ver, reason string) error {
+	const op errors.Op = "retention.delete"
+	if err := r.strg.Delete(ctx, mod, ver); err != nil && !errors.IsNotFoundErr(err) {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if r.indexer != nil {
+		if err := r.indexer.Delete(ctx, mod, ver); err != nil && !errors.IsNotFoundErr(err) {
+			return errors.E(op, err, errors.M(mod), errors.V(ver))
+		}
+	}
+	if r.events != nil {
+		r.events.Emit(ctx, &events.Event{Type: events.Deleted, Module: mod, Version: ver, Reason: "retention: " + reason})
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package retention
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	indexmem "github.com/gomods/athens/pkg/index/mem"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/gomods/athens/pkg/usage"
+	usagemem "github.com/gomods/athens/pkg/usage/mem"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	modA   = "example.com/a"
+	modB   = "example.com/b"
+	pseudo = "v0.0.0-20190101000000-abcdefabcdef"
+)
+
+var (
+	now     = time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	storedA = []string{"v1.0.0", "v1.1.0", "v1.2.0", pseudo}
+	storedB = []string{"v0.1.0", "v0.2.0"}
+)
+
+func newStorage(t *testing.T) storage.Backend {
+	t.Helper()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	ctx := context.Background()
+	for mod, versions := range map[string][]string{modA: storedA, modB: storedB} {
+		for _, ver := range versions {
+			require.NoError(t, s.Save(ctx, mod, ver, []byte("module "+mod), strings.NewReader("zip"), []byte("{}")))
+		}
+	}
+	return s
+}
+
+func newUsage(t *testing.T) usage.Store {
+	t.Helper()
+	u := usagemem.New()
+	download := func(mod, ver string, at time.Time) *usage.Increment {
+		return &usage.Increment{Module: mod, Version: ver, Hour: at.Truncate(time.Hour), Zips: 1, LastAccess: at}
+	}
+	require.NoError(t, u.Add(context.Background(), []*usage.Increment{
+		download(modA, "v1.0.0", now.Add(-time.Hour)),
+		download(modA, "v1.1.0", now.Add(-100*24*time.Hour)),
+		download(modB, "v0.1.0", now.Add(-time.Hour)),
+	}))
+	return u
+}
+
+func TestRetention(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		name          string
+		rules         []Rule
+		wantRemaining map[string][]string
+	}{
+		{
+			name:          "no rules",
+			wantRemaining: map[string][]string{modA: storedA, modB: storedB},
+		},
+		{
+			name:          "max idle",
+			rules:         []Rule{{MaxIdle: 30 * day}},
+			wantRemaining: map[string][]string{modA: {"v1.0.0"}, modB: {"v0.1.0"}},
+		},
+		{
+			name:          "keep newest",
+			rules:         []Rule{{KeepNewest: 2}},
+			wantRemaining: map[string][]string{modA: {"v1.1.0", "v1.2.0"}, modB: storedB},
+		},
+		{
+			name:          "max pseudo age",
+			rules:         []Rule{{MaxPseudoAge: 365 * day}},
+			wantRemaining: map[string][]string{modA: {"v1.0.0", "v1.1.0", "v1.2.0"}, modB: storedB},
+		},
+		{
+			name:          "recent pseudo-versions are kept",
+			rules:         []Rule{{MaxPseudoAge: 1000 * day}},
+			wantRemaining: map[string][]string{modA: storedA, modB: storedB},
+		},
+		{
+			name:          "patterns",
+			rules:         []Rule{{Patterns: []string{"example.com/b"}, KeepNewest: 1}},
+			wantRemaining: map[string][]string{modA: storedA, modB: {"v0.2.0"}},
+		},
+		{
+			name:          "first matching rule applies",
+			rules:         []Rule{{Patterns: []string{"example.com/a"}}, {KeepNewest: 1}},
+			wantRemaining: map[string][]string{modA: storedA, modB: {"v0.2.0"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStorage(t)
+			indexer := indexmem.New()
+			require.NoError(t, indexer.Index(ctx, modA, "v1.1.0"))
+			r, err := New(&Opts{Storage: s, Indexer: indexer, Usage: newUsage(t), Rules: tc.rules})
+			require.NoError(t, err)
+			r.now = func() time.Time { return now }
+			r.since = now.Add(-90 * 24 * time.Hour)
+
+			dry, err := r.Run(ctx, true)
+			require.NoError(t, err)
+			require.True(t, dry.DryRun)
+			require.Equal(t, len(storedA)+len(storedB), dry.Checked)
+			for mod, versions := range map[string][]string{modA: storedA, modB: storedB} {
+				remaining, err := s.List(ctx, mod)
+				require.NoError(t, err)
+				require.ElementsMatch(t, versions, remaining, "a dry run must not delete anything")
+			}
+
+			res, err := r.Run(ctx, false)
+			require.NoError(t, err)
+			require.Empty(t, res.Errors)
+			require.Equal(t, dry.Deleted, res.Deleted)
+			for mod, versions := range tc.wantRemaining {
+				remaining, err := s.List(ctx, mod)
+				require.NoError(t, err)
+				require.ElementsMatch(t, versions, remaining, mod)
+			}
+			for _, e := range res.Deleted {
+				require.NotEmpty(t, e.Reason)
+			}
+			require.Equal(t, res, r.Status().Last)
+		})
+	}
+}
+
+func TestNewRequiresUsage(t *testing.T) {
+	_, err := New(&Opts{Storage: newStorage(t), Rules: []Rule{{MaxIdle: time.Hour}}})
+	require.Error(t, err)
+}
+
+func TestPseudoVersionTime(t *testing.T) {
+	for ver, want := range map[string]string{
+		"v0.0.0-20190101000000-abcdefabcdef":                    "2019-01-01T00:00:00Z",
+		"v1.2.4-0.20191109021931-daa7c04131f5":                  "2019-11-09T02:19:31Z",
+		"v2.0.1-pre.0.20200102030405-abcdefabcdef+incompatible": "2020-01-02T03:04:05Z",
+		"v1.0.0":        "",
+		"v1.0.0-beta.1": "",
+	} {
+		got, ok := pseudoVersionTime(ver)
+		if want == "" {
+			require.False(t, ok, ver)
+			continue
+		}
+		require.True(t, ok, ver)
+		require.Equal(t, want, got.Format(time.RFC3339), ver)
+	}
+}
+
+func TestRetentionKeepsPins(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "athens-pin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pins, err := pin.NewStore(filepath.Join(dir, "pins"))
+	require.NoError(t, err)
+	require.NoError(t, pins.Pin(modB, "v0.1.0"))
+
+	s := newStorage(t)
+	r, err := New(&Opts{Storage: s, Pins: pins, Rules: []Rule{{Patterns: []string{modB}, KeepNewest: 1}}})
+	require.NoError(t, err)
+	res, err := r.Run(ctx, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Pinned)
+	require.Empty(t, res.Deleted)
+	remaining, err := s.List(ctx, modB)
+	require.NoError(t, err)
+	require.ElementsMatch(t, storedB, remaining)
+}
+
+func TestRetentionIncompatible(t *testing.T) {
+	const modC = "example.com/c"
+	ctx := context.Background()
+	s := newStorage(t)
+	for _, ver := range []string{"v2.0.0+incompatible", "v3.0.0+incompatible"} {
+		require.NoError(t, s.Save(ctx, modC, ver, []byte("module "+modC), strings.NewReader("zip"), []byte("{}")))
+	}
+	u := newUsage(t)
+	require.NoError(t, u.Add(ctx, []*usage.Increment{{
+		Module:     modC,
+		Version:    "v2.0.0+incompatible",
+		Hour:       now.Add(-time.Hour).Truncate(time.Hour),
+		Zips:       1,
+		LastAccess: now.Add(-time.Hour),
+	}}))
+	r, err := New(&Opts{Storage: s, Usage: u, Rules: []Rule{{Patterns: []string{modC}, MaxIdle: 30 * 24 * time.Hour}}})
+	require.NoError(t, err)
+	r.now = func() time.Time { return now }
+	r.since = now.Add(-90 * 24 * time.Hour)
+
+	res, err := r.Run(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, res.Deleted, 1)
+	remaining, err := s.List(ctx, modC)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v2.0.0+incompatible"}, remaining, "recently downloaded +incompatible versions must be kept")
+}
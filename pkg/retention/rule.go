@@ -0,0 +1,111 @@
+package retention
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/paths"
+	"golang.org/x/mod/semver"
+)
+
+// Rule selects the module versions to delete. A version is deleted
+// if any condition of the first rule that matches its module is met.
+// A rule with no conditions keeps every version of the modules it
+// matches, which lets a rule protect some modules from the ones
+// after it.
+type Rule struct {
+	// Patterns are the module path patterns, in the GONOSUMDB
+	// format, that the rule applies to. A rule with no
+	// patterns applies to every module.
+	Patterns []string
+	// MaxIdle, if not zero, deletes the versions that
+	// were not downloaded for longer than MaxIdle.
+	MaxIdle time.Duration
+	// KeepNewest, if not zero, deletes all but the
+	// newest KeepNewest versions of each module.
+	KeepNewest int
+	// MaxPseudoAge, if not zero, deletes the pseudo-versions
+	// of commits older than MaxPseudoAge.
+	MaxPseudoAge time.Duration
+}
+
+func (r Rule) matches(mod string) bool {
+	if len(r.Patterns) == 0 {
+		return true
+	}
+	for _, p := range r.Patterns {
+		if paths.MatchesPattern(p, mod) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstMatch returns the first rule that applies to mod.
+func firstMatch(rules []Rule, mod string) (Rule, bool) {
+	for _, r := range rules {
+		if r.matches(mod) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// expired returns the versions of mod that r deletes, with the
+// reason why, given when each version was last downloaded.
+func (r Rule) expired(mod string, versions []string, lastAccess func(mod, ver string) time.Time, now time.Time) map[string]string {
+	sorted := append([]string(nil), versions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return semver.Compare(sorted[i], sorted[j]) > 0
+	})
+	expired := map[string]string{}
+	for i, ver := range sorted {
+		if reason := r.reason(mod, ver, i, lastAccess, now); reason != "" {
+			expired[ver] = reason
+		}
+	}
+	return expired
+}
+
+// reason returns why r deletes ver, the newest but rank
+// version of mod, or the empty string if it does not.
+func (r Rule) reason(mod, ver string, rank int, lastAccess func(mod, ver string) time.Time, now time.Time) string {
+	if r.KeepNewest > 0 && rank >= r.KeepNewest {
+		return fmt.Sprintf("not one of the %d newest versions", r.KeepNewest)
+	}
+	if r.MaxIdle > 0 {
+		if last := lastAccess(mod, ver); now.Sub(last) > r.MaxIdle {
+			return fmt.Sprintf("not downloaded since %s", last.UTC().Format(time.RFC3339))
+		}
+	}
+	if r.MaxPseudoAge > 0 {
+		if t, ok := pseudoVersionTime(ver); ok && now.Sub(t) > r.MaxPseudoAge {
+			return fmt.Sprintf("pseudo-version of a commit from %s", t.Format(time.RFC3339))
+		}
+	}
+	return ""
+}
+
+// copied from go cmd https://github.com/golang/go/blob/master/src/cmd/go/internal/modfetch/pseudo.go#L93
+var pseudoVersionRE = regexp.MustCompile(`^v[0-9]+\.(0\.0-|\d+\.\d+-([^+]*\.)?0\.)\d{14}-[A-Za-z0-9]+(\+incompatible)?$`)
+
+// pseudoVersionTime returns the commit time of a pseudo-version.
+func pseudoVersionTime(ver string) (time.Time, bool) {
+	if strings.Count(ver, "-") < 2 || !pseudoVersionRE.MatchString(ver) {
+		return time.Time{}, false
+	}
+	// the timestamp is the last dot separated
+	// field before the commit hash.
+	ver = strings.TrimSuffix(ver, "+incompatible")
+	fields := strings.Split(ver, "-")
+	ts := fields[len(fields)-2]
+	ts = ts[strings.LastIndex(ts, ".")+1:]
+	t, err := time.Parse("20060102150405", ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
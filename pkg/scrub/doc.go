@@ -0,0 +1,4 @@
+// Package scrub provides a Scrubber that periodically re-hashes the
+// module versions in a storage backend, and reports, quarantines or
+// deletes the ones that no longer match their go.sum hashes.
+package scrub
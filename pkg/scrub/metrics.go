@@ -0,0 +1,35 @@
+package scrub
+
+import (
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+)
+
+var (
+	mChecked = stats.Int64("athens/scrub/checked", "Number of module versions scrubbed", stats.UnitDimensionless)
+	mCorrupt = stats.Int64("athens/scrub/corrupt", "Number of module versions found corrupt", stats.UnitDimensionless)
+	mErrors  = stats.Int64("athens/scrub/errors", "Number of module versions that could not be scrubbed", stats.UnitDimensionless)
+)
+
+// Views are the stats views of the scrubber,
+// to be registered with the stats exporter.
+var Views = []*view.View{
+	{
+		Name:        "athens/scrub/checked",
+		Description: mChecked.Description(),
+		Measure:     mChecked,
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "athens/scrub/corrupt",
+		Description: mCorrupt.Description(),
+		Measure:     mCorrupt,
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "athens/scrub/errors",
+		Description: mErrors.Description(),
+		Measure:     mErrors,
+		Aggregation: view.Count(),
+	},
+}
@@ -0,0 +1,371 @@
+package scrub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+	"go.opencensus.io/stats"
+)
+
+// Action is what the Scrubber does with a
+// module version whose hashes do not match.
+type Action string
+
+const (
+	// Report only lists corrupt versions in the report.
+	Report Action = "report"
+	// Quarantine copies corrupt versions to the quarantine
+	// storage before deleting them.
+	Quarantine Action = "quarantine"
+	// Delete deletes corrupt versions, so that they are
+	// fetched again the next time they are requested.
+	Delete Action = "delete"
+)
+
+const defaultPageSize = 100
+
+// Opts specifies the options of a Scrubber.
+type Opts struct {
+	// Storage is the backend to scrub. It must
+	// implement storage.Cataloger.
+	Storage storage.Backend
+	// Verifier, if not nil, is used to check the versions
+	// that have no go.sum lines recorded in Storage.
+	Verifier *checksum.Verifier
+	// Action is what to do with corrupt versions.
+	Action Action
+	// Quarantine receives the corrupt versions
+	// when Action is Quarantine.
+	Quarantine storage.Saver
+	// Events, if not nil, receives an event for
+	// every corrupt version deleted from Storage.
+	Events events.Emitter
+	// Pins, if not nil, has the versions that are
+	// only reported, whatever the Action.
+	Pins *pin.Store
+	// Fs and TempDir are where zips are spooled to be hashed.
+	Fs      afero.Fs
+	TempDir string
+	// PageSize is the number of versions to read from the
+	// Cataloger at once.
+	PageSize int
+}
+
+// Entry describes a module version that failed a scrub.
+type Entry struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+	Action  Action `json:"action,omitempty"`
+}
+
+// Result is the outcome of a single scrub of the storage.
+type Result struct {
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Checked    int       `json:"checked"`
+	Unverified int       `json:"unverified"`
+	Corrupt    []Entry   `json:"corrupt"`
+	Errors     []Entry   `json:"errors"`
+}
+
+// Status is the state of a Scrubber.
+type Status struct {
+	Running bool    `json:"running"`
+	Last    *Result `json:"last,omitempty"`
+}
+
+// Scrubber walks a storage backend and re-hashes every module version
+// in it, so that versions which were corrupted or truncated after being
+// saved are not served to clients.
+type Scrubber struct {
+	strg       storage.Backend
+	cataloger  storage.Cataloger
+	sums       storage.SumGetter
+	verifier   *checksum.Verifier
+	action     Action
+	quarantine storage.Saver
+	events     events.Emitter
+	pins       *pin.Store
+	fs         afero.Fs
+	tempDir    string
+	pageSize   int
+
+	mu      sync.Mutex
+	running bool
+	last    *Result
+}
+
+// New returns a Scrubber for opts.Storage.
+func New(opts *Opts) (*Scrubber, error) {
+	const op errors.Op = "scrub.New"
+	cataloger, ok := opts.Storage.(storage.Cataloger)
+	if !ok {
+		return nil, errors.E(op, "storage does not implement a catalog", errors.KindNotImplemented)
+	}
+	switch opts.Action {
+	case Report, Delete:
+	case Quarantine:
+		if opts.Quarantine == nil {
+			return nil, errors.E(op, "a quarantine storage is required to quarantine versions")
+		}
+	default:
+		return nil, errors.E(op, fmt.Sprintf("unknown scrub action %q", opts.Action))
+	}
+	sums, _ := opts.Storage.(storage.SumGetter)
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	fs := opts.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+	return &Scrubber{
+		strg:       opts.Storage,
+		cataloger:  cataloger,
+		sums:       sums,
+		verifier:   opts.Verifier,
+		action:     opts.Action,
+		quarantine: opts.Quarantine,
+		events:     opts.Events,
+		pins:       opts.Pins,
+		fs:         fs,
+		tempDir:    opts.TempDir,
+		pageSize:   pageSize,
+	}, nil
+}
+
+// Start scrubs the storage every interval until ctx is done.
+// Errors are logged using the entry in ctx.
+func (s *Scrubber) Start(ctx context.Context, interval time.Duration) {
+	lggr := log.EntryFromContext(ctx)
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				res, err := s.Run(ctx)
+				if err != nil {
+					lggr.SystemErr(err)
+					continue
+				}
+				if len(res.Corrupt) > 0 {
+					lggr.Infof("scrub found %d corrupt module versions out of %d", len(res.Corrupt), res.Checked)
+				}
+			}
+		}
+	}()
+}
+
+// Status returns whether a scrub is running
+// and the result of the last one.
+func (s *Scrubber) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Status{Running: s.running, Last: s.last}
+}
+
+// Run scrubs the whole storage once. Only one scrub
+// can run at a time.
+func (s *Scrubber) Run(ctx context.Context) (*Result, error) {
+	const op errors.Op = "scrub.Run"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, errors.E(op, "a scrub is already running", errors.KindAlreadyExists)
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	res := &Result{Started: time.Now(), Corrupt: []Entry{}, Errors: []Entry{}}
+	err := s.run(ctx, res)
+	res.Finished = time.Now()
+
+	s.mu.Lock()
+	s.running = false
+	if err == nil {
+		s.last = res
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return res, nil
+}
+
+func (s *Scrubber) run(ctx context.Context, res *Result) error {
+	const op errors.Op = "scrub.run"
+	token := ""
+	for {
+		page, next, err := s.cataloger.Catalog(ctx, token, s.pageSize)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		for _, p := range page {
+			if err := ctx.Err(); err != nil {
+				return errors.E(op, err)
+			}
+			s.scrub(ctx, res, p.Module, p.Version)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	// corrupt versions are only deleted once the walk is over,
+	// since deleting them would shift the pages of the catalogs
+	// whose tokens are offsets, and skip versions.
+	for i := range res.Corrupt {
+		e := &res.Corrupt[i]
+		if e.Action == Report {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return errors.E(op, err)
+		}
+		if err := s.act(ctx, e.Module, e.Version, e.Reason); err != nil {
+			e.Action = Report
+			res.Errors = append(res.Errors, Entry{Module: e.Module, Version: e.Version, Reason: err.Error()})
+			stats.Record(ctx, mErrors.M(1))
+		}
+	}
+	return nil
+}
+
+// scrub checks a single version and records the outcome in res.
+// Corrupt versions are acted on later, by run.
+func (s *Scrubber) scrub(ctx context.Context, res *Result, mod, ver string) {
+	res.Checked++
+	stats.Record(ctx, mChecked.M(1))
+	verified, reason, err := s.check(ctx, mod, ver)
+	if err != nil {
+		res.Errors = append(res.Errors, Entry{Module: mod, Version: ver, Reason: err.Error()})
+		stats.Record(ctx, mErrors.M(1))
+		return
+	}
+	if reason == "" {
+		if !verified {
+			res.Unverified++
+		}
+		return
+	}
+	stats.Record(ctx, mCorrupt.M(1))
+	action := s.action
+	if s.pins != nil && s.pins.Pinned(mod, ver) {
+		action = Report
+	}
+	res.Corrupt = append(res.Corrupt, Entry{Module: mod, Version: ver, Reason: reason, Action: action})
+}
+
+// check re-hashes a version. It returns a non-empty reason if the
+// version is corrupt, and whether there was a hash to compare to.
+func (s *Scrubber) check(ctx context.Context, mod, ver string) (verified bool, reason string, err error) {
+	const op errors.Op = "scrub.check"
+	goMod, err := s.strg.GoMod(ctx, mod, ver)
+	if err != nil {
+		return false, "", errors.E(op, err)
+	}
+	modHash, err := checksum.HashGoMod(goMod)
+	if err != nil {
+		return false, "", errors.E(op, err)
+	}
+	zip, err := s.strg.Zip(ctx, mod, ver)
+	if err != nil {
+		return false, "", errors.E(op, err)
+	}
+	zipHash, err := checksum.HashZipStream(s.fs, s.tempDir, zip)
+	if errors.Is(err, errors.KindBadRequest) {
+		return false, fmt.Sprintf("zip cannot be hashed: %v", err), nil
+	}
+	if err != nil {
+		// the zip could not be read, which says nothing about it.
+		return false, "", errors.E(op, err)
+	}
+
+	if s.sums != nil {
+		sum, err := s.sums.Sum(ctx, mod, ver)
+		if err != nil && !errors.IsNotFoundErr(err) {
+			return false, "", errors.E(op, err)
+		}
+		wantZip, wantMod := storage.ParseSumLines(sum, mod, ver)
+		if wantZip != "" && wantZip != zipHash {
+			return true, fmt.Sprintf("zip hash %s does not match recorded %s", zipHash, wantZip), nil
+		}
+		if wantMod != "" && wantMod != modHash {
+			return true, fmt.Sprintf("go.mod hash %s does not match recorded %s", modHash, wantMod), nil
+		}
+		if wantZip != "" || wantMod != "" {
+			return true, "", nil
+		}
+	}
+
+	if s.verifier == nil {
+		return false, "", nil
+	}
+	err = s.verifier.Verify(ctx, mod, ver, zipHash, modHash)
+	if errors.Is(err, errors.KindChecksumMismatch) {
+		return true, err.Error(), nil
+	}
+	if err != nil {
+		return false, "", errors.E(op, err)
+	}
+	return true, "", nil
+}
+
+// act applies the scrubber's Action to a corrupt version.
+func (s *Scrubber) act(ctx context.Context, mod, ver, reason string) error {
+	const op errors.Op = "scrub.act"
+	switch s.action {
+	case Quarantine:
+		if err := s.copyToQuarantine(ctx, mod, ver); err != nil {
+			return errors.E(op, err)
+		}
+	case Delete:
+	default:
+		return nil
+	}
+	if err := s.strg.Delete(ctx, mod, ver); err != nil {
+		return errors.E(op, err)
+	}
+	if s.events != nil {
+		s.events.Emit(ctx, &events.Event{Type: events.Deleted, Module: mod, Version: ver, Reason: "scrub: " + reason})
+	}
+	return nil
+}
+
+func (s *Scrubber) copyToQuarantine(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "scrub.copyToQuarantine"
+	info, err := s.strg.Info(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	goMod, err := s.strg.GoMod(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	zip, err := s.strg.Zip(ctx, mod, ver)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer zip.Close()
+	if err := s.quarantine.Save(ctx, mod, ver, goMod, zip, info); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package scrub
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/iotest"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/pin"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const testGoMod = "module example.com/mod\n"
+
+func makeZip(t *testing.T, mod, ver, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create(mod + "@" + ver + "/main.go")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func newStorage(t *testing.T, memFs afero.Fs, dir string) storage.Backend {
+	t.Helper()
+	require.NoError(t, memFs.MkdirAll(dir, 0777))
+	s, err := fs.NewStorage(dir, memFs)
+	require.NoError(t, err)
+	return s
+}
+
+// save stores zipBytes for mod@ver, along with the
+// go.sum lines of sumZip if it is not nil.
+func save(t *testing.T, s storage.Backend, mod, ver string, zipBytes, sumZip []byte) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, s.Save(ctx, mod, ver, []byte(testGoMod), bytes.NewReader(zipBytes), []byte("{}")))
+	if sumZip == nil {
+		return
+	}
+	zipHash, err := checksum.HashZip(bytes.NewReader(sumZip), int64(len(sumZip)))
+	require.NoError(t, err)
+	modHash, err := checksum.HashGoMod([]byte(testGoMod))
+	require.NoError(t, err)
+	sum := storage.SumLines(mod, ver, zipHash, modHash)
+	require.NoError(t, s.(storage.SumSaver).SaveSum(ctx, mod, ver, sum))
+}
+
+func TestScrub(t *testing.T) {
+	const mod = "example.com/mod"
+	good := makeZip(t, mod, "v1.0.0", "package mod")
+	tampered := makeZip(t, mod, "v1.1.0", "package mod // evil")
+	truncated := makeZip(t, mod, "v1.2.0", "package mod")
+	truncated = truncated[:len(truncated)/2]
+
+	tests := []struct {
+		action         Action
+		wantRemaining  []string
+		wantQuarantine []string
+	}{
+		{action: Report, wantRemaining: []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"}},
+		{action: Delete, wantRemaining: []string{"v1.0.0", "v1.3.0"}},
+		{action: Quarantine, wantRemaining: []string{"v1.0.0", "v1.3.0"}, wantQuarantine: []string{"v1.1.0", "v1.2.0"}},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.action), func(t *testing.T) {
+			ctx := context.Background()
+			memFs := afero.NewMemMapFs()
+			s := newStorage(t, memFs, "/storage")
+			q := newStorage(t, memFs, "/quarantine")
+			save(t, s, mod, "v1.0.0", good, good)
+			save(t, s, mod, "v1.1.0", tampered, makeZip(t, mod, "v1.1.0", "package mod"))
+			save(t, s, mod, "v1.2.0", truncated, nil)
+			save(t, s, mod, "v1.3.0", makeZip(t, mod, "v1.3.0", "package mod"), nil)
+
+			sc, err := New(&Opts{
+				Storage:    s,
+				Action:     tc.action,
+				Quarantine: q,
+				Fs:         memFs,
+				TempDir:    "/tmp",
+				PageSize:   1,
+			})
+			require.NoError(t, err)
+			res, err := sc.Run(ctx)
+			require.NoError(t, err)
+			require.Equal(t, 4, res.Checked)
+			require.Equal(t, 1, res.Unverified, "v1.3.0 has no hash to compare to")
+			require.Empty(t, res.Errors)
+			require.Len(t, res.Corrupt, 2)
+			require.Equal(t, "v1.1.0", res.Corrupt[0].Version)
+			require.Equal(t, "v1.2.0", res.Corrupt[1].Version)
+			require.Equal(t, tc.action, res.Corrupt[0].Action)
+
+			remaining, err := s.List(ctx, mod)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tc.wantRemaining, remaining)
+			quarantined, err := q.List(ctx, mod)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tc.wantQuarantine, quarantined)
+			require.Equal(t, res, sc.Status().Last)
+		})
+	}
+}
+
+// offsetCatalog is a storage whose catalog
+// tokens are offsets, as in some backends.
+type offsetCatalog struct {
+	storage.Backend
+}
+
+func (oc offsetCatalog) Catalog(ctx context.Context, token string, pageSize int) ([]paths.AllPathParams, string, error) {
+	all, _, err := oc.Backend.(storage.Cataloger).Catalog(ctx, "", 1000)
+	if err != nil {
+		return nil, "", err
+	}
+	from := 0
+	if token != "" {
+		if from, err = strconv.Atoi(token); err != nil {
+			return nil, "", err
+		}
+	}
+	if from+pageSize >= len(all) {
+		return all[from:], "", nil
+	}
+	return all[from : from+pageSize], strconv.Itoa(from + pageSize), nil
+}
+
+func (oc offsetCatalog) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	return oc.Backend.(storage.SumGetter).Sum(ctx, mod, ver)
+}
+
+func TestScrubOffsetCatalog(t *testing.T) {
+	const mod = "example.com/mod"
+	ctx := context.Background()
+	memFs := afero.NewMemMapFs()
+	s := newStorage(t, memFs, "/storage")
+	good := makeZip(t, mod, "v1.0.0", "package mod")
+	save(t, s, mod, "v1.0.0", good, good)
+	for _, ver := range []string{"v1.1.0", "v1.2.0"} {
+		save(t, s, mod, ver, makeZip(t, mod, ver, "package mod // evil"), makeZip(t, mod, ver, "package mod"))
+	}
+
+	sc, err := New(&Opts{
+		Storage:  offsetCatalog{s},
+		Action:   Delete,
+		Fs:       memFs,
+		TempDir:  "/tmp",
+		PageSize: 1,
+	})
+	require.NoError(t, err)
+	res, err := sc.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, res.Checked)
+	require.Len(t, res.Corrupt, 2, "deleting versions during the walk must not skip any")
+	remaining, err := s.List(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.0.0"}, remaining)
+}
+
+// failingZips is a storage whose zips fail midway through being read.
+type failingZips struct {
+	offsetCatalog
+}
+
+func (fz failingZips) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	zip, err := fz.Backend.Zip(ctx, mod, ver)
+	if err != nil {
+		return nil, err
+	}
+	r := io.MultiReader(io.LimitReader(zip, 10), iotest.TimeoutReader(zip))
+	return storage.NewSizer(ioutil.NopCloser(r), zip.Size()), nil
+}
+
+func TestScrubReadErrors(t *testing.T) {
+	const mod = "example.com/mod"
+	ctx := context.Background()
+	memFs := afero.NewMemMapFs()
+	s := newStorage(t, memFs, "/storage")
+	good := makeZip(t, mod, "v1.0.0", "package mod")
+	save(t, s, mod, "v1.0.0", good, good)
+
+	sc, err := New(&Opts{
+		Storage: failingZips{offsetCatalog{s}},
+		Action:  Delete,
+		Fs:      memFs,
+		TempDir: "/tmp",
+	})
+	require.NoError(t, err)
+	res, err := sc.Run(ctx)
+	require.NoError(t, err)
+	require.Empty(t, res.Corrupt, "a zip that cannot be read is not corrupt")
+	require.Len(t, res.Errors, 1)
+	remaining, err := s.List(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.0.0"}, remaining)
+}
+
+func TestScrubKeepsPins(t *testing.T) {
+	const mod = "example.com/mod"
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "athens-pin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pins, err := pin.NewStore(filepath.Join(dir, "pins"))
+	require.NoError(t, err)
+	require.NoError(t, pins.Pin(mod, "v1.0.0"))
+
+	memFs := afero.NewMemMapFs()
+	s := newStorage(t, memFs, "/storage")
+	save(t, s, mod, "v1.0.0", makeZip(t, mod, "v1.0.0", "package mod // evil"), makeZip(t, mod, "v1.0.0", "package mod"))
+	sc, err := New(&Opts{Storage: s, Action: Delete, Pins: pins, Fs: memFs, TempDir: "/tmp"})
+	require.NoError(t, err)
+	res, err := sc.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, res.Corrupt, 1)
+	require.Equal(t, Report, res.Corrupt[0].Action, "pinned versions are only reported")
+	remaining, err := s.List(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.0.0"}, remaining)
+}
+
+func TestNewErrors(t *testing.T) {
+	s := newStorage(t, afero.NewMemMapFs(), "/storage")
+	_, err := New(&Opts{Storage: s, Action: Quarantine})
+	require.Error(t, err, "quarantine requires a quarantine storage")
+	_, err = New(&Opts{Storage: s, Action: "shred"})
+	require.Error(t, err)
+}
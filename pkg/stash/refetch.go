@@ -0,0 +1,150 @@
+package stash
+
+import (
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
+	"github.com/gomods/athens/pkg/index"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+)
+
+// Refetch fetches mod@ver again with f and replaces the version in s with
+// it. The stored version is only replaced once the fetch has succeeded and
+// the zip is spooled to dir in fs, so that it is kept if upstream is
+// unavailable. The stored version is also spooled before it is replaced,
+// and saved again if saving the new one fails. It returns the semver of
+// the version, like Stash.
+func Refetch(ctx context.Context, f module.Fetcher, s storage.Backend, indexer index.Indexer, emitter events.Emitter, fs afero.Fs, dir, mod, ver string) (string, error) {
+	const op errors.Op = "stash.Refetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	v, err := f.Fetch(ctx, mod, ver)
+	if err != nil {
+		return "", errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	defer v.Zip.Close()
+	zip, err := afero.TempFile(fs, dir, "refetch")
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	defer fs.Remove(zip.Name())
+	defer zip.Close()
+	if _, err := io.Copy(zip, v.Zip); err != nil {
+		return "", errors.E(op, err, errors.M(mod), errors.V(v.Semver))
+	}
+	if _, err := zip.Seek(0, io.SeekStart); err != nil {
+		return "", errors.E(op, err)
+	}
+
+	exists, err := storage.WithChecker(s).Exists(ctx, mod, v.Semver)
+	if err != nil {
+		return "", errors.E(op, err, errors.M(mod), errors.V(v.Semver))
+	}
+	// not every backend overwrites a version on save
+	if exists {
+		old, err := backup(ctx, s, fs, dir, mod, v.Semver)
+		if err != nil {
+			return "", errors.E(op, err, errors.M(mod), errors.V(v.Semver))
+		}
+		defer old.Close()
+		if err := s.Delete(ctx, mod, v.Semver); err != nil {
+			return "", errors.E(op, err, errors.M(mod), errors.V(v.Semver))
+		}
+		if err := s.Save(ctx, mod, v.Semver, v.Mod, zip, v.Info); err != nil {
+			if rerr := old.restore(ctx, s); rerr != nil {
+				log.EntryFromContext(ctx).SystemErr(errors.E(op, rerr, errors.M(mod), errors.V(v.Semver)))
+			}
+			return "", errors.E(op, err, errors.M(mod), errors.V(v.Semver))
+		}
+	} else if err := s.Save(ctx, mod, v.Semver, v.Mod, zip, v.Info); err != nil {
+		return "", errors.E(op, err, errors.M(mod), errors.V(v.Semver))
+	}
+	st := &stasher{fetcher: f, storage: s, indexer: indexer, emitter: emitter}
+	st.saveSum(ctx, mod, v)
+	if err := indexer.Index(ctx, mod, v.Semver); err != nil && !errors.Is(err, errors.KindAlreadyExists) {
+		return "", errors.E(op, err, errors.M(mod), errors.V(v.Semver))
+	}
+	if emitter != nil {
+		emitter.Emit(ctx, &events.Event{Type: events.Stashed, Module: mod, Version: v.Semver})
+	}
+	return v.Semver, nil
+}
+
+// stored is a version as it was in storage,
+// with its zip spooled to a temporary file.
+type stored struct {
+	mod, ver    string
+	info, goMod []byte
+	// sum is nil if the version had no sum.
+	sum []byte
+	zip afero.File
+	fs  afero.Fs
+}
+
+// backup spools mod@ver from s to dir in fs.
+func backup(ctx context.Context, s storage.Backend, fs afero.Fs, dir, mod, ver string) (*stored, error) {
+	const op errors.Op = "stash.backup"
+	info, err := s.Info(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	goMod, err := s.GoMod(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	var sum []byte
+	if sg, ok := s.(storage.SumGetter); ok {
+		sum, err = sg.Sum(ctx, mod, ver)
+		if err != nil && !errors.IsNotFoundErr(err) {
+			return nil, errors.E(op, err)
+		}
+	}
+	zip, err := s.Zip(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer zip.Close()
+	tmp, err := afero.TempFile(fs, dir, "refetch-backup")
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	old := &stored{mod: mod, ver: ver, info: info, goMod: goMod, sum: sum, zip: tmp, fs: fs}
+	if _, err := io.Copy(tmp, zip); err != nil {
+		old.Close()
+		return nil, errors.E(op, err)
+	}
+	return old, nil
+}
+
+// restore saves old back into s, replacing whatever
+// a failed save may have left of the version.
+func (old *stored) restore(ctx context.Context, s storage.Backend) error {
+	const op errors.Op = "stash.restore"
+	if err := s.Delete(ctx, old.mod, old.ver); err != nil && !errors.IsNotFoundErr(err) {
+		return errors.E(op, err)
+	}
+	if _, err := old.zip.Seek(0, io.SeekStart); err != nil {
+		return errors.E(op, err)
+	}
+	if err := s.Save(ctx, old.mod, old.ver, old.goMod, old.zip, old.info); err != nil {
+		return errors.E(op, err)
+	}
+	if ss, ok := s.(storage.SumSaver); ok && old.sum != nil {
+		if err := ss.SaveSum(ctx, old.mod, old.ver, old.sum); err != nil {
+			return errors.E(op, err)
+		}
+	}
+	return nil
+}
+
+// Close removes the spooled zip of old.
+func (old *stored) Close() error {
+	old.zip.Close()
+	return old.fs.Remove(old.zip.Name())
+}
@@ -0,0 +1,147 @@
+package stash
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/gomods/athens/pkg/index/nop"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+)
+
+type failingFetcher struct{}
+
+func (failingFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	return nil, fmt.Errorf("upstream is down")
+}
+
+func TestRefetch(t *testing.T) {
+	const mod, ver = "github.com/athens-artifacts/happy-path", "v0.0.1"
+	ctx := context.Background()
+	memFs := afero.NewMemMapFs()
+	if err := memFs.MkdirAll("/storage", 0777); err != nil {
+		t.Fatal(err)
+	}
+	s, err := fs.NewStorage("/storage", memFs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(ctx, mod, ver, []byte("old"), strings.NewReader("old zip"), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Refetch(ctx, failingFetcher{}, s, nop.New(), nil, memFs, "/tmp", mod, ver); err == nil {
+		t.Fatal("expected a failed fetch to be an error")
+	}
+	gomod, err := s.GoMod(ctx, mod, ver)
+	if err != nil {
+		t.Fatalf("expected a failed fetch to keep the stored version but got %v", err)
+	}
+	if string(gomod) != "old" {
+		t.Fatalf("expected the stored go.mod to be kept but got %q", gomod)
+	}
+
+	var em mockEmitter
+	newVer, err := Refetch(ctx, &mockFetcher{ver: ver}, s, nop.New(), &em, memFs, "/tmp", mod, ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newVer != ver {
+		t.Fatalf("expected version %v but got %v", ver, newVer)
+	}
+	gomod, err = s.GoMod(ctx, mod, ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gomod) != "gomod" {
+		t.Fatalf("expected the stored go.mod to be replaced but got %q", gomod)
+	}
+	zip, err := s.Zip(ctx, mod, ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zip.Close()
+	b, err := ioutil.ReadAll(zip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "zipfile" {
+		t.Fatalf("expected the stored zip to be replaced but got %q", b)
+	}
+	if len(em.events) != 1 {
+		t.Fatalf("expected a single stashed event but got %v", em.events)
+	}
+	tmp, err := afero.ReadDir(memFs, "/tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmp) != 0 {
+		t.Fatalf("expected the spooled zip to be removed but found %d files", len(tmp))
+	}
+}
+
+// failingSave is a storage whose next save fails.
+type failingSave struct {
+	storage.Backend
+	fail bool
+}
+
+func (fs *failingSave) Save(ctx context.Context, mod, ver string, goMod []byte, zip io.Reader, info []byte) error {
+	if fs.fail {
+		fs.fail = false
+		return fmt.Errorf("storage is down")
+	}
+	return fs.Backend.Save(ctx, mod, ver, goMod, zip, info)
+}
+
+func TestRefetchFailedSave(t *testing.T) {
+	const mod, ver = "github.com/athens-artifacts/happy-path", "v0.0.1"
+	ctx := context.Background()
+	memFs := afero.NewMemMapFs()
+	if err := memFs.MkdirAll("/storage", 0777); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := fs.NewStorage("/storage", memFs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Save(ctx, mod, ver, []byte("old"), strings.NewReader("old zip"), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	s := &failingSave{Backend: backend, fail: true}
+
+	if _, err := Refetch(ctx, &mockFetcher{ver: ver}, s, nop.New(), nil, memFs, "/tmp", mod, ver); err == nil {
+		t.Fatal("expected a failed save to be an error")
+	}
+	gomod, err := backend.GoMod(ctx, mod, ver)
+	if err != nil {
+		t.Fatalf("expected a failed save to keep the stored version but got %v", err)
+	}
+	if string(gomod) != "old" {
+		t.Fatalf("expected the stored go.mod to be kept but got %q", gomod)
+	}
+	zip, err := backend.Zip(ctx, mod, ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zip.Close()
+	b, err := ioutil.ReadAll(zip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "old zip" {
+		t.Fatalf("expected the stored zip to be kept but got %q", b)
+	}
+	tmp, err := afero.ReadDir(memFs, "/tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmp) != 0 {
+		t.Fatalf("expected the spooled zips to be removed but found %d files", len(tmp))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/events"
 	"github.com/gomods/athens/pkg/index"
 	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/module"
@@ -28,8 +29,10 @@ type Wrapper func(Stasher) Stasher
 // New returns a plain stasher that takes
 // a module from a download.Protocol and
 // stashes it into a backend.Storage.
-func New(f module.Fetcher, s storage.Backend, indexer index.Indexer, wrappers ...Wrapper) Stasher {
-	var st Stasher = &stasher{f, s, storage.WithChecker(s), indexer}
+// If emitter is not nil, it receives an
+// event for every version that is saved.
+func New(f module.Fetcher, s storage.Backend, indexer index.Indexer, emitter events.Emitter, wrappers ...Wrapper) Stasher {
+	var st Stasher = &stasher{f, s, storage.WithChecker(s), indexer, emitter}
 	for _, w := range wrappers {
 		st = w(st)
 	}
@@ -42,18 +45,22 @@ type stasher struct {
 	storage storage.Backend
 	checker storage.Checker
 	indexer index.Indexer
+	emitter events.Emitter
 }
 
 func (s *stasher) Stash(ctx context.Context, mod, ver string) (string, error) {
 	const op errors.Op = "stasher.Stash"
 	_, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
-	log.EntryFromContext(ctx).Debugf("saving %s@%s to storage...", mod, ver)
+	lggr := log.EntryFromContext(ctx)
+	lggr.Debugf("saving %s@%s to storage...", mod, ver)
 
 	// create a new context that ditches whatever deadline the caller passed
-	// but keep the tracing info so that we can properly trace the whole thing.
+	// but keep the tracing info and the logger so that we can properly trace
+	// and log the whole thing.
 	ctx, cancel := context.WithTimeout(trace.NewContext(context.Background(), span), time.Minute*10)
 	defer cancel()
+	ctx = log.SetEntryInContext(ctx, lggr)
 	v, err := s.fetchModule(ctx, mod, ver)
 	if err != nil {
 		return "", errors.E(op, err)
@@ -72,13 +79,37 @@ func (s *stasher) Stash(ctx context.Context, mod, ver string) (string, error) {
 	if err != nil {
 		return "", errors.E(op, err)
 	}
+	s.saveSum(ctx, mod, v)
 	err = s.indexer.Index(ctx, mod, v.Semver)
 	if err != nil && !errors.Is(err, errors.KindAlreadyExists) {
 		return "", errors.E(op, err)
 	}
+	if s.emitter != nil {
+		s.emitter.Emit(ctx, &events.Event{Type: events.Stashed, Module: mod, Version: v.Semver})
+	}
 	return v.Semver, nil
 }
 
+// saveSum records the go.sum hashes of v if the fetcher
+// provided them and the storage is able to keep them.
+// The module itself is already saved at this point, so a
+// failure is only logged rather than failing the stash: the
+// download protocol computes missing sums when they are requested.
+func (s *stasher) saveSum(ctx context.Context, mod string, v *storage.Version) {
+	const op errors.Op = "stasher.saveSum"
+	sumSaver, ok := s.storage.(storage.SumSaver)
+	if !ok {
+		return
+	}
+	sum := storage.SumLines(mod, v.Semver, v.Sum, v.GoModSum)
+	if len(sum) == 0 {
+		return
+	}
+	if err := sumSaver.SaveSum(ctx, mod, v.Semver, sum); err != nil {
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, errors.M(mod), errors.V(v.Semver), err))
+	}
+}
+
 func (s *stasher) fetchModule(ctx context.Context, mod, ver string) (*storage.Version, error) {
 	const op errors.Op = "stasher.fetchModule"
 	v, err := s.fetcher.Fetch(ctx, mod, ver)
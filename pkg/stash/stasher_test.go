@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/gomods/athens/pkg/events"
 	"github.com/gomods/athens/pkg/index/nop"
 	"github.com/gomods/athens/pkg/storage"
 )
@@ -55,7 +56,8 @@ func TestStash(t *testing.T) {
 			var mf mockFetcher
 			mf.ver = testCase.modVer
 
-			s := New(&mf, &ms, nop.New())
+			var em mockEmitter
+			s := New(&mf, &ms, nop.New(), &em)
 			newVersion, err := s.Stash(context.Background(), "module", testCase.ver)
 			if err != nil {
 				t.Fatal(err)
@@ -70,18 +72,36 @@ func TestStash(t *testing.T) {
 				if ms.givenVersion != testCase.modVer {
 					t.Fatalf("expected storage.Save to be called with version %v but got %v", testCase.modVer, ms.givenVersion)
 				}
+				expectedSum := "module " + testCase.modVer + " h1:zip\nmodule " + testCase.modVer + "/go.mod h1:mod\n"
+				if string(ms.givenSum) != expectedSum {
+					t.Fatalf("expected storage.SaveSum to be called with %q but got %q", expectedSum, ms.givenSum)
+				}
+				if len(em.events) != 1 || em.events[0].Type != events.Stashed || em.events[0].Version != testCase.modVer {
+					t.Fatalf("expected a single stashed event for %v but got %v", testCase.modVer, em.events)
+				}
 			} else if ms.saveCalled {
 				t.Fatalf("expected save not to be called")
+			} else if len(em.events) != 0 {
+				t.Fatalf("expected no events but got %v", em.events)
 			}
 		})
 	}
 }
 
+type mockEmitter struct {
+	events []*events.Event
+}
+
+func (em *mockEmitter) Emit(ctx context.Context, e *events.Event) {
+	em.events = append(em.events, e)
+}
+
 type mockStorage struct {
 	storage.Backend
 	existsCalled   bool
 	saveCalled     bool
 	givenVersion   string
+	givenSum       []byte
 	existsResponse bool
 }
 
@@ -91,6 +111,11 @@ func (ms *mockStorage) Save(ctx context.Context, module, version string, mod []b
 	return nil
 }
 
+func (ms *mockStorage) SaveSum(ctx context.Context, module, version string, sum []byte) error {
+	ms.givenSum = sum
+	return nil
+}
+
 func (ms *mockStorage) Exists(ctx context.Context, mod, ver string) (bool, error) {
 	ms.existsCalled = true
 	return ms.existsResponse, nil
@@ -102,9 +127,11 @@ type mockFetcher struct {
 
 func (mf *mockFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
 	return &storage.Version{
-		Info:   []byte("info"),
-		Mod:    []byte("gomod"),
-		Zip:    ioutil.NopCloser(strings.NewReader("zipfile")),
-		Semver: mf.ver,
+		Info:     []byte("info"),
+		Mod:      []byte("gomod"),
+		Zip:      ioutil.NopCloser(strings.NewReader("zipfile")),
+		Semver:   mf.ver,
+		Sum:      "h1:zip",
+		GoModSum: "h1:mod",
 	}, nil
 }
@@ -0,0 +1,53 @@
+package stash
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// WithNotFoundCache returns a wrapper that remembers the versions
+// that upstream reported as not found in c, and fails to stash them
+// without asking upstream again until they expire from c.
+// It should be the last wrapper, so that the versions that are
+// cached as not found do not wait for a lock or a worker.
+func WithNotFoundCache(c notfound.Cache) Wrapper {
+	return func(s Stasher) Stasher {
+		return &withNotFound{s, c}
+	}
+}
+
+type withNotFound struct {
+	stasher Stasher
+	cache   notfound.Cache
+}
+
+func (s *withNotFound) Stash(ctx context.Context, mod, ver string) (string, error) {
+	const op errors.Op = "notFoundCache.Stash"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	lggr := log.EntryFromContext(ctx)
+
+	// the cache is an optimization: if it fails,
+	// the version is stashed as if it was not there.
+	cached, err := s.cache.Has(ctx, mod, ver)
+	if err != nil {
+		lggr.SystemErr(errors.E(op, err))
+	}
+	if cached {
+		return "", errors.E(op, errors.M(mod), errors.V(ver), "cached as not found upstream", errors.KindNotFound)
+	}
+	newVer, err := s.stasher.Stash(ctx, mod, ver)
+	if err != nil {
+		if errors.IsNotFoundErr(err) {
+			if cacheErr := s.cache.Add(ctx, mod, ver); cacheErr != nil {
+				lggr.SystemErr(errors.E(op, cacheErr))
+			}
+		}
+		return "", errors.E(op, err)
+	}
+	return newVer, nil
+}
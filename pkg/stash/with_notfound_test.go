@@ -0,0 +1,45 @@
+package stash
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/notfound"
+	"github.com/stretchr/testify/require"
+)
+
+// notFoundStasher only finds v1.0.0.
+type notFoundStasher struct {
+	calls int
+}
+
+func (s *notFoundStasher) Stash(ctx context.Context, mod, ver string) (string, error) {
+	s.calls++
+	if ver != "v1.0.0" {
+		return "", errors.E("notFoundStasher.Stash", errors.KindNotFound)
+	}
+	return ver, nil
+}
+
+func TestWithNotFoundCache(t *testing.T) {
+	ctx := context.Background()
+	ms := &notFoundStasher{}
+	c := notfound.NewMemory(time.Hour)
+	s := WithNotFoundCache(c)(ms)
+
+	for i := 0; i < 2; i++ {
+		_, err := s.Stash(ctx, "mod", "v0.0.0")
+		require.True(t, errors.IsNotFoundErr(err))
+		ver, err := s.Stash(ctx, "mod", "v1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, "v1.0.0", ver)
+	}
+	require.Equal(t, 3, ms.calls, "a version that is not found must only be stashed once")
+
+	require.NoError(t, c.Invalidate(ctx, "mod", "v0.0.0"))
+	_, err := s.Stash(ctx, "mod", "v0.0.0")
+	require.True(t, errors.IsNotFoundErr(err))
+	require.Equal(t, 4, ms.calls)
+}
@@ -25,6 +25,9 @@ type client interface {
 
 type azureBlobStoreClient struct {
 	containerURL *azblob.ContainerURL
+	// cred and containerName sign zip URLs.
+	cred          *azblob.SharedKeyCredential
+	containerName string
 }
 
 func newBlobStoreClient(accountURL *url.URL, accountName, accountKey, containerName string) (*azureBlobStoreClient, error) {
@@ -40,7 +43,7 @@ func newBlobStoreClient(accountURL *url.URL, accountName, accountKey, containerN
 	//
 	// This container must exist
 	containerURL := serviceURL.NewContainerURL(containerName)
-	cl := &azureBlobStoreClient{containerURL: &containerURL}
+	cl := &azureBlobStoreClient{containerURL: &containerURL, cred: cred, containerName: containerName}
 	return cl, nil
 }
 
@@ -3,6 +3,7 @@ package azureblob
 import (
 	"context"
 
+	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
 	modupl "github.com/gomods/athens/pkg/storage/module"
@@ -24,5 +25,17 @@ func (s *Storage) Delete(ctx context.Context, module string, version string) err
 		return errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
 	}
 
+	// versions saved before sums were recorded have none.
+	sumPath := config.PackageVersionedName(module, version, "sum")
+	sumExists, err := s.client.BlobExists(ctx, sumPath)
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if sumExists {
+		if err := s.client.DeleteBlob(ctx, sumPath); err != nil {
+			return errors.E(op, err, errors.M(module), errors.V(version))
+		}
+	}
+
 	return modupl.Delete(ctx, module, version, s.client.DeleteBlob, s.timeout)
 }
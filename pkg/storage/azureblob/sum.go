@@ -0,0 +1,52 @@
+package azureblob
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// SaveSum implements the (./pkg/storage).SumSaver interface.
+func (s *Storage) SaveSum(ctx context.Context, module string, version string, sum []byte) error {
+	const op errors.Op = "azureblob.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	err := s.client.UploadWithContext(ctx, config.PackageVersionedName(module, version, "sum"), "text/plain", bytes.NewReader(sum))
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
+
+// Sum implements the (./pkg/storage).SumGetter interface
+func (s *Storage) Sum(ctx context.Context, module string, version string) ([]byte, error) {
+	const op errors.Op = "azureblob.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	sumPath := config.PackageVersionedName(module, version, "sum")
+	exists, err := s.client.BlobExists(ctx, sumPath)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if !exists {
+		return nil, errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+	}
+
+	sumReader, err := s.client.ReadBlob(ctx, sumPath)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	defer sumReader.Close()
+
+	sum, err := ioutil.ReadAll(sumReader)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return sum, nil
+}
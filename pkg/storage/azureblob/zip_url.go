@@ -0,0 +1,50 @@
+package azureblob
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// ZipURL implements the (./pkg/storage).ZipURLer interface
+// with a read-only SAS URL.
+func (s *Storage) ZipURL(ctx context.Context, module, version string, expiry time.Duration) (string, error) {
+	const op errors.Op = "azureblob.ZipURL"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	path := config.PackageVersionedName(module, version, "zip")
+	exists, err := s.client.BlobExists(ctx, path)
+	if err != nil {
+		return "", errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if !exists {
+		return "", errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+	}
+	u, err := s.client.SignedURL(path, expiry)
+	if err != nil {
+		return "", errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return u, nil
+}
+
+// SignedURL returns a URL to read the blob at path that is valid for expiry.
+func (c *azureBlobStoreClient) SignedURL(path string, expiry time.Duration) (string, error) {
+	const op errors.Op = "azureblob.SignedURL"
+	sas, err := azblob.BlobSASSignatureValues{
+		Protocol:      azblob.SASProtocolHTTPS,
+		ExpiryTime:    time.Now().UTC().Add(expiry),
+		ContainerName: c.containerName,
+		BlobName:      path,
+		Permissions:   azblob.BlobSASPermissions{Read: true}.String(),
+	}.NewSASQueryParameters(c.cred)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	u := c.containerURL.NewBlobURL(path).URL()
+	u.RawQuery = sas.Encode()
+	return u.String(), nil
+}
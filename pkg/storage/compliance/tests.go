@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"sort"
@@ -26,6 +27,8 @@ func RunTests(t *testing.T, b storage.Backend, clearBackend func() error) {
 	testGet(t, b)
 	testExists(t, b)
 	testShouldNotExist(t, b)
+	testSum(t, b)
+	testFailedSave(t, b)
 	// testCatalog(t, b)
 }
 
@@ -209,6 +212,76 @@ func testDelete(t *testing.T, b storage.Backend) {
 	require.Equal(t, false, exists)
 }
 
+// testSum tests that a backend recording go.sum lines
+// returns them until the version is deleted.
+func testSum(t *testing.T, b storage.Backend) {
+	sumSaver, ok := b.(storage.SumSaver)
+	if !ok {
+		return
+	}
+	sumGetter, ok := b.(storage.SumGetter)
+	if !ok {
+		t.Fatal("a backend that saves sums must also get them")
+	}
+	ctx := context.Background()
+	modname := "github.com/gomods/athens"
+	version := fmt.Sprintf("%s%d", "sum", rand.Int())
+
+	mock := getMockModule()
+	err := b.Save(ctx, modname, version, mock.Mod, mock.Zip, mock.Info)
+	require.NoError(t, err)
+
+	_, err = sumGetter.Sum(ctx, modname, version)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err), "a version without a saved sum should not have one")
+
+	sum := storage.SumLines(modname, version, "h1:zip=", "h1:mod=")
+	err = sumSaver.SaveSum(ctx, modname, version, sum)
+	require.NoError(t, err)
+
+	exists, err := storage.WithChecker(b).Exists(ctx, modname, version)
+	require.NoError(t, err)
+	require.True(t, exists, "saving a sum should not change whether a version exists")
+
+	given, err := sumGetter.Sum(ctx, modname, version)
+	require.NoError(t, err)
+	require.Equal(t, sum, given)
+
+	err = b.Delete(ctx, modname, version)
+	require.NoError(t, err)
+
+	_, err = sumGetter.Sum(ctx, modname, version)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err), "deleting a version should delete its sum")
+}
+
+// testFailedSave tests that a version whose zip could not
+// be read in full is not reported as present afterwards.
+func testFailedSave(t *testing.T, b storage.Backend) {
+	ctx := context.Background()
+	modname := "github.com/gomods/athens"
+	version := fmt.Sprintf("%s%d", "failed", rand.Int())
+
+	mock := getMockModule()
+	zip := io.MultiReader(bytes.NewReader([]byte("partial zip")), &failingReader{})
+	err := b.Save(ctx, modname, version, mock.Mod, zip, mock.Info)
+	require.Error(t, err, "a save whose zip fails to read must fail")
+	defer b.Delete(ctx, modname, version)
+
+	exists, err := storage.WithChecker(b).Exists(ctx, modname, version)
+	require.NoError(t, err)
+	require.False(t, exists, "a failed save must not leave a version behind")
+
+	_, err = b.Info(ctx, modname, version)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err), "a failed save must not leave an .info file behind")
+}
+
+// failingReader simulates a connection
+// dropped in the middle of a save.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("connection reset")
+}
+
 func testCatalog(t *testing.T, b storage.Backend) {
 	cs, ok := b.(storage.Cataloger)
 	if !ok {
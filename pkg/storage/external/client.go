@@ -2,6 +2,7 @@ package external
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -113,6 +114,45 @@ func (s *service) Save(ctx context.Context, mod, ver string, modFile []byte, zip
 	return nil
 }
 
+func (s *service) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "external.Sum"
+	body, _, err := s.getRequest(ctx, mod, ver, "sum")
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer body.Close()
+	sum, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return sum, nil
+}
+
+func (s *service) SaveSum(ctx context.Context, mod, ver string, sum []byte) error {
+	const op errors.Op = "external.SaveSum"
+	var err error
+	mod, err = module.EscapePath(mod)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	url := s.url + "/" + mod + "/@v/" + ver + ".sum"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(sum))
+	if err != nil {
+		return errors.E(op, err)
+	}
+	req.Header.Add("Content-Type", "text/plain")
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		bts, _ := ioutil.ReadAll(resp.Body)
+		return errors.E(op, fmt.Errorf("unexpected status code: %v - body: %s", resp.StatusCode, bts), resp.StatusCode)
+	}
+	return nil
+}
+
 func (s *service) Delete(ctx context.Context, mod, ver string) error {
 	const op errors.Op = "external.Delete"
 	body, _, err := s.doRequest(ctx, "DELETE", mod, ver, "delete")
@@ -123,8 +163,10 @@ func (s *service) Delete(ctx context.Context, mod, ver string) error {
 	return nil
 }
 
+// upload writes the multipart body of a save. The closing boundary
+// is only written on success, so that a failure part way through
+// never reaches the server as a complete form with a truncated zip.
 func upload(mw *multipart.Writer, mod, info []byte, zip io.Reader) error {
-	defer mw.Close()
 	infoW, err := mw.CreateFormFile("mod.info", "mod.info")
 	if err != nil {
 		return fmt.Errorf("error creating info file: %v", err)
@@ -149,7 +191,7 @@ func upload(mw *multipart.Writer, mod, info []byte, zip io.Reader) error {
 	if err != nil {
 		return fmt.Errorf("error writing zip file: %v", err)
 	}
-	return nil
+	return mw.Close()
 }
 
 func (s *service) getRequest(ctx context.Context, mod, ver, ext string) (io.ReadCloser, int64, error) {
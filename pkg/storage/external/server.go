@@ -71,6 +71,46 @@ func NewServer(strg storage.Backend) http.Handler {
 		w.Header().Set("Content-Length", strconv.FormatInt(zip.Size(), 10))
 		io.Copy(w, zip)
 	}).Methods(http.MethodGet)
+	r.HandleFunc(download.PathVersionSum, func(w http.ResponseWriter, r *http.Request) {
+		params, err := paths.GetAllParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		sumGetter, ok := strg.(storage.SumGetter)
+		if !ok {
+			http.Error(w, "storage does not record sums", errors.KindNotImplemented)
+			return
+		}
+		sum, err := sumGetter.Sum(r.Context(), params.Module, params.Version)
+		if err != nil {
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+		w.Write(sum)
+	}).Methods(http.MethodGet)
+	r.HandleFunc(download.PathVersionSum, func(w http.ResponseWriter, r *http.Request) {
+		params, err := paths.GetAllParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		sumSaver, ok := strg.(storage.SumSaver)
+		if !ok {
+			http.Error(w, "storage does not record sums", errors.KindNotImplemented)
+			return
+		}
+		sum, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		err = sumSaver.SaveSum(r.Context(), params.Module, params.Version, sum)
+		if err != nil {
+			http.Error(w, err.Error(), errors.Kind(err))
+			return
+		}
+	}).Methods(http.MethodPost)
 	r.HandleFunc("/{module:.+}/@v/{version}.save", func(w http.ResponseWriter, r *http.Request) {
 		params, err := paths.GetAllParams(r)
 		if err != nil {
@@ -32,6 +32,9 @@ func (s *storageImpl) Catalog(ctx context.Context, token string, pageSize int) (
 	count := pageSize
 
 	err = afero.Walk(s.filesystem, s.rootDir, func(path string, info os.FileInfo, err error) error {
+		if info.IsDir() && path == s.stagingLocation() {
+			return filepath.SkipDir
+		}
 		if strings.HasSuffix(info.Name(), ".info") {
 			verDir := filepath.Dir(path)
 			modVer, err := filepath.Rel(s.rootDir, verDir)
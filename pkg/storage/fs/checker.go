@@ -24,5 +24,12 @@ func (v *storageImpl) Exists(ctx context.Context, module, version string) (bool,
 		return false, errors.E(op, errors.M(module), errors.V(version), err)
 	}
 
-	return len(files) == 3, nil
+	var count int
+	for _, fi := range files {
+		switch fi.Name() {
+		case "go.mod", "source.zip", version + ".info":
+			count++
+		}
+	}
+	return count == 3, nil
 }
@@ -4,12 +4,24 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/storage"
 	"github.com/spf13/afero"
 )
 
+// stagingDir is where files are written before being moved
+// into place. It lives under the root directory so that the
+// move is a rename within the same file system, and it cannot
+// clash with a module because module paths never start with a dot.
+const stagingDir = ".staging"
+
+// staleStaging is the age after which a staged file is considered
+// left behind by an interrupted save. Younger files may belong to
+// saves in progress, of this or of other processes sharing the disk.
+const staleStaging = 24 * time.Hour
+
 type storageImpl struct {
 	rootDir    string
 	filesystem afero.Fs
@@ -24,6 +36,10 @@ func (s *storageImpl) versionLocation(module, version string) string {
 
 }
 
+func (s *storageImpl) stagingLocation() string {
+	return filepath.Join(s.rootDir, stagingDir)
+}
+
 // NewStorage returns a new ListerSaver implementation that stores
 // everything under rootDir
 // If the root directory does not exist an error is returned
@@ -36,7 +52,33 @@ func NewStorage(rootDir string, filesystem afero.Fs) (storage.Backend, error) {
 	if !exists {
 		return nil, errors.E(op, fmt.Errorf("root directory `%s` does not exist", rootDir))
 	}
-	return &storageImpl{rootDir: rootDir, filesystem: filesystem}, nil
+	s := &storageImpl{rootDir: rootDir, filesystem: filesystem}
+	if err := s.cleanStaging(); err != nil {
+		return nil, errors.E(op, fmt.Errorf("could not clean up the staging directory in `%s`: %s", rootDir, err))
+	}
+	return s, nil
+}
+
+// cleanStaging removes the staged files that interrupted saves left
+// behind. Versions whose .info file was never moved into place are not
+// looked for, since they are ignored and overwritten by the next save.
+func (s *storageImpl) cleanStaging() error {
+	files, err := afero.ReadDir(s.filesystem, s.stagingLocation())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, fi := range files {
+		if time.Since(fi.ModTime()) < staleStaging {
+			continue
+		}
+		if err := s.filesystem.RemoveAll(filepath.Join(s.stagingLocation(), fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *storageImpl) Clear() error {
@@ -1,8 +1,16 @@
 package fs
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/storage/compliance"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
@@ -35,3 +43,82 @@ func getStorage(tb testing.TB, fs afero.Fs) *storageImpl {
 	require.NoError(tb, err)
 	return backend.(*storageImpl)
 }
+
+func TestIncompleteSave(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	b := getStorage(t, fs)
+	ctx := context.Background()
+	const mod = "github.com/gomods/athens"
+	require.NoError(t, b.Save(ctx, mod, "v1.0.0", []byte("mod"), bytes.NewReader([]byte("zip")), []byte("info")))
+
+	// simulate a crash after the go.mod and zip were moved into place
+	// but before the .info file was.
+	incomplete := b.versionLocation(mod, "v1.1.0")
+	require.NoError(t, fs.MkdirAll(incomplete, 0777))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(incomplete, "go.mod"), []byte("mod"), 0666))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(incomplete, "source.zip"), []byte("zi"), 0666))
+
+	versions, err := b.List(ctx, mod)
+	require.NoError(t, err)
+	require.Equal(t, []string{"v1.0.0"}, versions, "incomplete versions must not be listed")
+	exists, err := b.Exists(ctx, mod, "v1.1.0")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	require.NoError(t, b.Save(ctx, mod, "v1.1.0", []byte("mod"), bytes.NewReader([]byte("zip")), []byte("info")))
+	zip, err := b.Zip(ctx, mod, "v1.1.0")
+	require.NoError(t, err)
+	defer zip.Close()
+	content, err := ioutil.ReadAll(zip)
+	require.NoError(t, err)
+	require.Equal(t, "zip", string(content), "a new save must replace an incomplete one")
+}
+
+func TestFailedSave(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	b := getStorage(t, fs)
+	ctx := context.Background()
+	const mod, ver = "github.com/gomods/athens", "v1.0.0"
+
+	zip := io.MultiReader(bytes.NewReader([]byte("partial zip")), failingReader{})
+	err := b.Save(ctx, mod, ver, []byte("mod"), zip, []byte("info"))
+	require.Error(t, err, "a save whose zip fails to read must fail")
+
+	exists, err := b.Exists(ctx, mod, ver)
+	require.NoError(t, err)
+	require.False(t, exists, "a failed save must not leave a version behind")
+	_, err = b.Info(ctx, mod, ver)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err), "a failed save must not leave an .info file behind")
+	staged, err := afero.ReadDir(fs, b.stagingLocation())
+	require.NoError(t, err)
+	require.Empty(t, staged, "a failed save must remove its staged files")
+}
+
+// failingReader simulates a connection
+// dropped in the middle of a save.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("connection reset")
+}
+
+func TestCleanStaging(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	b := getStorage(t, fs)
+	require.NoError(t, fs.MkdirAll(b.stagingLocation(), 0777))
+	stale := filepath.Join(b.stagingLocation(), "athens123")
+	inProgress := filepath.Join(b.stagingLocation(), "athens456")
+	require.NoError(t, afero.WriteFile(fs, stale, []byte("zi"), 0666))
+	require.NoError(t, afero.WriteFile(fs, inProgress, []byte("zi"), 0666))
+	old := time.Now().Add(-staleStaging - time.Minute)
+	require.NoError(t, fs.Chtimes(stale, old, old))
+
+	_, err := NewStorage(b.rootDir, fs)
+	require.NoError(t, err)
+	exists, err := afero.Exists(fs, stale)
+	require.NoError(t, err)
+	require.False(t, exists, "stale staged files must be removed on startup")
+	exists, err = afero.Exists(fs, inProgress)
+	require.NoError(t, err)
+	require.True(t, exists, "the staged files of saves in progress must be kept")
+}
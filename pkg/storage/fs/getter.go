@@ -51,7 +51,7 @@ func (v *storageImpl) Zip(ctx context.Context, module, version string) (storage.
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
-	return storage.NewSizer(src, fi.Size()), nil
+	return storage.NewValidatedSizer(src, fi.Size(), "", fi.ModTime()), nil
 }
 
 func (v *storageImpl) ZipSize(ctx context.Context, module, version string) (int64, error) {
@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/gomods/athens/pkg/errors"
@@ -30,7 +31,15 @@ func (l *storageImpl) List(ctx context.Context, module string) ([]string, error)
 			continue
 		}
 		ver := fileInfo.Name()
-		if v := semver.Canonical(ver); v != "" && strings.HasPrefix(ver, v) {
+		if v := semver.Canonical(ver); v == "" || !strings.HasPrefix(ver, v) {
+			continue
+		}
+		// versions without an .info file were never completely saved.
+		complete, err := afero.Exists(l.filesystem, filepath.Join(loc, ver, ver+".info"))
+		if err != nil {
+			return nil, errors.E(op, errors.M(module), err, errors.KindUnexpected)
+		}
+		if complete {
 			ret = append(ret, ver)
 		}
 	}
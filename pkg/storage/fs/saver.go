@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os"
@@ -11,6 +12,11 @@ import (
 	"github.com/spf13/afero"
 )
 
+// Save writes every file of a version to the staging directory first
+// and only then moves them into the versioned directory, the .info file
+// last. A version is therefore never visible before all its files are
+// complete, and a crash in the middle of a save leaves a version without
+// an .info file, which is ignored until it is saved again.
 func (s *storageImpl) Save(ctx context.Context, module, version string, mod []byte, zip io.Reader, info []byte) error {
 	const op errors.Op = "fs.Save"
 	ctx, span := observ.StartSpan(ctx, op.String())
@@ -21,31 +27,63 @@ func (s *storageImpl) Save(ctx context.Context, module, version string, mod []by
 	// so a umask of for example 0077 allows directories and files to be
 	// created with mode 0700 / 0600, i.e. not world- or group-readable
 
-	// make the versioned directory to hold the go.mod and the zipfile
-	if err := s.filesystem.MkdirAll(dir, 0777); err != nil {
+	modPath, err := s.stage(bytes.NewReader(mod))
+	if err != nil {
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
+	defer s.filesystem.Remove(modPath)
 
-	// write the go.mod file
-	if err := afero.WriteFile(s.filesystem, filepath.Join(dir, "go.mod"), mod, 0666); err != nil {
+	zipPath, err := s.stage(zip)
+	if err != nil {
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
+	defer s.filesystem.Remove(zipPath)
 
-	// write the zipfile
-	f, err := s.filesystem.OpenFile(filepath.Join(dir, "source.zip"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	infoPath, err := s.stage(bytes.NewReader(info))
 	if err != nil {
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
-	defer f.Close()
-	_, err = io.Copy(f, zip)
-	if err != nil {
+	defer s.filesystem.Remove(infoPath)
+
+	// make the versioned directory to hold the go.mod and the zipfile
+	if err := s.filesystem.MkdirAll(dir, 0777); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	// a sum saved along with a previous save may not match anymore.
+	if err := s.filesystem.Remove(filepath.Join(dir, version+".sum")); err != nil && !os.IsNotExist(err) {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if err := s.filesystem.Rename(modPath, filepath.Join(dir, "go.mod")); err != nil {
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
+	if err := s.filesystem.Rename(zipPath, filepath.Join(dir, "source.zip")); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	// the info file marks the version as complete, so it goes last.
+	if err := s.filesystem.Rename(infoPath, filepath.Join(dir, version+".info")); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
 
-	// write the info file
-	err = afero.WriteFile(s.filesystem, filepath.Join(dir, version+".info"), info, 0666)
+// stage copies r to a new file in the staging
+// directory and returns its path.
+func (s *storageImpl) stage(r io.Reader) (string, error) {
+	const op errors.Op = "fs.stage"
+	if err := s.filesystem.MkdirAll(s.stagingLocation(), 0777); err != nil {
+		return "", errors.E(op, err)
+	}
+	f, err := afero.TempFile(s.filesystem, s.stagingLocation(), "athens")
 	if err != nil {
-		return errors.E(op, err)
+		return "", errors.E(op, err)
 	}
-	return nil
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		s.filesystem.Remove(f.Name())
+		return "", errors.E(op, err)
+	}
+	return f.Name(), nil
 }
@@ -0,0 +1,47 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/spf13/afero"
+)
+
+// SaveSum implements the (./pkg/storage).SumSaver interface
+func (s *storageImpl) SaveSum(ctx context.Context, module, version string, sum []byte) error {
+	const op errors.Op = "fs.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	exists, err := s.Exists(ctx, module, version)
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if !exists {
+		return errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+	}
+	sumPath, err := s.stage(bytes.NewReader(sum))
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	defer s.filesystem.Remove(sumPath)
+	err = s.filesystem.Rename(sumPath, filepath.Join(s.versionLocation(module, version), version+".sum"))
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
+
+// Sum implements the (./pkg/storage).SumGetter interface
+func (s *storageImpl) Sum(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "fs.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sum, err := afero.ReadFile(s.filesystem, filepath.Join(s.versionLocation(module, version), version+".sum"))
+	if err != nil {
+		return nil, errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+	}
+	return sum, nil
+}
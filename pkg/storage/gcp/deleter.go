@@ -3,6 +3,8 @@ package gcp
 import (
 	"context"
 
+	"cloud.google.com/go/storage"
+	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
 	modupl "github.com/gomods/athens/pkg/storage/module"
@@ -29,5 +31,10 @@ func (s *Storage) Delete(ctx context.Context, module, version string) error {
 	if err != nil {
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
+	// versions saved before sums were recorded have none.
+	err = del(ctx, config.PackageVersionedName(module, version, "sum"))
+	if err != nil && err != storage.ErrObjectNotExist {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 )
@@ -17,6 +18,13 @@ import (
 type Storage struct {
 	bucket  *storage.BucketHandle
 	timeout time.Duration
+	// bucketName, accessID and privateKey sign zip URLs. They
+	// are only set if a service account JSON key is given,
+	// otherwise signErr says why zip URLs cannot be signed.
+	bucketName string
+	accessID   string
+	privateKey []byte
+	signErr    error
 }
 
 // New returns a new Storage instance backed by a Google Cloud Storage bucket.
@@ -49,6 +57,9 @@ func New(ctx context.Context, gcpConf *config.GCPConfig, timeout time.Duration)
 func newClient(ctx context.Context, gcpConf *config.GCPConfig, timeout time.Duration) (*Storage, error) {
 	const op errors.Op = "gcp.newClient"
 	opts := []option.ClientOption{}
+	var accessID string
+	var privateKey []byte
+	signErr := fmt.Errorf("no JSON key is set")
 	if gcpConf.JSONKey != "" {
 		key, err := base64.StdEncoding.DecodeString(gcpConf.JSONKey)
 		if err != nil {
@@ -59,6 +70,14 @@ func newClient(ctx context.Context, gcpConf *config.GCPConfig, timeout time.Dura
 			return nil, errors.E(op, fmt.Errorf("could not get GCS credentials: %v", err))
 		}
 		opts = append(opts, option.WithCredentials(creds))
+		// only service account keys can sign URLs, other
+		// credentials can still read and write the bucket.
+		if jwt, err := google.JWTConfigFromJSON(key); err != nil {
+			signErr = err
+			log.EntryFromContext(ctx).Warnf("zip URLs cannot be signed with the GCS JSON key: %v", err)
+		} else {
+			accessID, privateKey, signErr = jwt.Email, jwt.PrivateKey, nil
+		}
 	}
 	s, err := storage.NewClient(ctx, opts...)
 	if err != nil {
@@ -66,7 +85,11 @@ func newClient(ctx context.Context, gcpConf *config.GCPConfig, timeout time.Dura
 	}
 
 	return &Storage{
-		bucket:  s.Bucket(gcpConf.Bucket),
-		timeout: timeout,
+		bucket:     s.Bucket(gcpConf.Bucket),
+		timeout:    timeout,
+		bucketName: gcpConf.Bucket,
+		accessID:   accessID,
+		privateKey: privateKey,
+		signErr:    signErr,
 	}, nil
 }
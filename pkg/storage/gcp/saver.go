@@ -45,14 +45,19 @@ func (s *Storage) upload(ctx context.Context, path string, stream io.Reader) err
 	const op errors.Op = "gcp.upload"
 	ctx, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
+	// closing the writer would save whatever was written, so it is
+	// canceled instead if the stream fails, to not leave a partial object.
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	wc := s.bucket.Object(path).If(storage.Conditions{
 		DoesNotExist: true,
-	}).NewWriter(ctx)
+	}).NewWriter(wctx)
 
 	// NOTE: content type is auto detected on GCP side and ACL defaults to public
 	// Once we support private storage buckets this may need refactoring
 	// unless there is a way to set the default perms in the project.
 	if _, err := io.Copy(wc, stream); err != nil {
+		cancel()
 		wc.Close()
 		return err
 	}
@@ -0,0 +1,40 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// SaveSum implements SumSaver
+func (s *Storage) SaveSum(ctx context.Context, module, version string, sum []byte) error {
+	const op errors.Op = "gcp.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	err := s.upload(ctx, config.PackageVersionedName(module, version, "sum"), bytes.NewReader(sum))
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
+
+// Sum implements SumGetter
+func (s *Storage) Sum(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "gcp.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sumReader, err := s.bucket.Object(config.PackageVersionedName(module, version, "sum")).NewReader(ctx)
+	if err != nil {
+		return nil, errors.E(op, err, getErrorKind(err), errors.M(module), errors.V(version))
+	}
+	sum, err := ioutil.ReadAll(sumReader)
+	sumReader.Close()
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return sum, nil
+}
@@ -0,0 +1,40 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// ZipURL implements the (./pkg/storage).ZipURLer interface with a
+// signed GET URL. URLs can only be signed with the key of a service
+// account, so with other credentials it returns a KindNotImplemented
+// error, and the zips are served by the proxy.
+func (s *Storage) ZipURL(ctx context.Context, module, version string, expiry time.Duration) (string, error) {
+	const op errors.Op = "gcp.ZipURL"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if s.signErr != nil {
+		return "", errors.E(op, fmt.Errorf("signing zip URLs requires a service account key: %v", s.signErr), errors.KindNotImplemented)
+	}
+	name := config.PackageVersionedName(module, version, "zip")
+	if _, err := s.bucket.Object(name).Attrs(ctx); err != nil {
+		return "", errors.E(op, err, getErrorKind(err), errors.M(module), errors.V(version))
+	}
+	u, err := storage.SignedURL(s.bucketName, name, &storage.SignedURLOptions{
+		GoogleAccessID: s.accessID,
+		PrivateKey:     s.privateKey,
+		Method:         http.MethodGet,
+		Expires:        time.Now().Add(expiry),
+	})
+	if err != nil {
+		return "", errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return u, nil
+}
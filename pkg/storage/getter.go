@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"io"
+	"time"
 )
 
 // Getter gets module metadata and its source from underlying storage
@@ -21,8 +22,12 @@ type SizeReadCloser interface {
 }
 
 // NewSizer is a helper wrapper to return an implementation
-// of ReadCloserSizer
+// of ReadCloserSizer. If rc can seek, so can the returned
+// SizeReadCloser, which lets parts of it be served.
 func NewSizer(rc io.ReadCloser, size int64) SizeReadCloser {
+	if seeker, ok := rc.(io.Seeker); ok {
+		return &sizeReadSeekCloser{sizeReadCloser{rc, size}, seeker}
+	}
 	return &sizeReadCloser{rc, size}
 }
 
@@ -34,3 +39,54 @@ type sizeReadCloser struct {
 func (zf *sizeReadCloser) Size() int64 {
 	return zf.size
 }
+
+type sizeReadSeekCloser struct {
+	sizeReadCloser
+	seeker io.Seeker
+}
+
+func (zf *sizeReadSeekCloser) Seek(offset int64, whence int) (int64, error) {
+	return zf.seeker.Seek(offset, whence)
+}
+
+// Validators is implemented by the SizeReadClosers of the backends
+// that get, along with a zip, a strong ETag of its content or the
+// time it was saved, so that it can be served conditionally without
+// more requests to the backend. Either may be empty.
+type Validators interface {
+	ETag() string
+	ModTime() time.Time
+}
+
+// NewValidatedSizer is like NewSizer, but the returned
+// SizeReadCloser also implements Validators.
+func NewValidatedSizer(rc io.ReadCloser, size int64, etag string, modtime time.Time) SizeReadCloser {
+	v := validators{etag: etag, modtime: modtime}
+	if seeker, ok := rc.(io.Seeker); ok {
+		return &validatedReadSeekCloser{sizeReadSeekCloser{sizeReadCloser{rc, size}, seeker}, v}
+	}
+	return &validatedReadCloser{sizeReadCloser{rc, size}, v}
+}
+
+type validators struct {
+	etag    string
+	modtime time.Time
+}
+
+func (v validators) ETag() string {
+	return v.etag
+}
+
+func (v validators) ModTime() time.Time {
+	return v.modtime
+}
+
+type validatedReadCloser struct {
+	sizeReadCloser
+	validators
+}
+
+type validatedReadSeekCloser struct {
+	sizeReadSeekCloser
+	validators
+}
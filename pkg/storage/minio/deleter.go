@@ -38,5 +38,13 @@ func (v *storageImpl) Delete(ctx context.Context, module, version string) error
 	if err != nil {
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
+
+	// removing an object that does not exist is not an
+	// error, so versions without a recorded sum are fine.
+	sumPath := fmt.Sprintf("%s/%s.sum", versionedPath, version)
+	err = v.minioClient.RemoveObject(v.bucketName, sumPath)
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
 	return nil
 }
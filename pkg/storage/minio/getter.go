@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
@@ -64,7 +65,11 @@ func (v *storageImpl) Zip(ctx context.Context, module, vsn string) (storage.Size
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
-	return storage.NewSizer(zipReader, oi.Size), nil
+	var etag string
+	if oi.ETag != "" {
+		etag = `"` + strings.Trim(oi.ETag, `"`) + `"`
+	}
+	return storage.NewValidatedSizer(zipReader, oi.Size, etag, oi.LastModified), nil
 }
 
 func transformNotFoundErr(op errors.Op, module, version string, err error) error {
@@ -1,11 +1,19 @@
 package minio
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/storage/compliance"
+	minio "github.com/minio/minio-go/v6"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBackend(t *testing.T) {
@@ -73,6 +81,38 @@ func TestNewStorageError(t *testing.T) {
 	}
 }
 
+func TestZipURL(t *testing.T) {
+	backend := getStorage(t)
+	defer backend.clear()
+	ctx := context.Background()
+	const mod, ver = "github.com/athens-artifacts/happy-path", "v1.0.0"
+	zip := []byte("zip of " + ver)
+	err := backend.Save(ctx, mod, ver, []byte("module "+mod), bytes.NewReader(zip), []byte(`{"Version":"`+ver+`"}`))
+	require.NoError(t, err)
+
+	u, err := backend.ZipURL(ctx, mod, ver, time.Minute)
+	require.NoError(t, err)
+	resp, err := http.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	got, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, zip, got)
+
+	_, err = backend.ZipURL(ctx, mod, "v1.1.0", time.Minute)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err))
+
+	// only a missing zip is not found: the proxy must not
+	// report a version as missing when minio fails.
+	denied := *backend
+	denied.minioClient, err = minio.New(os.Getenv("ATHENS_MINIO_ENDPOINT"), "minio", "wrong-secret", false)
+	require.NoError(t, err)
+	_, err = denied.ZipURL(ctx, mod, ver, time.Minute)
+	require.Error(t, err)
+	require.NotEqual(t, errors.KindNotFound, errors.Kind(err))
+}
+
 func BenchmarkBackend(b *testing.B) {
 	backend := getStorage(b)
 	compliance.RunBenchmarks(b, backend, backend.clear)
@@ -0,0 +1,40 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	minio "github.com/minio/minio-go/v6"
+)
+
+func (s *storageImpl) SaveSum(ctx context.Context, module, vsn string, sum []byte) error {
+	const op errors.Op = "minio.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sumPath := fmt.Sprintf("%s/%s.sum", s.versionLocation(module, vsn), vsn)
+	_, err := s.minioClient.PutObject(s.bucketName, sumPath, bytes.NewReader(sum), int64(len(sum)), minio.PutObjectOptions{})
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(vsn))
+	}
+	return nil
+}
+
+func (s *storageImpl) Sum(ctx context.Context, module, vsn string) ([]byte, error) {
+	const op errors.Op = "minio.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sumPath := fmt.Sprintf("%s/%s.sum", s.versionLocation(module, vsn), vsn)
+	sumReader, err := s.minioClient.GetObject(s.bucketName, sumPath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	sum, err := ioutil.ReadAll(sumReader)
+	if err != nil {
+		return nil, transformNotFoundErr(op, module, vsn, err)
+	}
+	return sum, nil
+}
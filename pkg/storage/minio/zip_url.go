@@ -0,0 +1,34 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	minio "github.com/minio/minio-go/v6"
+)
+
+// ZipURL implements the (./pkg/storage).ZipURLer interface
+// with a pre-signed GET URL.
+func (v *storageImpl) ZipURL(ctx context.Context, module, vsn string, expiry time.Duration) (string, error) {
+	const op errors.Op = "minio.ZipURL"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	zipPath := fmt.Sprintf("%s/source.zip", v.versionLocation(module, vsn))
+	_, err := v.minioClient.StatObject(v.bucketName, zipPath, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == minioErrorCodeNoSuchKey {
+		return "", errors.E(op, err, errors.KindNotFound, errors.M(module), errors.V(vsn))
+	}
+	if err != nil {
+		return "", errors.E(op, err, errors.M(module), errors.V(vsn))
+	}
+	u, err := v.minioClient.PresignedGetObject(v.bucketName, zipPath, expiry, url.Values{})
+	if err != nil {
+		return "", errors.E(op, err, errors.M(module), errors.V(vsn))
+	}
+	return u.String(), nil
+}
@@ -13,4 +13,5 @@ type Module struct {
 	Version string             `bson:"version"`
 	Mod     []byte             `bson:"mod"`
 	Info    []byte             `bson:"info"`
+	Sum     []byte             `bson:"sum,omitempty"`
 }
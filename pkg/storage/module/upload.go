@@ -16,7 +16,9 @@ const numFiles = 3
 // Uploader takes a stream and saves it to the blob store under a given path
 type Uploader func(ctx context.Context, path, contentType string, stream io.Reader) error
 
-// Upload saves .info, .mod and .zip files to the blob store in parallel.
+// Upload saves .mod and .zip files to the blob store in parallel, and then
+// the .info file, so that a version whose .mod or .zip failed to upload
+// has no .info file and is not found.
 // Returns multierror containing errors from all uploads and timeouts
 func Upload(ctx context.Context, module, version string, info, mod, zip io.Reader, uploader Uploader, timeout time.Duration) error {
 	const op errors.Op = "module.Upload"
@@ -43,17 +45,22 @@ func Upload(ctx context.Context, module, version string, info, mod, zip io.Reade
 			errChan <- fmt.Errorf("uploading %s.%s.%s failed: %s", module, version, ext, tctx.Err())
 		}
 	}
-	go saveOrAbort("info", "application/json", info)
 	go saveOrAbort("mod", "text/plain", mod)
 	go saveOrAbort("zip", "application/octet-stream", zip)
 
 	var errs error
-	for i := 0; i < numFiles; i++ {
+	for i := 0; i < numFiles-1; i++ {
 		err := <-errChan
 		if err != nil {
 			errs = multierror.Append(errs, err)
 		}
 	}
+	if errs == nil {
+		saveOrAbort("info", "application/json", info)
+		if err := <-errChan; err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
 	close(errChan)
 	if errs != nil {
 		return errors.E(op, errs)
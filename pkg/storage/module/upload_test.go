@@ -5,7 +5,10 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/ioutil"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/gobuffalo/envy"
@@ -33,9 +36,9 @@ func (u *UploadTests) TestUploadTimeout() {
 	rd := bytes.NewReader([]byte("123"))
 	err := Upload(context.Background(), "mx", "1.1.1", rd, rd, rd, uplWithTimeout, time.Second)
 	r.Error(err, "deleter returned at least one error")
-	r.Contains(err.Error(), "uploading mx.1.1.1.info failed: context deadline exceeded")
 	r.Contains(err.Error(), "uploading mx.1.1.1.zip failed: context deadline exceeded")
 	r.Contains(err.Error(), "uploading mx.1.1.1.mod failed: context deadline exceeded")
+	r.NotContains(err.Error(), "mx.1.1.1.info", "the .info file must not be uploaded after a failure")
 }
 
 func (u *UploadTests) TestUploadError() {
@@ -46,6 +49,27 @@ func (u *UploadTests) TestUploadError() {
 	r.Contains(err.Error(), "some err")
 }
 
+func (u *UploadTests) TestUploadFailedZip() {
+	r := u.Require()
+	var (
+		mu       sync.Mutex
+		uploaded []string
+	)
+	upl := func(ctx context.Context, path, contentType string, stream io.Reader) error {
+		if _, err := ioutil.ReadAll(stream); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		uploaded = append(uploaded, path)
+		return nil
+	}
+	zip := io.MultiReader(bytes.NewReader([]byte("partial zip")), iotest.TimeoutReader(bytes.NewReader([]byte("zip"))))
+	err := Upload(context.Background(), "mx", "1.1.1", bytes.NewReader([]byte("info")), bytes.NewReader([]byte("mod")), zip, upl, time.Second)
+	r.Error(err)
+	r.Equal([]string{"mx/@v/1.1.1.mod"}, uploaded, "the .info file must only be uploaded once the others are")
+}
+
 func uplWithTimeout(ctx context.Context, path, contentType string, stream io.Reader) error {
 	time.Sleep(2 * time.Second)
 	return nil
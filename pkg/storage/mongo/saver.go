@@ -41,10 +41,13 @@ func (s *ModuleStore) Save(ctx context.Context, module, version string, mod []by
 
 	numBytesWritten, err := io.Copy(uStream, zip)
 
+	// closing the stream would save a partial zip, so it is aborted.
 	if err != nil {
+		uStream.Abort()
 		return errors.E(op, err, errors.M(module), errors.V(version))
 	}
 	if numBytesWritten <= 0 {
+		uStream.Abort()
 		e := fmt.Errorf("copied %d bytes to Mongo GridFS", numBytesWritten)
 		return errors.E(op, e, errors.M(module), errors.V(version))
 	}
@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SaveSum implements the (./pkg/storage).SumSaver interface
+func (s *ModuleStore) SaveSum(ctx context.Context, module, version string, sum []byte) error {
+	const op errors.Op = "mongo.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	c := s.client.Database(s.db).Collection(s.coll)
+	tctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := c.UpdateOne(tctx, bson.M{"module": module, "version": version}, bson.M{"$set": bson.M{"sum": sum}})
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if res.MatchedCount == 0 {
+		return errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+	}
+	return nil
+}
+
+// Sum implements the (./pkg/storage).SumGetter interface
+func (s *ModuleStore) Sum(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "mongo.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	result, err := query(ctx, s, module, version)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	if len(result.Sum) == 0 {
+		return nil, errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+	}
+	return result.Sum, nil
+}
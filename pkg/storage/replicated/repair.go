@@ -0,0 +1,90 @@
+package replicated
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/migrate"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+const repairPageSize = 100
+
+// RepairResult is the outcome of a repair.
+type RepairResult struct {
+	Checked int               `json:"checked"`
+	Copied  int               `json:"copied"`
+	Failed  []migrate.Failure `json:"failed"`
+}
+
+// StartRepair repairs the secondaries every interval until
+// ctx is done. Errors are logged using the entry in ctx.
+func (s *Storage) StartRepair(ctx context.Context, interval time.Duration) {
+	lggr := log.EntryFromContext(ctx)
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				res, err := s.Repair(ctx)
+				if err != nil {
+					lggr.SystemErr(err)
+					continue
+				}
+				if res.Copied > 0 || len(res.Failed) > 0 {
+					lggr.Infof("repair copied %d module versions to secondaries, %d failed", res.Copied, len(res.Failed))
+				}
+			}
+		}
+	}()
+}
+
+// Repair walks the catalog of the primary and copies
+// the versions that a secondary is missing to it.
+func (s *Storage) Repair(ctx context.Context) (*RepairResult, error) {
+	const op errors.Op = "replicated.Repair"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	cataloger, ok := s.primary.(storage.Cataloger)
+	if !ok {
+		return nil, errors.E(op, "primary storage does not implement a catalog", errors.KindNotImplemented)
+	}
+	res := &RepairResult{Failed: []migrate.Failure{}}
+	token := ""
+	for {
+		page, next, err := cataloger.Catalog(ctx, token, repairPageSize)
+		if err != nil {
+			return res, errors.E(op, err)
+		}
+		for _, p := range page {
+			if err := ctx.Err(); err != nil {
+				return res, errors.E(op, err)
+			}
+			res.Checked++
+			for _, sec := range s.secondaries {
+				exists, err := storage.WithChecker(sec).Exists(ctx, p.Module, p.Version)
+				if err == nil && exists {
+					continue
+				}
+				if err == nil {
+					err = migrate.CopyVersion(ctx, s.primary, sec, p.Module, p.Version)
+				}
+				if err != nil {
+					res.Failed = append(res.Failed, migrate.Failure{Module: p.Module, Version: p.Version, Error: err.Error()})
+					continue
+				}
+				res.Copied++
+			}
+		}
+		if next == "" {
+			return res, nil
+		}
+		token = next
+	}
+}
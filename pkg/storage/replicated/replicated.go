@@ -0,0 +1,295 @@
+// Package replicated provides a storage backend that saves every module
+// version to a primary backend and replicates it to secondary backends,
+// either before Save returns or in the background. Reads fail over to
+// the secondaries when the primary fails, and Repair copies the versions
+// that a secondary is missing from the primary.
+package replicated
+
+import (
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/migrate"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+// queueSize is the number of versions that can wait to be
+// replicated in the background. Versions that do not fit
+// in the queue are only replicated by the next Repair.
+const queueSize = 1000
+
+// job is a version to replicate in the background,
+// or only its go.sum lines if sum is not nil.
+type job struct {
+	mod, ver string
+	sum      []byte
+	// lggr is the log entry of the request that saved
+	// the version, used to log replication errors.
+	lggr log.Entry
+}
+
+// Storage is a storage.Backend that replicates
+// a primary backend to secondary backends.
+type Storage struct {
+	primary     storage.Backend
+	secondaries []storage.Backend
+	queue       chan job
+}
+
+// New returns a Storage that saves to primary and replicates to
+// secondaries. If async is true, Save returns once the version is
+// saved to primary, and it is replicated in the background, with
+// errors logged using the entry in the context given to Save.
+// Otherwise Save returns an error if the version could not be
+// saved to every backend.
+func New(primary storage.Backend, secondaries []storage.Backend, async bool) *Storage {
+	s := &Storage{primary: primary, secondaries: secondaries}
+	if async {
+		s.queue = make(chan job, queueSize)
+		go s.replicateQueued()
+	}
+	return s
+}
+
+func (s *Storage) replicateQueued() {
+	const op errors.Op = "replicated.replicateQueued"
+	for j := range s.queue {
+		ctx := log.SetEntryInContext(context.Background(), j.lggr)
+		var err error
+		if j.sum != nil {
+			err = s.replicateSum(ctx, j.mod, j.ver, j.sum)
+		} else {
+			err = s.replicate(ctx, j.mod, j.ver)
+		}
+		if err != nil {
+			j.lggr.SystemErr(errors.E(op, err))
+		}
+	}
+}
+
+// replicate copies a version from the primary to every secondary.
+func (s *Storage) replicate(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "replicated.replicate"
+	var firstErr error
+	for _, sec := range s.secondaries {
+		err := migrate.CopyVersion(ctx, s.primary, sec, mod, ver)
+		if err != nil && !errors.Is(err, errors.KindAlreadyExists) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return errors.E(op, firstErr, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
+
+// replicateSum saves go.sum lines to every secondary.
+func (s *Storage) replicateSum(ctx context.Context, mod, ver string, sum []byte) error {
+	const op errors.Op = "replicated.replicateSum"
+	for _, sec := range s.secondaries {
+		if ss, ok := sec.(storage.SumSaver); ok {
+			if err := ss.SaveSum(ctx, mod, ver, sum); err != nil {
+				return errors.E(op, err, errors.M(mod), errors.V(ver))
+			}
+		}
+	}
+	return nil
+}
+
+// failover reports whether a read that failed
+// on the primary should be tried on a secondary.
+func failover(err error) bool {
+	return err != nil && !errors.IsNotFoundErr(err)
+}
+
+// List implements storage.Lister.
+func (s *Storage) List(ctx context.Context, mod string) ([]string, error) {
+	const op errors.Op = "replicated.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	vers, err := s.primary.List(ctx, mod)
+	for i := 0; failover(err) && i < len(s.secondaries); i++ {
+		vers, err = s.secondaries[i].List(ctx, mod)
+	}
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod))
+	}
+	return vers, nil
+}
+
+// Info implements storage.Getter.
+func (s *Storage) Info(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "replicated.Info"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	info, err := s.primary.Info(ctx, mod, ver)
+	for i := 0; failover(err) && i < len(s.secondaries); i++ {
+		info, err = s.secondaries[i].Info(ctx, mod, ver)
+	}
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return info, nil
+}
+
+// GoMod implements storage.Getter.
+func (s *Storage) GoMod(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "replicated.GoMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	goMod, err := s.primary.GoMod(ctx, mod, ver)
+	for i := 0; failover(err) && i < len(s.secondaries); i++ {
+		goMod, err = s.secondaries[i].GoMod(ctx, mod, ver)
+	}
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return goMod, nil
+}
+
+// Zip implements storage.Getter.
+func (s *Storage) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	const op errors.Op = "replicated.Zip"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	zip, err := s.primary.Zip(ctx, mod, ver)
+	for i := 0; failover(err) && i < len(s.secondaries); i++ {
+		zip, err = s.secondaries[i].Zip(ctx, mod, ver)
+	}
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return zip, nil
+}
+
+// Exists implements storage.Checker.
+func (s *Storage) Exists(ctx context.Context, mod, ver string) (bool, error) {
+	const op errors.Op = "replicated.Exists"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	exists, err := storage.WithChecker(s.primary).Exists(ctx, mod, ver)
+	for i := 0; failover(err) && i < len(s.secondaries); i++ {
+		exists, err = storage.WithChecker(s.secondaries[i]).Exists(ctx, mod, ver)
+	}
+	if err != nil {
+		return false, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return exists, nil
+}
+
+// Sum implements storage.SumGetter.
+func (s *Storage) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "replicated.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	sum, err := getSum(ctx, s.primary, mod, ver)
+	for i := 0; failover(err) && i < len(s.secondaries); i++ {
+		sum, err = getSum(ctx, s.secondaries[i], mod, ver)
+	}
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return sum, nil
+}
+
+func getSum(ctx context.Context, s storage.Backend, mod, ver string) ([]byte, error) {
+	const op errors.Op = "replicated.getSum"
+	sg, ok := s.(storage.SumGetter)
+	if !ok {
+		return nil, errors.E(op, "storage does not store go.sum lines", errors.KindNotFound)
+	}
+	return sg.Sum(ctx, mod, ver)
+}
+
+// Save implements storage.Saver. The version is saved to the
+// primary, and then copied from there to the secondaries.
+func (s *Storage) Save(ctx context.Context, mod, ver string, goMod []byte, zip io.Reader, info []byte) error {
+	const op errors.Op = "replicated.Save"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if err := s.primary.Save(ctx, mod, ver, goMod, zip, info); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if s.queue != nil {
+		s.enqueue(job{mod: mod, ver: ver, lggr: log.EntryFromContext(ctx)})
+		return nil
+	}
+	if err := s.replicate(ctx, mod, ver); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// SaveSum implements storage.SumSaver.
+func (s *Storage) SaveSum(ctx context.Context, mod, ver string, sum []byte) error {
+	const op errors.Op = "replicated.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if ss, ok := s.primary.(storage.SumSaver); ok {
+		if err := ss.SaveSum(ctx, mod, ver, sum); err != nil {
+			return errors.E(op, err, errors.M(mod), errors.V(ver))
+		}
+	}
+	if s.queue != nil {
+		s.enqueue(job{mod: mod, ver: ver, sum: sum, lggr: log.EntryFromContext(ctx)})
+		return nil
+	}
+	if err := s.replicateSum(ctx, mod, ver, sum); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+func (s *Storage) enqueue(j job) {
+	const op errors.Op = "replicated.enqueue"
+	select {
+	case s.queue <- j:
+	default:
+		j.lggr.SystemErr(errors.E(op, "replication queue is full, the version will be replicated by the next repair", errors.M(j.mod), errors.V(j.ver)))
+	}
+}
+
+// Delete implements storage.Deleter. The version is deleted from
+// every backend, and a KindNotFound error is only returned if no
+// backend had it.
+func (s *Storage) Delete(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "replicated.Delete"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	found := false
+	for _, b := range append([]storage.Backend{s.primary}, s.secondaries...) {
+		err := b.Delete(ctx, mod, ver)
+		if errors.IsNotFoundErr(err) {
+			continue
+		}
+		if err != nil {
+			return errors.E(op, err, errors.M(mod), errors.V(ver))
+		}
+		found = true
+	}
+	if !found {
+		return errors.E(op, errors.M(mod), errors.V(ver), errors.KindNotFound)
+	}
+	return nil
+}
+
+// Catalog implements storage.Cataloger by
+// listing the versions of the primary.
+func (s *Storage) Catalog(ctx context.Context, token string, pageSize int) ([]paths.AllPathParams, string, error) {
+	const op errors.Op = "replicated.Catalog"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	cataloger, ok := s.primary.(storage.Cataloger)
+	if !ok {
+		return nil, "", errors.E(op, "primary storage does not implement a catalog", errors.KindNotImplemented)
+	}
+	res, next, err := cataloger.Catalog(ctx, token, pageSize)
+	if err != nil {
+		return nil, "", errors.E(op, err)
+	}
+	return res, next, nil
+}
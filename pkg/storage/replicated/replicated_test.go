@@ -0,0 +1,125 @@
+package replicated
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/compliance"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const testMod = "github.com/gomods/athens"
+
+func newFs(t *testing.T) (storage.Backend, afero.Fs) {
+	t.Helper()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	return s, memFs
+}
+
+func save(t *testing.T, s storage.Backend, ver string) {
+	t.Helper()
+	require.NoError(t, s.Save(context.Background(), testMod, ver, []byte("module "+testMod), bytes.NewReader([]byte("zip")), []byte("{}")))
+}
+
+func exists(t *testing.T, s storage.Backend, ver string) bool {
+	t.Helper()
+	ok, err := storage.WithChecker(s).Exists(context.Background(), testMod, ver)
+	require.NoError(t, err)
+	return ok
+}
+
+func TestBackend(t *testing.T) {
+	primary, primaryFs := newFs(t)
+	secondary, secondaryFs := newFs(t)
+	b := New(primary, []storage.Backend{secondary}, false)
+	clear := func() error {
+		for _, f := range []afero.Fs{primaryFs, secondaryFs} {
+			if err := f.RemoveAll("/storage"); err != nil {
+				return err
+			}
+			if err := f.MkdirAll("/storage", 0777); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	compliance.RunTests(t, b, clear)
+}
+
+func TestSyncReplication(t *testing.T) {
+	primary, _ := newFs(t)
+	secondary, _ := newFs(t)
+	b := New(primary, []storage.Backend{secondary}, false)
+	save(t, b, "v1.0.0")
+	require.True(t, exists(t, primary, "v1.0.0"))
+	require.True(t, exists(t, secondary, "v1.0.0"))
+
+	require.NoError(t, b.SaveSum(context.Background(), testMod, "v1.0.0", []byte("sum")))
+	sum, err := secondary.(storage.SumGetter).Sum(context.Background(), testMod, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "sum", string(sum))
+
+	require.NoError(t, b.Delete(context.Background(), testMod, "v1.0.0"))
+	require.False(t, exists(t, secondary, "v1.0.0"))
+}
+
+func TestAsyncReplication(t *testing.T) {
+	primary, _ := newFs(t)
+	secondary, _ := newFs(t)
+	b := New(primary, []storage.Backend{secondary}, true)
+	save(t, b, "v1.0.0")
+	require.True(t, exists(t, primary, "v1.0.0"))
+	require.Eventually(t, func() bool {
+		return exists(t, secondary, "v1.0.0")
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// brokenStorage fails every read with an unexpected error.
+type brokenStorage struct {
+	storage.Backend
+}
+
+func (b *brokenStorage) Info(ctx context.Context, mod, ver string) ([]byte, error) {
+	return nil, errors.E("brokenStorage.Info", fmt.Errorf("connection refused"))
+}
+
+func TestFailover(t *testing.T) {
+	primary, _ := newFs(t)
+	secondary, _ := newFs(t)
+	save(t, secondary, "v1.0.0")
+
+	b := New(&brokenStorage{primary}, []storage.Backend{secondary}, false)
+	info, err := b.Info(context.Background(), testMod, "v1.0.0")
+	require.NoError(t, err, "reads must fail over to the secondary")
+	require.Equal(t, "{}", string(info))
+
+	b = New(primary, []storage.Backend{secondary}, false)
+	_, err = b.Info(context.Background(), testMod, "v1.0.0")
+	require.True(t, errors.IsNotFoundErr(err), "a version missing from the primary must not be read from a secondary")
+}
+
+func TestRepair(t *testing.T) {
+	primary, _ := newFs(t)
+	secondary, _ := newFs(t)
+	save(t, primary, "v1.0.0")
+	save(t, primary, "v1.1.0")
+	save(t, secondary, "v1.1.0")
+
+	b := New(primary, []storage.Backend{secondary}, false)
+	res, err := b.Repair(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Checked)
+	require.Equal(t, 1, res.Copied)
+	require.Empty(t, res.Failed)
+	require.True(t, exists(t, secondary, "v1.0.0"))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gomods/athens/pkg/config"
 	"github.com/gomods/athens/pkg/errors"
 	"github.com/gomods/athens/pkg/observ"
 	modupl "github.com/gomods/athens/pkg/storage/module"
@@ -25,6 +26,11 @@ func (s *Storage) Delete(ctx context.Context, module, version string) error {
 		return errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
 	}
 
+	// deleting a key that does not exist succeeds, so the
+	// sum can be removed whether it was recorded or not.
+	if err := s.remove(ctx, config.PackageVersionedName(module, version, "sum")); err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
 	return modupl.Delete(ctx, module, version, s.remove, s.timeout)
 }
 
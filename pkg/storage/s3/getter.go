@@ -104,5 +104,5 @@ func (s *Storage) open(ctx context.Context, path string) (storage.SizeReadCloser
 	if goo.ContentLength != nil {
 		size = *goo.ContentLength
 	}
-	return storage.NewSizer(goo.Body, size), nil
+	return storage.NewValidatedSizer(goo.Body, size, aws.StringValue(goo.ETag), aws.TimeValue(goo.LastModified)), nil
 }
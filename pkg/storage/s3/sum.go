@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// SaveSum implements the (./pkg/storage).SumSaver interface.
+func (s *Storage) SaveSum(ctx context.Context, module, version string, sum []byte) error {
+	const op errors.Op = "s3.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	err := s.upload(ctx, config.PackageVersionedName(module, version, "sum"), "text/plain", bytes.NewReader(sum))
+	if err != nil {
+		return errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return nil
+}
+
+// Sum implements the (./pkg/storage).SumGetter interface
+func (s *Storage) Sum(ctx context.Context, module, version string) ([]byte, error) {
+	const op errors.Op = "s3.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	getParams := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(config.PackageVersionedName(module, version, "sum")),
+	}
+	goo, err := s.s3API.GetObjectWithContext(ctx, getParams)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+		}
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	defer goo.Body.Close()
+	sum, err := ioutil.ReadAll(goo.Body)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return sum, nil
+}
@@ -0,0 +1,37 @@
+package s3
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+)
+
+// ZipURL implements the (./pkg/storage).ZipURLer interface
+// with a pre-signed GET URL.
+func (s *Storage) ZipURL(ctx context.Context, module, version string, expiry time.Duration) (string, error) {
+	const op errors.Op = "s3.ZipURL"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	exists, err := s.Exists(ctx, module, version)
+	if err != nil {
+		return "", errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	if !exists {
+		return "", errors.E(op, errors.M(module), errors.V(version), errors.KindNotFound)
+	}
+
+	req, _ := s.s3API.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(config.PackageVersionedName(module, version, "zip")),
+	})
+	u, err := req.Presign(expiry)
+	if err != nil {
+		return "", errors.E(op, err, errors.M(module), errors.V(version))
+	}
+	return u, nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SumSaver is the interface that saves the go.sum lines
+// of a module version next to its source
+type SumSaver interface {
+	// SaveSum stores sum, which holds the go.sum lines of
+	// the module at the given version
+	SaveSum(ctx context.Context, module, version string, sum []byte) error
+}
+
+// SumGetter is the interface that gets the go.sum lines
+// of a module version from the backing storage
+type SumGetter interface {
+	// Sum returns the go.sum lines of the module at the given
+	// version, or an error of KindNotFound if none were saved
+	Sum(ctx context.Context, module, version string) ([]byte, error)
+}
+
+// SumLines formats the hashes of a module version the way they
+// appear in a go.sum file. Empty hashes are left out, so that the
+// result is empty if neither hash is known.
+func SumLines(module, version, sum, goModSum string) []byte {
+	var lines string
+	if sum != "" {
+		lines += fmt.Sprintf("%s %s %s\n", module, version, sum)
+	}
+	if goModSum != "" {
+		lines += fmt.Sprintf("%s %s/go.mod %s\n", module, version, goModSum)
+	}
+	return []byte(lines)
+}
+
+// ParseSumLines returns the hashes of the module zip and go.mod
+// file that sum, as formatted by SumLines, records for the module
+// at the given version. Missing hashes are returned empty.
+func ParseSumLines(sum []byte, module, version string) (zipSum, goModSum string) {
+	for _, line := range strings.Split(string(sum), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != module {
+			continue
+		}
+		switch fields[1] {
+		case version:
+			zipSum = fields[2]
+		case version + "/go.mod":
+			goModSum = fields[2]
+		}
+	}
+	return zipSum, goModSum
+}
@@ -0,0 +1,82 @@
+package tiered
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru tracks the versions in the local tier and
+// the total size of their files, least recently
+// used last.
+type lru struct {
+	maxSize int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	mod, ver string
+	size     int64
+}
+
+func newLRU(maxSize int64) *lru {
+	return &lru{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func lruKey(mod, ver string) string {
+	return mod + "@" + ver
+}
+
+// touch marks a version as the most recently used.
+func (l *lru) touch(mod, ver string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.entries[lruKey(mod, ver)]; ok {
+		l.order.MoveToFront(el)
+	}
+}
+
+// add records a version of the given size as the most recently
+// used, and returns the versions that must be evicted so that
+// the total size does not exceed the maximum.
+func (l *lru) add(mod, ver string, size int64) []*lruEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := lruKey(mod, ver)
+	if el, ok := l.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		l.size += size - e.size
+		e.size = size
+		l.order.MoveToFront(el)
+	} else {
+		l.entries[key] = l.order.PushFront(&lruEntry{mod: mod, ver: ver, size: size})
+		l.size += size
+	}
+	var evicted []*lruEntry
+	for l.size > l.maxSize && l.order.Len() > 0 {
+		e := l.order.Remove(l.order.Back()).(*lruEntry)
+		delete(l.entries, lruKey(e.mod, e.ver))
+		l.size -= e.size
+		evicted = append(evicted, e)
+	}
+	return evicted
+}
+
+// remove forgets a version.
+func (l *lru) remove(mod, ver string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := lruKey(mod, ver)
+	if el, ok := l.entries[key]; ok {
+		l.size -= el.Value.(*lruEntry).size
+		l.order.Remove(el)
+		delete(l.entries, key)
+	}
+}
@@ -0,0 +1,338 @@
+// Package tiered provides a storage backend that keeps a bounded local
+// tier, such as a disk or memory storage, in front of a remote backend,
+// such as an object store. Reads are served from the local tier when
+// possible, and zip reads that miss it fill it from the remote backend.
+// Writes go to both tiers. The local tier evicts the least recently used
+// versions once the total size of their files exceeds a maximum.
+package tiered
+
+import (
+	"context"
+	"io"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/paths"
+	"github.com/gomods/athens/pkg/storage"
+)
+
+type storageImpl struct {
+	local  storage.Backend
+	remote storage.Backend
+	lru    *lru
+}
+
+// New returns a storage backend that caches up to maxSize bytes of
+// the module versions in remote in local. The versions already in
+// local count towards maxSize if local implements storage.Cataloger,
+// and are deleted otherwise, since their size cannot be tracked.
+func New(ctx context.Context, local, remote storage.Backend, maxSize int64) (storage.Backend, error) {
+	const op errors.Op = "tiered.New"
+	s := &storageImpl{local: local, remote: remote, lru: newLRU(maxSize)}
+	if err := s.loadLocal(ctx); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return s, nil
+}
+
+// loadLocal records the versions already in the local tier.
+func (s *storageImpl) loadLocal(ctx context.Context) error {
+	const op errors.Op = "tiered.loadLocal"
+	cataloger, ok := s.local.(storage.Cataloger)
+	if !ok {
+		return nil
+	}
+	token := ""
+	for {
+		page, next, err := cataloger.Catalog(ctx, token, 1000)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		for _, p := range page {
+			size, err := s.localSize(ctx, p.Module, p.Version)
+			if err != nil {
+				return errors.E(op, err)
+			}
+			s.evict(ctx, s.lru.add(p.Module, p.Version, size))
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+func (s *storageImpl) localSize(ctx context.Context, mod, ver string) (int64, error) {
+	const op errors.Op = "tiered.localSize"
+	info, err := s.local.Info(ctx, mod, ver)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+	goMod, err := s.local.GoMod(ctx, mod, ver)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+	zip, err := s.local.Zip(ctx, mod, ver)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+	zip.Close()
+	return int64(len(info)+len(goMod)) + zip.Size(), nil
+}
+
+// evict deletes the given versions from the local tier.
+func (s *storageImpl) evict(ctx context.Context, evicted []*lruEntry) {
+	const op errors.Op = "tiered.evict"
+	for _, e := range evicted {
+		if err := s.local.Delete(ctx, e.mod, e.ver); err != nil && !errors.IsNotFoundErr(err) {
+			log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(e.mod), errors.V(e.ver)))
+		}
+	}
+}
+
+// localErr logs errors of the local tier other than not found ones,
+// which are expected. The caller falls back to the remote backend.
+func localErr(ctx context.Context, op errors.Op, err error) {
+	if !errors.IsNotFoundErr(err) {
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err))
+	}
+}
+
+// List only asks the remote backend, which
+// holds every version of the module.
+func (s *storageImpl) List(ctx context.Context, mod string) ([]string, error) {
+	const op errors.Op = "tiered.List"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	vers, err := s.remote.List(ctx, mod)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod))
+	}
+	return vers, nil
+}
+
+func (s *storageImpl) Info(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "tiered.Info"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	info, err := s.local.Info(ctx, mod, ver)
+	if err == nil {
+		s.lru.touch(mod, ver)
+		return info, nil
+	}
+	localErr(ctx, op, err)
+	info, err = s.remote.Info(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return info, nil
+}
+
+func (s *storageImpl) GoMod(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "tiered.GoMod"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	goMod, err := s.local.GoMod(ctx, mod, ver)
+	if err == nil {
+		s.lru.touch(mod, ver)
+		return goMod, nil
+	}
+	localErr(ctx, op, err)
+	goMod, err = s.remote.GoMod(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return goMod, nil
+}
+
+// Zip serves the zip from the local tier, after filling it
+// from the remote backend if needed. If the local tier cannot
+// be filled, the zip is served from the remote backend.
+func (s *storageImpl) Zip(ctx context.Context, mod, ver string) (storage.SizeReadCloser, error) {
+	const op errors.Op = "tiered.Zip"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	zip, err := s.local.Zip(ctx, mod, ver)
+	if err == nil {
+		s.lru.touch(mod, ver)
+		return zip, nil
+	}
+	localErr(ctx, op, err)
+
+	info, err := s.remote.Info(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	goMod, err := s.remote.GoMod(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	zip, err = s.remote.Zip(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	err = s.local.Save(ctx, mod, ver, goMod, zip, info)
+	zip.Close()
+	if err == nil {
+		s.copySum(ctx, mod, ver)
+		zip, err = s.local.Zip(ctx, mod, ver)
+		if err == nil {
+			s.evict(ctx, s.lru.add(mod, ver, int64(len(info)+len(goMod))+zip.Size()))
+			return zip, nil
+		}
+		s.deleteLocal(ctx, mod, ver)
+	}
+	log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(mod), errors.V(ver)))
+	zip, err = s.remote.Zip(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return zip, nil
+}
+
+// copySum copies the go.sum lines of a version
+// from the remote backend to the local tier.
+func (s *storageImpl) copySum(ctx context.Context, mod, ver string) {
+	const op errors.Op = "tiered.copySum"
+	sg, ok := s.remote.(storage.SumGetter)
+	if !ok {
+		return
+	}
+	ss, ok := s.local.(storage.SumSaver)
+	if !ok {
+		return
+	}
+	sum, err := sg.Sum(ctx, mod, ver)
+	if err == nil {
+		err = ss.SaveSum(ctx, mod, ver, sum)
+	}
+	if err != nil && !errors.IsNotFoundErr(err) {
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(mod), errors.V(ver)))
+	}
+}
+
+// Save writes the version to the local tier first, and then
+// copies it from there to the remote backend, so that the zip
+// only has to be read once. The version is removed from the
+// local tier if it cannot be saved to the remote backend.
+func (s *storageImpl) Save(ctx context.Context, mod, ver string, goMod []byte, zip io.Reader, info []byte) error {
+	const op errors.Op = "tiered.Save"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if err := s.local.Save(ctx, mod, ver, goMod, zip, info); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	localZip, err := s.local.Zip(ctx, mod, ver)
+	if err != nil {
+		s.deleteLocal(ctx, mod, ver)
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	size := int64(len(info)+len(goMod)) + localZip.Size()
+	err = s.remote.Save(ctx, mod, ver, goMod, localZip, info)
+	localZip.Close()
+	if err != nil {
+		s.deleteLocal(ctx, mod, ver)
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	s.evict(ctx, s.lru.add(mod, ver, size))
+	return nil
+}
+
+func (s *storageImpl) deleteLocal(ctx context.Context, mod, ver string) {
+	const op errors.Op = "tiered.deleteLocal"
+	s.lru.remove(mod, ver)
+	if err := s.local.Delete(ctx, mod, ver); err != nil && !errors.IsNotFoundErr(err) {
+		log.EntryFromContext(ctx).SystemErr(errors.E(op, err, errors.M(mod), errors.V(ver)))
+	}
+}
+
+// Delete deletes the version from both tiers. It returns a
+// KindNotFound error if the remote backend does not have it.
+func (s *storageImpl) Delete(ctx context.Context, mod, ver string) error {
+	const op errors.Op = "tiered.Delete"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	s.lru.remove(mod, ver)
+	if err := s.local.Delete(ctx, mod, ver); err != nil && !errors.IsNotFoundErr(err) {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	if err := s.remote.Delete(ctx, mod, ver); err != nil {
+		return errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return nil
+}
+
+// Exists implements storage.Checker.
+func (s *storageImpl) Exists(ctx context.Context, mod, ver string) (bool, error) {
+	const op errors.Op = "tiered.Exists"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if exists, err := storage.WithChecker(s.local).Exists(ctx, mod, ver); err == nil && exists {
+		return true, nil
+	}
+	exists, err := storage.WithChecker(s.remote).Exists(ctx, mod, ver)
+	if err != nil {
+		return false, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return exists, nil
+}
+
+// Catalog implements storage.Cataloger by
+// listing the versions of the remote backend.
+func (s *storageImpl) Catalog(ctx context.Context, token string, pageSize int) ([]paths.AllPathParams, string, error) {
+	const op errors.Op = "tiered.Catalog"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	cataloger, ok := s.remote.(storage.Cataloger)
+	if !ok {
+		return nil, "", errors.E(op, "remote storage does not implement a catalog", errors.KindNotImplemented)
+	}
+	res, next, err := cataloger.Catalog(ctx, token, pageSize)
+	if err != nil {
+		return nil, "", errors.E(op, err)
+	}
+	return res, next, nil
+}
+
+// Sum implements storage.SumGetter.
+func (s *storageImpl) Sum(ctx context.Context, mod, ver string) ([]byte, error) {
+	const op errors.Op = "tiered.Sum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if sg, ok := s.local.(storage.SumGetter); ok {
+		sum, err := sg.Sum(ctx, mod, ver)
+		if err == nil {
+			return sum, nil
+		}
+		localErr(ctx, op, err)
+	}
+	sg, ok := s.remote.(storage.SumGetter)
+	if !ok {
+		return nil, errors.E(op, "remote storage does not store go.sum lines", errors.KindNotFound)
+	}
+	sum, err := sg.Sum(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(mod), errors.V(ver))
+	}
+	return sum, nil
+}
+
+// SaveSum implements storage.SumSaver. The go.sum lines
+// are only saved to the local tier if it has the version.
+func (s *storageImpl) SaveSum(ctx context.Context, mod, ver string, sum []byte) error {
+	const op errors.Op = "tiered.SaveSum"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if ss, ok := s.remote.(storage.SumSaver); ok {
+		if err := ss.SaveSum(ctx, mod, ver, sum); err != nil {
+			return errors.E(op, err, errors.M(mod), errors.V(ver))
+		}
+	}
+	if ss, ok := s.local.(storage.SumSaver); ok {
+		if err := ss.SaveSum(ctx, mod, ver, sum); err != nil {
+			localErr(ctx, op, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package tiered
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/compliance"
+	"github.com/gomods/athens/pkg/storage/fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func newFs(t *testing.T) (storage.Backend, afero.Fs) {
+	t.Helper()
+	memFs := afero.NewMemMapFs()
+	require.NoError(t, memFs.MkdirAll("/storage", 0777))
+	s, err := fs.NewStorage("/storage", memFs)
+	require.NoError(t, err)
+	return s, memFs
+}
+
+func TestBackend(t *testing.T) {
+	local, localFs := newFs(t)
+	remote, remoteFs := newFs(t)
+	b, err := New(context.Background(), local, remote, 1<<20)
+	require.NoError(t, err)
+	clear := func() error {
+		b.(*storageImpl).lru = newLRU(1 << 20)
+		for _, f := range []afero.Fs{localFs, remoteFs} {
+			if err := f.RemoveAll("/storage"); err != nil {
+				return err
+			}
+			if err := f.MkdirAll("/storage", 0777); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	compliance.RunTests(t, b, clear)
+}
+
+func save(t *testing.T, s storage.Backend, mod, ver, zip string) {
+	t.Helper()
+	require.NoError(t, s.Save(context.Background(), mod, ver, []byte("module "+mod), bytes.NewReader([]byte(zip)), []byte("{}")))
+}
+
+func exists(t *testing.T, s storage.Backend, mod, ver string) bool {
+	t.Helper()
+	ok, err := storage.WithChecker(s).Exists(context.Background(), mod, ver)
+	require.NoError(t, err)
+	return ok
+}
+
+func TestEviction(t *testing.T) {
+	const mod = "github.com/gomods/athens"
+	ctx := context.Background()
+	local, _ := newFs(t)
+	remote, _ := newFs(t)
+	zip := string(make([]byte, 100))
+	// every version takes 100 bytes of zip, 2 of .info and
+	// 31 of go.mod, so only two of them fit in the local tier.
+	b, err := New(ctx, local, remote, 300)
+	require.NoError(t, err)
+	save(t, b, mod, "v1.0.0", zip)
+	save(t, b, mod, "v1.1.0", zip)
+	// v1.0.0 is now the most recently used
+	_, err = b.Info(ctx, mod, "v1.0.0")
+	require.NoError(t, err)
+	save(t, b, mod, "v1.2.0", zip)
+
+	require.True(t, exists(t, local, mod, "v1.0.0"))
+	require.False(t, exists(t, local, mod, "v1.1.0"), "the least recently used version must be evicted")
+	require.True(t, exists(t, local, mod, "v1.2.0"))
+	for _, ver := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		require.True(t, exists(t, remote, mod, ver), "writes must go to every tier")
+	}
+
+	// reading the zip of v1.1.0 fills the local tier
+	// again and evicts v1.0.0
+	rc, err := b.Zip(ctx, mod, "v1.1.0")
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	require.Equal(t, zip, string(got))
+	require.True(t, exists(t, local, mod, "v1.1.0"))
+	require.False(t, exists(t, local, mod, "v1.0.0"))
+}
+
+func TestNewLoadsLocalTier(t *testing.T) {
+	const mod = "github.com/gomods/athens"
+	ctx := context.Background()
+	local, _ := newFs(t)
+	remote, _ := newFs(t)
+	save(t, local, mod, "v1.0.0", string(make([]byte, 100)))
+	save(t, local, mod, "v1.1.0", string(make([]byte, 100)))
+	_, err := New(ctx, local, remote, 200)
+	require.NoError(t, err)
+	versions, err := local.List(ctx, mod)
+	require.NoError(t, err)
+	require.Len(t, versions, 1, "versions over the maximum size must be evicted")
+}
@@ -2,10 +2,13 @@ package storage
 
 import "io"
 
-// Version represents a version of a module and contains .mod file, a .info file and zip file of a specific version
+// Version represents a version of a module and contains .mod file, a .info file and zip file of a specific version.
+// Sum and GoModSum hold the go.sum hashes of the zip and the .mod file, if the fetcher knows them.
 type Version struct {
-	Mod    []byte
-	Zip    io.ReadCloser
-	Info   []byte
-	Semver string
+	Mod      []byte
+	Zip      io.ReadCloser
+	Info     []byte
+	Semver   string
+	Sum      string
+	GoModSum string
 }
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ZipURLer is the interface of the storage backends that can give
+// out time-limited URLs to download zips from, so that clients get
+// them from the backend instead of through the proxy.
+type ZipURLer interface {
+	// ZipURL returns a URL to download the zip of the module at the
+	// given version from, which is valid for expiry. It returns an
+	// error of KindNotFound if the version is not in storage, and
+	// of KindNotImplemented if the backend cannot sign URLs as
+	// configured.
+	ZipURL(ctx context.Context, module, version string, expiry time.Duration) (string, error)
+}
@@ -0,0 +1,99 @@
+package compliance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/usage"
+	"github.com/stretchr/testify/require"
+)
+
+// RunTests runs compliance tests for the given Store implementation.
+// clearStore is a function that must clear the entire store so that
+// tests can assume a clean state.
+func RunTests(t *testing.T, s usage.Store, clearStore func() error) {
+	ctx := context.Background()
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-48 * time.Hour)
+	last := hour.Add(30 * time.Minute)
+	seed := []*usage.Increment{
+		{Module: "example.com/a", Version: "v1.0.0", Hour: hour, GoMods: 1, Zips: 1, LastAccess: last},
+		{Module: "example.com/a", Version: "v1.1.0", Hour: hour, GoMods: 2, Zips: 2, LastAccess: last},
+		{Module: "example.com/b", Version: "v0.1.0", Hour: hour, GoMods: 1, LastAccess: last},
+		// downloads of the same version in a later hour
+		{Module: "example.com/a", Version: "v1.0.0", Hour: hour.Add(time.Hour), Zips: 3, LastAccess: last.Add(time.Hour)},
+	}
+
+	tests := []struct {
+		name  string
+		query *usage.Query
+		want  []*usage.Count
+	}{
+		{
+			name:  "all",
+			query: &usage.Query{Limit: 10},
+			want: []*usage.Count{
+				{Module: "example.com/a", Version: "v1.0.0", GoMods: 1, Zips: 4, LastAccess: last.Add(time.Hour)},
+				{Module: "example.com/a", Version: "v1.1.0", GoMods: 2, Zips: 2, LastAccess: last},
+				{Module: "example.com/b", Version: "v0.1.0", GoMods: 1, LastAccess: last},
+			},
+		},
+		{
+			name:  "limit",
+			query: &usage.Query{Limit: 1},
+			want: []*usage.Count{
+				{Module: "example.com/a", Version: "v1.0.0", GoMods: 1, Zips: 4, LastAccess: last.Add(time.Hour)},
+			},
+		},
+		{
+			name:  "since",
+			query: &usage.Query{Since: last.Add(time.Hour), Limit: 10},
+			want: []*usage.Count{
+				{Module: "example.com/a", Version: "v1.0.0", Zips: 3, LastAccess: last.Add(time.Hour)},
+			},
+		},
+		{
+			name:  "until",
+			query: &usage.Query{Until: hour.Add(time.Hour), Limit: 10},
+			want: []*usage.Count{
+				{Module: "example.com/a", Version: "v1.1.0", GoMods: 2, Zips: 2, LastAccess: last},
+				{Module: "example.com/a", Version: "v1.0.0", GoMods: 1, Zips: 1, LastAccess: last},
+				{Module: "example.com/b", Version: "v0.1.0", GoMods: 1, LastAccess: last},
+			},
+		},
+		{
+			name:  "module",
+			query: &usage.Query{Module: "example.com/b", Limit: 10},
+			want: []*usage.Count{
+				{Module: "example.com/b", Version: "v0.1.0", GoMods: 1, LastAccess: last},
+			},
+		},
+		{
+			name:  "by module",
+			query: &usage.Query{ByModule: true, Limit: 10},
+			want: []*usage.Count{
+				{Module: "example.com/a", GoMods: 3, Zips: 6, LastAccess: last.Add(time.Hour)},
+				{Module: "example.com/b", GoMods: 1, LastAccess: last},
+			},
+		},
+	}
+
+	require.NoError(t, clearStore())
+	// adding the seed in two batches checks that
+	// counts of the same hour are added up.
+	require.NoError(t, s.Add(ctx, seed[:2]))
+	require.NoError(t, s.Add(ctx, seed[2:]))
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.Top(ctx, tc.query)
+			require.NoError(t, err)
+			require.Len(t, got, len(tc.want))
+			for i, want := range tc.want {
+				require.True(t, want.LastAccess.Equal(got[i].LastAccess), "last access of %s@%s", want.Module, want.Version)
+				got[i].LastAccess = want.LastAccess
+				require.Equal(t, want, got[i])
+			}
+		})
+	}
+	require.NoError(t, clearStore())
+}
@@ -0,0 +1,92 @@
+package mem
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/usage"
+)
+
+// New returns a new in-memory usage store.
+func New() usage.Store {
+	return &store{counts: map[key]*usage.Increment{}}
+}
+
+type key struct {
+	mod, ver string
+	hour     time.Time
+}
+
+type store struct {
+	mu     sync.RWMutex
+	counts map[key]*usage.Increment
+}
+
+func (s *store) Add(ctx context.Context, incs []*usage.Increment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, inc := range incs {
+		k := key{mod: inc.Module, ver: inc.Version, hour: inc.Hour.UTC()}
+		c, ok := s.counts[k]
+		if !ok {
+			c = &usage.Increment{Module: inc.Module, Version: inc.Version, Hour: k.hour}
+			s.counts[k] = c
+		}
+		c.GoMods += inc.GoMods
+		c.Zips += inc.Zips
+		if inc.LastAccess.After(c.LastAccess) {
+			c.LastAccess = inc.LastAccess
+		}
+	}
+	return nil
+}
+
+func (s *store) Top(ctx context.Context, q *usage.Query) ([]*usage.Count, error) {
+	since := q.Since.Truncate(time.Hour)
+	totals := map[key]*usage.Count{}
+	s.mu.RLock()
+	for k, inc := range s.counts {
+		if k.hour.Before(since) || (!q.Until.IsZero() && !k.hour.Before(q.Until)) {
+			continue
+		}
+		if q.Module != "" && k.mod != q.Module {
+			continue
+		}
+		tk := key{mod: k.mod, ver: k.ver}
+		if q.ByModule {
+			tk.ver = ""
+		}
+		c, ok := totals[tk]
+		if !ok {
+			c = &usage.Count{Module: tk.mod, Version: tk.ver}
+			totals[tk] = c
+		}
+		c.GoMods += inc.GoMods
+		c.Zips += inc.Zips
+		if inc.LastAccess.After(c.LastAccess) {
+			c.LastAccess = inc.LastAccess
+		}
+	}
+	s.mu.RUnlock()
+
+	counts := make([]*usage.Count, 0, len(totals))
+	for _, c := range totals {
+		counts = append(counts, c)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := counts[i], counts[j]
+		if a.GoMods+a.Zips != b.GoMods+b.Zips {
+			return a.GoMods+a.Zips > b.GoMods+b.Zips
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Version < b.Version
+	})
+	if q.Limit >= 0 && len(counts) > q.Limit {
+		counts = counts[:q.Limit]
+	}
+	return counts, nil
+}
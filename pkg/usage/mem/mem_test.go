@@ -0,0 +1,16 @@
+package mem
+
+import (
+	"testing"
+
+	"github.com/gomods/athens/pkg/usage"
+	"github.com/gomods/athens/pkg/usage/compliance"
+)
+
+func TestMem(t *testing.T) {
+	s := New()
+	compliance.RunTests(t, s, func() error {
+		s.(*store).counts = map[key]*usage.Increment{}
+		return nil
+	})
+}
@@ -0,0 +1,162 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/usage"
+)
+
+// New returns a new usage Store with a MySQL implementation.
+// It attempts to connect to the DB and create the downloads
+// table if it does not already exist.
+func New(cfg *config.MySQL) (usage.Store, error) {
+	dataSource := getMySQLSource(cfg)
+	db, err := sql.Open("mysql", dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(schema)
+	if err != nil {
+		return nil, err
+	}
+	return &store{db}, nil
+}
+
+const schema = `
+	CREATE TABLE IF NOT EXISTS downloads(
+	path VARCHAR(255)
+		NOT NULL
+		COMMENT 'Import path of the module',
+
+	version VARCHAR(255)
+		NOT NULL
+		COMMENT 'Module version',
+
+	hour TIMESTAMP
+		NOT NULL
+		COMMENT 'Start of the hour that the downloads happened in',
+
+	gomods BIGINT
+		NOT NULL
+		DEFAULT 0
+		COMMENT 'Number of go.mod downloads',
+
+	zips BIGINT
+		NOT NULL
+		DEFAULT 0
+		COMMENT 'Number of zip downloads',
+
+	last_access TIMESTAMP(6)
+		NOT NULL
+		COMMENT 'Date and time of the last download in the hour',
+
+	PRIMARY KEY (path, version, hour),
+	INDEX (hour)
+	) CHARACTER SET utf8;
+`
+
+type store struct {
+	db *sql.DB
+}
+
+func (s *store) Add(ctx context.Context, incs []*usage.Increment) error {
+	const op errors.Op = "mysql.Add"
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	for _, inc := range incs {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO downloads (path, version, hour, gomods, zips, last_access) VALUES (?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				gomods = gomods + VALUES(gomods),
+				zips = zips + VALUES(zips),
+				last_access = GREATEST(last_access, VALUES(last_access))`,
+			inc.Module,
+			inc.Version,
+			inc.Hour.UTC(),
+			inc.GoMods,
+			inc.Zips,
+			inc.LastAccess.UTC(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return errors.E(op, err, errors.M(inc.Module), errors.V(inc.Version))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+func (s *store) Top(ctx context.Context, q *usage.Query) ([]*usage.Count, error) {
+	const op errors.Op = "mysql.Top"
+	cols, group := "path, version", "path, version"
+	if q.ByModule {
+		cols, group = "path, '' AS version", "path"
+	}
+	where := []string{"hour >= ?"}
+	args := []interface{}{q.Since.Truncate(time.Hour).UTC()}
+	if q.Since.IsZero() {
+		args[0] = time.Unix(0, 0).UTC()
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "hour < ?")
+		args = append(args, q.Until.UTC())
+	}
+	if q.Module != "" {
+		where = append(where, "path = ?")
+		args = append(args, q.Module)
+	}
+	args = append(args, q.Limit)
+	query := fmt.Sprintf(
+		`SELECT %[1]s, SUM(gomods), SUM(zips), MAX(last_access) FROM downloads
+		WHERE %[2]s
+		GROUP BY %[3]s
+		ORDER BY SUM(gomods) + SUM(zips) DESC, %[3]s
+		LIMIT ?`,
+		cols,
+		strings.Join(where, " AND "),
+		group,
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer rows.Close()
+	counts := []*usage.Count{}
+	for rows.Next() {
+		var c usage.Count
+		if err := rows.Scan(&c.Module, &c.Version, &c.GoMods, &c.Zips, &c.LastAccess); err != nil {
+			return nil, errors.E(op, err)
+		}
+		counts = append(counts, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return counts, nil
+}
+
+func getMySQLSource(cfg *config.MySQL) string {
+	c := mysql.NewConfig()
+	c.Net = cfg.Protocol
+	c.Addr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	c.User = cfg.User
+	c.Passwd = cfg.Password
+	c.DBName = cfg.Database
+	c.Params = cfg.Params
+	return c.FormatDSN()
+}
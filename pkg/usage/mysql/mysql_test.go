@@ -0,0 +1,37 @@
+package mysql
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/usage/compliance"
+)
+
+// The downloads table lives next to the index, so
+// these tests run against the index test database.
+func TestMySQL(t *testing.T) {
+	if os.Getenv("TEST_INDEX_MYSQL") != "true" {
+		t.SkipNow()
+	}
+	cfg := getTestConfig(t)
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compliance.RunTests(t, s, s.(*store).clear)
+}
+
+func (s *store) clear() error {
+	_, err := s.db.Exec(`DELETE FROM downloads`)
+	return err
+}
+
+func getTestConfig(t *testing.T) *config.MySQL {
+	t.Helper()
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Index.MySQL
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// register the driver with database/sql
+	_ "github.com/lib/pq"
+
+	"github.com/gomods/athens/pkg/config"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/usage"
+)
+
+// New returns a new usage Store with a PostgreSQL implementation.
+// It attempts to connect to the DB and create the downloads
+// table if it does not already exist.
+func New(cfg *config.Postgres) (usage.Store, error) {
+	dataSource := getPostgresSource(cfg)
+	db, err := sql.Open("postgres", dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+	for _, statement := range schema {
+		_, err = db.Exec(statement)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &store{db}, nil
+}
+
+var schema = [...]string{
+	`
+		CREATE TABLE IF NOT EXISTS downloads(
+			path VARCHAR(255) NOT NULL,
+			version VARCHAR(255) NOT NULL,
+			hour timestamp NOT NULL,
+			gomods BIGINT NOT NULL DEFAULT 0,
+			zips BIGINT NOT NULL DEFAULT 0,
+			last_access timestamp NOT NULL,
+			PRIMARY KEY (path, version, hour)
+		)
+	`,
+	`
+		CREATE INDEX IF NOT EXISTS idx_downloads_hour ON downloads (hour)
+	`,
+}
+
+type store struct {
+	db *sql.DB
+}
+
+func (s *store) Add(ctx context.Context, incs []*usage.Increment) error {
+	const op errors.Op = "postgres.Add"
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	for _, inc := range incs {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO downloads (path, version, hour, gomods, zips, last_access) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (path, version, hour) DO UPDATE SET
+				gomods = downloads.gomods + EXCLUDED.gomods,
+				zips = downloads.zips + EXCLUDED.zips,
+				last_access = GREATEST(downloads.last_access, EXCLUDED.last_access)`,
+			inc.Module,
+			inc.Version,
+			inc.Hour.UTC(),
+			inc.GoMods,
+			inc.Zips,
+			inc.LastAccess.UTC(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return errors.E(op, err, errors.M(inc.Module), errors.V(inc.Version))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+func (s *store) Top(ctx context.Context, q *usage.Query) ([]*usage.Count, error) {
+	const op errors.Op = "postgres.Top"
+	cols, group := "path, version", "path, version"
+	if q.ByModule {
+		cols, group = "path, '' AS version", "path"
+	}
+	since := q.Since.Truncate(time.Hour).UTC()
+	if q.Since.IsZero() {
+		since = time.Unix(0, 0).UTC()
+	}
+	where := []string{"hour >= $1"}
+	args := []interface{}{since}
+	if !q.Until.IsZero() {
+		args = append(args, q.Until.UTC())
+		where = append(where, "hour < $"+strconv.Itoa(len(args)))
+	}
+	if q.Module != "" {
+		args = append(args, q.Module)
+		where = append(where, "path = $"+strconv.Itoa(len(args)))
+	}
+	args = append(args, q.Limit)
+	query := fmt.Sprintf(
+		`SELECT %[1]s, SUM(gomods), SUM(zips), MAX(last_access) FROM downloads
+		WHERE %[2]s
+		GROUP BY %[3]s
+		ORDER BY SUM(gomods) + SUM(zips) DESC, %[3]s
+		LIMIT $%[4]d`,
+		cols,
+		strings.Join(where, " AND "),
+		group,
+		len(args),
+	)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer rows.Close()
+	counts := []*usage.Count{}
+	for rows.Next() {
+		var c usage.Count
+		if err := rows.Scan(&c.Module, &c.Version, &c.GoMods, &c.Zips, &c.LastAccess); err != nil {
+			return nil, errors.E(op, err)
+		}
+		counts = append(counts, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	return counts, nil
+}
+
+func getPostgresSource(cfg *config.Postgres) string {
+	args := []string{}
+	args = append(args, "host="+cfg.Host)
+	args = append(args, "port=", strconv.Itoa(cfg.Port))
+	args = append(args, "user=", cfg.User)
+	args = append(args, "dbname=", cfg.Database)
+	args = append(args, "password="+cfg.Password)
+	for k, v := range cfg.Params {
+		args = append(args, k+"="+v)
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,131 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+)
+
+// finalFlushTimeout is how long Start waits for the
+// Store on the last flush, once its context is done.
+const finalFlushTimeout = 10 * time.Second
+
+type key struct {
+	mod, ver string
+	hour     time.Time
+}
+
+// Recorder counts downloads in memory and adds them to a
+// Store in batches, so that downloads do not wait on the Store.
+// It implements download.DownloadRecorder.
+type Recorder struct {
+	store Store
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[key]*Increment
+}
+
+// NewRecorder returns a Recorder that adds to s.
+func NewRecorder(s Store) *Recorder {
+	return &Recorder{store: s, now: time.Now, pending: map[key]*Increment{}}
+}
+
+// RecordGoMod counts a download of the go.mod file of mod@ver.
+func (r *Recorder) RecordGoMod(ctx context.Context, mod, ver string) {
+	r.record(mod, ver, 1, 0)
+}
+
+// RecordZip counts a download of the zip of mod@ver.
+func (r *Recorder) RecordZip(ctx context.Context, mod, ver string) {
+	r.record(mod, ver, 0, 1)
+}
+
+func (r *Recorder) record(mod, ver string, goMods, zips int64) {
+	now := r.now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.merge(&Increment{
+		Module:     mod,
+		Version:    ver,
+		Hour:       now.Truncate(time.Hour),
+		GoMods:     goMods,
+		Zips:       zips,
+		LastAccess: now,
+	})
+}
+
+// merge adds inc to the pending increments. r.mu must be held.
+func (r *Recorder) merge(inc *Increment) {
+	k := key{mod: inc.Module, ver: inc.Version, hour: inc.Hour}
+	p, ok := r.pending[k]
+	if !ok {
+		r.pending[k] = inc
+		return
+	}
+	p.GoMods += inc.GoMods
+	p.Zips += inc.Zips
+	if inc.LastAccess.After(p.LastAccess) {
+		p.LastAccess = inc.LastAccess
+	}
+}
+
+// Flush adds the downloads counted since the last Flush to the
+// Store. If the Store fails, they are kept for the next Flush.
+func (r *Recorder) Flush(ctx context.Context) error {
+	const op errors.Op = "usage.Flush"
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = map[key]*Increment{}
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	incs := make([]*Increment, 0, len(pending))
+	for _, inc := range pending {
+		incs = append(incs, inc)
+	}
+	if err := r.store.Add(ctx, incs); err != nil {
+		r.mu.Lock()
+		for _, inc := range incs {
+			r.merge(inc)
+		}
+		r.mu.Unlock()
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// Start flushes the counts every interval until ctx is done,
+// and once more then, so that the last downloads are not lost.
+// The returned channel is closed after that last flush.
+// Errors are logged using the entry in ctx.
+func (r *Recorder) Start(ctx context.Context, interval time.Duration) <-chan struct{} {
+	lggr := log.EntryFromContext(ctx)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// ctx is done, so the last flush has its own.
+				fctx, cancel := context.WithTimeout(context.Background(), finalFlushTimeout)
+				if err := r.Flush(fctx); err != nil {
+					lggr.SystemErr(err)
+				}
+				cancel()
+				return
+			case <-ticker.C:
+				if err := r.Flush(ctx); err != nil {
+					lggr.SystemErr(err)
+				}
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,72 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct {
+	mu   sync.Mutex
+	fail bool
+	incs []*Increment
+}
+
+func (s *failingStore) Add(ctx context.Context, incs []*Increment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return fmt.Errorf("store is down")
+	}
+	s.incs = append(s.incs, incs...)
+	return nil
+}
+
+func (s *failingStore) Top(ctx context.Context, q *Query) ([]*Count, error) {
+	return nil, nil
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	s := &failingStore{fail: true}
+	r := NewRecorder(s)
+	now := time.Date(2020, 6, 1, 10, 30, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	r.RecordGoMod(ctx, "example.com/a", "v1.0.0")
+	r.RecordZip(ctx, "example.com/a", "v1.0.0")
+	require.Error(t, r.Flush(ctx))
+
+	now = now.Add(10 * time.Minute)
+	r.RecordZip(ctx, "example.com/a", "v1.0.0")
+	s.fail = false
+	require.NoError(t, r.Flush(ctx))
+	require.Equal(t, []*Increment{{
+		Module:     "example.com/a",
+		Version:    "v1.0.0",
+		Hour:       time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+		GoMods:     1,
+		Zips:       2,
+		LastAccess: now,
+	}}, s.incs, "counts that failed to flush must be kept")
+
+	require.NoError(t, r.Flush(ctx))
+	require.Len(t, s.incs, 1, "counts must only be flushed once")
+}
+
+func TestRecorderFlushesOnStop(t *testing.T) {
+	s := &failingStore{}
+	r := NewRecorder(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := r.Start(ctx, time.Hour)
+	r.RecordZip(ctx, "example.com/a", "v1.0.0")
+	cancel()
+	<-done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Len(t, s.incs, 1, "counts must be flushed before the recorder is done")
+}
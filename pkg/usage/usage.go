@@ -0,0 +1,55 @@
+// Package usage counts the go.mod files and zips that the proxy serves
+// for each module version, so that operators can see which modules and
+// versions are actually used.
+package usage
+
+import (
+	"context"
+	"time"
+)
+
+// Increment is a number of downloads of a module
+// version that happened in the same hour.
+type Increment struct {
+	Module, Version string
+	// Hour is the start of the hour that the downloads happened in.
+	Hour       time.Time
+	GoMods     int64
+	Zips       int64
+	LastAccess time.Time
+}
+
+// Query selects the download counts returned by Store.Top.
+type Query struct {
+	// Since and Until, if not zero, restrict the counts to the
+	// downloads in that window. Downloads are counted per hour,
+	// so Since is rounded down to the hour.
+	Since, Until time.Time
+	// Module, if not empty, only returns the versions of Module.
+	Module string
+	// ByModule adds up the downloads of all the versions of
+	// each module, and leaves the Version of the counts empty.
+	ByModule bool
+	// Limit is the maximum number of counts to return.
+	Limit int
+}
+
+// Count is the number of downloads of a module version, or of
+// a module if the query was by module.
+type Count struct {
+	Module     string    `json:"module"`
+	Version    string    `json:"version,omitempty"`
+	GoMods     int64     `json:"goMods"`
+	Zips       int64     `json:"zips"`
+	LastAccess time.Time `json:"lastAccess"`
+}
+
+// Store keeps download counts.
+type Store interface {
+	// Add adds the downloads in incs to the counts.
+	Add(ctx context.Context, incs []*Increment) error
+
+	// Top returns the counts selected by q, with the most
+	// downloaded first. Ties are ordered by module and version.
+	Top(ctx context.Context, q *Query) ([]*Count, error)
+}
@@ -0,0 +1,70 @@
+package validation
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache keeps the verdicts of a hook for a limited time.
+// A nil *Cache caches nothing.
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	verdicts  map[string]cached
+	lastSweep time.Time
+}
+
+type cached struct {
+	verdict *Verdict
+	expiry  time.Time
+}
+
+// NewCache returns a Cache whose verdicts expire after ttl,
+// or nil if ttl is not positive. Expired verdicts are removed
+// lazily, at most once every ttl.
+func NewCache(ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &Cache{ttl: ttl, now: time.Now, verdicts: map[string]cached{}}
+}
+
+// Get returns the verdict cached for mod@ver, if any.
+func (c *Cache) Get(mod, ver string) (*Verdict, bool) {
+	if c == nil {
+		return nil, false
+	}
+	k := mod + "@" + ver
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.verdicts[k]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(v.expiry) {
+		delete(c.verdicts, k)
+		return nil, false
+	}
+	return v.verdict, true
+}
+
+// Set caches the verdict for mod@ver.
+func (c *Cache) Set(mod, ver string, verdict *Verdict) {
+	if c == nil {
+		return
+	}
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for k, v := range c.verdicts {
+			if !now.Before(v.expiry) {
+				delete(c.verdicts, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.verdicts[mod+"@"+ver] = cached{verdict: verdict, expiry: now.Add(c.ttl)}
+}
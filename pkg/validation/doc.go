@@ -0,0 +1,15 @@
+// Package validation lets an external policy engine reject
+// module versions through a validator hook.
+//
+// The hook is called in two phases. Before a version is served,
+// it receives the module and version being requested, see
+// middleware.NewValidationMiddleware. After a version is fetched
+// from upstream but before it is saved, it also receives the
+// go.mod file, the zip hash and the license files of the version,
+// see NewFetcher, so that it can reject modules based on their
+// contents. The hook responds with a 200 to accept a version and
+// with a 403 to reject it, and the response body is the reason.
+//
+// Verdicts are cached per module@version, so that the hook
+// is not called on every request.
+package validation
@@ -0,0 +1,174 @@
+package validation
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/internal/spool"
+	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const (
+	// maxLicenses is the number of license files sent to the hook.
+	maxLicenses = 16
+	// maxLicenseSize is the number of bytes of a license file sent to the hook.
+	maxLicenseSize = 64 << 10
+)
+
+// licensePrefixes are the prefixes of the names
+// of license files, in upper case.
+var licensePrefixes = []string{"LICENSE", "LICENCE", "COPYING", "NOTICE", "UNLICENSE"}
+
+type validatingFetcher struct {
+	fetcher module.Fetcher
+	hook    *Hook
+	cache   *Cache
+	fs      afero.Fs
+	dir     string
+}
+
+// NewFetcher wraps f so that every module version it fetches is sent
+// to hook, along with its go.mod file, its zip hash and its license
+// files, before being returned. The zip is spooled to a temporary file
+// in dir in order to be read, unless f already spooled it, and that file
+// is removed once the returned zip is closed. Versions that hook rejects are never returned, so they
+// cannot be stashed, and fail with errors.KindForbidden.
+//
+// The verdicts of hook are kept in cache, so that a rejected version
+// is not fetched again until its verdict expires.
+func NewFetcher(f module.Fetcher, hook *Hook, cache *Cache, fs afero.Fs, dir string) module.Fetcher {
+	return &validatingFetcher{
+		fetcher: f,
+		hook:    hook,
+		cache:   cache,
+		fs:      fs,
+		dir:     dir,
+	}
+}
+
+func (f *validatingFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "validatingFetcher.Fetch"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if verdict, ok := f.cache.Get(mod, ver); ok && !verdict.Valid {
+		return nil, rejected(op, mod, ver, verdict)
+	}
+	v, err := f.fetcher.Fetch(ctx, mod, ver)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	zip, err := spool.Reuse(f.fs, f.dir, "athens-validate", v.Zip)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(v.Semver), err)
+	}
+	if err := f.validate(ctx, mod, v, zip); err != nil {
+		zip.Close()
+		return nil, errors.E(op, err)
+	}
+	v.Zip = zip
+	return v, nil
+}
+
+func (f *validatingFetcher) validate(ctx context.Context, mod string, v *storage.Version, zip *spool.File) error {
+	const op errors.Op = "validatingFetcher.validate"
+	verdict, ok := f.cache.Get(mod, v.Semver)
+	if !ok {
+		var err error
+		req := &Request{Module: mod, Version: v.Semver, GoMod: string(v.Mod), ZipHash: v.Sum}
+		if req.ZipHash == "" {
+			req.ZipHash, err = checksum.HashZip(zip, zip.Size)
+			if err != nil {
+				return errors.E(op, errors.M(mod), errors.V(v.Semver), err)
+			}
+		}
+		req.Licenses, err = licenses(zip, zip.Size, mod+"@"+v.Semver+"/")
+		if err != nil {
+			return errors.E(op, errors.M(mod), errors.V(v.Semver), err)
+		}
+		verdict, err = f.hook.Validate(ctx, req)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		f.cache.Set(mod, v.Semver, verdict)
+	}
+	if !verdict.Valid {
+		return rejected(op, mod, v.Semver, verdict)
+	}
+	if verdict.Message != "" {
+		log.EntryFromContext(ctx).Warnf("validating %s@%s: %s", mod, v.Semver, verdict.Message)
+	}
+	if _, err := zip.Seek(0, io.SeekStart); err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// rejected returns the error of a version that the hook rejected.
+func rejected(op errors.Op, mod, ver string, verdict *Verdict) error {
+	msg := "rejected by the validator hook"
+	if verdict.Message != "" {
+		msg += ": " + verdict.Message
+	}
+	return errors.E(op, errors.M(mod), errors.V(ver), msg, errors.KindForbidden, logrus.InfoLevel)
+}
+
+// licenses returns the license files in a module zip,
+// whose files are all in the prefix directory.
+func licenses(r io.ReaderAt, size int64, prefix string) ([]License, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var ls []License
+	for _, file := range z.File {
+		if len(ls) == maxLicenses {
+			break
+		}
+		if !isLicense(path.Base(file.Name)) {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(rc, maxLicenseSize))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		ls = append(ls, License{
+			Path:      strings.TrimPrefix(file.Name, prefix),
+			Content:   string(content),
+			Truncated: file.UncompressedSize64 > maxLicenseSize,
+		})
+	}
+	return ls, nil
+}
+
+// isLicense reports whether name is the name of a license file,
+// such as LICENSE, LICENSE.md, LICENSE-MIT or COPYING.txt.
+func isLicense(name string) bool {
+	name = strings.ToUpper(name)
+	if strings.HasSuffix(name, ".GO") {
+		return false
+	}
+	for _, prefix := range licensePrefixes {
+		if name == prefix ||
+			strings.HasPrefix(name, prefix+".") ||
+			strings.HasPrefix(name, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package validation
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomods/athens/pkg/checksum"
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const testGoMod = "module example.com/mod\n"
+
+type mockFetcher struct {
+	zip   []byte
+	calls int
+}
+
+func (m *mockFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	m.calls++
+	return &storage.Version{
+		Semver: ver,
+		Mod:    []byte(testGoMod),
+		Zip:    ioutil.NopCloser(bytes.NewReader(m.zip)),
+	}, nil
+}
+
+func makeZip(t *testing.T, mod, ver string, files map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, data := range files {
+		w, err := zw.Create(mod + "@" + ver + "/" + name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// hookMock rejects the versions whose
+// licenses contain the forbidden string.
+type hookMock struct {
+	forbidden string
+	status    int
+	reqs      []Request
+}
+
+func (m *hookMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	json.NewDecoder(r.Body).Decode(&req)
+	m.reqs = append(m.reqs, req)
+	if m.status != 0 {
+		w.WriteHeader(m.status)
+		return
+	}
+	for _, l := range req.Licenses {
+		if strings.Contains(l.Content, m.forbidden) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(m.forbidden + " is not allowed"))
+			return
+		}
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	const mod, ver = "example.com/mod", "v1.0.0"
+	mit := makeZip(t, mod, ver, map[string]string{"go.mod": testGoMod, "LICENSE": "MIT License", "main.go": "package mod"})
+	gpl := makeZip(t, mod, ver, map[string]string{"go.mod": testGoMod, "sub/COPYING.txt": "GNU AGPL", "main.go": "package mod"})
+
+	tests := []struct {
+		name    string
+		zip     []byte
+		status  int
+		license License
+		wantErr int
+	}{
+		{name: "accepted", zip: mit, license: License{Path: "LICENSE", Content: "MIT License"}},
+		{name: "rejected", zip: gpl, license: License{Path: "sub/COPYING.txt", Content: "GNU AGPL"}, wantErr: errors.KindForbidden},
+		{name: "hook failure", zip: mit, status: http.StatusBadGateway, license: License{Path: "LICENSE", Content: "MIT License"}, wantErr: errors.KindUnexpected},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hm := &hookMock{forbidden: "AGPL", status: tc.status}
+			srv := httptest.NewServer(hm)
+			defer srv.Close()
+			fs := afero.NewMemMapFs()
+			mf := &mockFetcher{zip: tc.zip}
+			f := NewFetcher(mf, NewHook(srv.URL, srv.Client()), NewCache(time.Minute), fs, "/tmp")
+
+			v, err := f.Fetch(context.Background(), mod, ver)
+			if tc.wantErr != 0 {
+				require.Error(t, err)
+				require.Equal(t, tc.wantErr, errors.Kind(err))
+			} else {
+				require.NoError(t, err)
+				zipBytes, err := ioutil.ReadAll(v.Zip)
+				require.NoError(t, err)
+				require.Equal(t, tc.zip, zipBytes)
+				require.NoError(t, v.Zip.Close())
+			}
+
+			require.Len(t, hm.reqs, 1)
+			req := hm.reqs[0]
+			require.Equal(t, mod, req.Module)
+			require.Equal(t, ver, req.Version)
+			require.Equal(t, testGoMod, req.GoMod)
+			zipHash, err := checksum.HashZip(bytes.NewReader(tc.zip), int64(len(tc.zip)))
+			require.NoError(t, err)
+			require.Equal(t, zipHash, req.ZipHash)
+			require.Equal(t, []License{tc.license}, req.Licenses)
+
+			// a second fetch uses the cached verdict,
+			// and rejected versions are not fetched again.
+			v, err = f.Fetch(context.Background(), mod, ver)
+			if err == nil {
+				require.NoError(t, v.Zip.Close())
+			}
+			if tc.status == 0 {
+				require.Len(t, hm.reqs, 1)
+			} else {
+				require.Len(t, hm.reqs, 2, "hook failures must not be cached")
+			}
+			if tc.wantErr == errors.KindForbidden {
+				require.Equal(t, errors.KindForbidden, errors.Kind(err))
+				require.Equal(t, 1, mf.calls)
+			}
+
+			files, err := afero.ReadDir(fs, "/tmp")
+			require.NoError(t, err)
+			require.Empty(t, files, "temporary zip files must be removed")
+		})
+	}
+}
+
+// countingFs counts the files that are opened for writing.
+type countingFs struct {
+	afero.Fs
+	created int
+}
+
+func (fs *countingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&os.O_CREATE != 0 {
+		fs.created++
+	}
+	return fs.Fs.OpenFile(name, flag, perm)
+}
+
+func TestFetcherReusesSpooledZip(t *testing.T) {
+	const mod, ver = "example.com/mod", "v1.0.0"
+	z := makeZip(t, mod, ver, map[string]string{"go.mod": testGoMod, "LICENSE": "MIT License"})
+	hm := &hookMock{forbidden: "AGPL"}
+	srv := httptest.NewServer(hm)
+	defer srv.Close()
+	fs := &countingFs{Fs: afero.NewMemMapFs()}
+	hashing := checksum.NewHashingFetcher(&mockFetcher{zip: z}, fs, "/tmp")
+	f := NewFetcher(hashing, NewHook(srv.URL, srv.Client()), NewCache(time.Minute), fs, "/tmp")
+
+	v, err := f.Fetch(context.Background(), mod, ver)
+	require.NoError(t, err)
+	require.Equal(t, 1, fs.created, "the zip spooled to be hashed must be validated without being spooled again")
+	zipBytes, err := ioutil.ReadAll(v.Zip)
+	require.NoError(t, err)
+	require.Equal(t, z, zipBytes)
+	require.NoError(t, v.Zip.Close())
+	require.Len(t, hm.reqs, 1)
+	require.Equal(t, v.Sum, hm.reqs[0].ZipHash)
+
+	files, err := afero.ReadDir(fs, "/tmp")
+	require.NoError(t, err)
+	require.Empty(t, files, "temporary zip files must be removed")
+}
+
+func TestLicensesTruncated(t *testing.T) {
+	big := strings.Repeat("x", maxLicenseSize+1)
+	z := makeZip(t, "example.com/mod", "v1.0.0", map[string]string{"LICENSE.md": big, "license_test.go": "package mod"})
+	ls, err := licenses(bytes.NewReader(z), int64(len(z)), "example.com/mod@v1.0.0/")
+	require.NoError(t, err)
+	require.Len(t, ls, 1)
+	require.Equal(t, "LICENSE.md", ls[0].Path)
+	require.True(t, ls[0].Truncated)
+	require.Len(t, ls[0].Content, maxLicenseSize)
+}
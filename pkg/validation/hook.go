@@ -0,0 +1,74 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gomods/athens/pkg/errors"
+)
+
+// Request is what the hook receives. The fields other than Module
+// and Version are only set after the version was fetched.
+type Request struct {
+	Module   string
+	Version  string
+	GoMod    string    `json:",omitempty"`
+	ZipHash  string    `json:",omitempty"`
+	Licenses []License `json:",omitempty"`
+}
+
+// License is a license file of a module version.
+type License struct {
+	// Path is the path of the file inside the module.
+	Path    string
+	Content string
+	// Truncated is true if Content is only the start of the file.
+	Truncated bool `json:",omitempty"`
+}
+
+// Verdict is the response of the hook.
+type Verdict struct {
+	Valid   bool
+	Message string
+}
+
+// Hook calls a validator hook.
+type Hook struct {
+	url    string
+	client *http.Client
+}
+
+// NewHook returns a Hook that posts requests to url.
+func NewHook(url string, client *http.Client) *Hook {
+	return &Hook{url: url, client: client}
+}
+
+// Validate posts req to the hook, and returns its verdict. Responses
+// other than a 200 or a 403 are errors rather than verdicts.
+func (h *Hook) Validate(ctx context.Context, req *Request) (*Verdict, error) {
+	const op errors.Op = "validation.Validate"
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(hreq)
+	if err != nil {
+		return nil, errors.E(op, err, errors.M(req.Module), errors.V(req.Version))
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusForbidden:
+		return &Verdict{Valid: resp.StatusCode == http.StatusOK, Message: string(msg)}, nil
+	default:
+		return nil, errors.E(op, errors.M(req.Module), errors.V(req.Version), "unexpected status code "+resp.Status)
+	}
+}